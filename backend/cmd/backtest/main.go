@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/config"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/services"
+)
+
+func main() {
+	strategy := flag.String("strategy", services.DefaultStrategyName, "scoring strategy to replay")
+	from := flag.String("from", "", "first ranking date, YYYY-MM-DD (default one year before -to)")
+	to := flag.String("to", "", "last ranking date, YYYY-MM-DD (default so the last picks are sold on the last price)")
+	step := flag.Int("step", 7, "days between rankings")
+	horizon := flag.Int("horizon", 30, "days each ranking is held")
+	top := flag.Int("top", 10, "tickers taken from each ranking")
+	benchmark := flag.String("benchmark", "", "benchmark ticker in the price file (default an equal-weight basket of every ticker)")
+	pricesPath := flag.String("prices", "", "daily price CSV with date,ticker,close (default PRICE_HISTORY_PATH)")
+	asJSON := flag.Bool("json", false, "print the full result as JSON")
+	flag.Parse()
+
+	// Load configuration
+	cfg := config.Load()
+	if *pricesPath == "" {
+		*pricesPath = cfg.PriceHistoryPath
+	}
+	if *pricesPath == "" {
+		log.Fatalf("No price history; pass -prices or set PRICE_HISTORY_PATH")
+	}
+
+	req := models.BacktestRequest{
+		Strategy:    *strategy,
+		StepDays:    *step,
+		HorizonDays: *horizon,
+		TopN:        *top,
+		Benchmark:   *benchmark,
+	}
+	for _, date := range []struct {
+		value  string
+		target *time.Time
+	}{{*from, &req.From}, {*to, &req.To}} {
+		if date.value == "" {
+			continue
+		}
+		parsed, err := services.ParseDate(date.value)
+		if err != nil {
+			log.Fatalf("Invalid date %q: %v", date.value, err)
+		}
+		*date.target = parsed
+	}
+
+	prices, err := services.LoadPriceHistory(*pricesPath)
+	if err != nil {
+		log.Fatalf("Failed to load price history: %v", err)
+	}
+	profiles, err := services.NewScoringProfileStore(cfg.ScoringProfilePath)
+	if err != nil {
+		log.Fatalf("Failed to load scoring profile: %v", err)
+	}
+	strategies, err := services.NewStrategyRegistry(services.NewDefaultStrategy(), services.NewConsensusStrategy())
+	if err != nil {
+		log.Fatalf("Failed to register scoring strategies: %v", err)
+	}
+
+	db, err := repository.NewDatabase(cfg.DatabaseURL, repository.DatabaseOptionsFromConfig(cfg))
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	migrator, err := repository.NewMigrator(db)
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
+	}
+	if err := migrator.EnsureCurrent(context.Background()); err != nil {
+		log.Fatalf("Failed to verify database schema: %v (run `go run cmd/migrate/main.go up`)", err)
+	}
+
+	stockService := services.NewStockService(repository.NewStockRepository(db), cfg.StockAPIURL, cfg.StockAPIKey, cfg.SyncWriteMode)
+	backtestService := services.NewBacktestService(stockService, strategies, profiles, prices)
+
+	result, err := backtestService.Run(context.Background(), req)
+	if err != nil {
+		log.Fatalf("Backtest failed: %v", err)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			log.Fatalf("Failed to write result: %v", err)
+		}
+		return
+	}
+	printResult(result)
+}
+
+func printResult(result *models.BacktestResult) {
+	benchmark := result.Benchmark
+	if benchmark == "" {
+		benchmark = "equal-weight basket"
+	}
+
+	fmt.Printf("Strategy %s v%s, profile %s\n", result.Strategy, result.StrategyVersion, result.ProfileVersion)
+	fmt.Printf("%s to %s, top %d every %dd, held %dd, benchmark %s\n\n",
+		result.From.Format("2006-01-02"), result.To.Format("2006-01-02"),
+		result.TopN, result.StepDays, result.HorizonDays, benchmark)
+
+	fmt.Printf("%-12s %6s %10s %10s\n", "date", "picks", "return", "benchmark")
+	for _, period := range result.Periods {
+		fmt.Printf("%-12s %6d %10s %10s\n", period.Date.Format("2006-01-02"), len(period.Picks),
+			percent(period.AverageReturn), percent(period.BenchmarkReturn))
+	}
+
+	summary := result.Summary
+	fmt.Printf("\n%d periods, %d picks with a known return\n", summary.Periods, summary.Picks)
+	fmt.Printf("hit rate          %s\n", rate(summary.HitRate))
+	fmt.Printf("beat benchmark    %s\n", rate(summary.BeatRate))
+	fmt.Printf("average return    %s\n", percent(summary.AverageReturn))
+	fmt.Printf("benchmark return  %s\n", percent(summary.BenchmarkAverageReturn))
+	fmt.Printf("excess return     %s\n", percent(summary.ExcessReturn))
+}
+
+func rate(value *float64) string {
+	if value == nil {
+		return "–"
+	}
+	return fmt.Sprintf("%.1f%%", *value*100)
+}
+
+func percent(value *float64) string {
+	if value == nil {
+		return "–"
+	}
+	return fmt.Sprintf("%+.2f%%", *value*100)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/config"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/services"
+)
+
+const usage = `Usage: maintenance <command> [flags]
+
+Commands:
+  prune                Delete rows older than the RETENTION_* rules
+  rebuild-consensus    Recompute ticker_consensus from the stored events
+  import-securities    Load ticker sectors and industries from a CSV file
+
+Run "maintenance <command> -h" for the flags of a command.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "prune":
+		prune(os.Args[2:])
+	case "rebuild-consensus":
+		rebuildConsensus(os.Args[2:])
+	case "import-securities":
+		importSecurities(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func prune(args []string) {
+	flags := flag.NewFlagSet("prune", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "only report how many rows each rule would delete")
+	archiveDir := flags.String("archive-dir", "", "write pruned rows here as gzip JSON lines (default RETENTION_ARCHIVE_DIR)")
+	ttl := flags.Bool("ttl", false, "configure CockroachDB row-level TTL from the rules instead of deleting rows now")
+	flags.Parse(args)
+
+	// Load configuration
+	cfg := config.Load()
+	if len(cfg.Retention) == 0 {
+		log.Fatalf("No retention rules configured; set RETENTION_STOCKS, RETENTION_STOCK_REVISIONS or RETENTION_SYNC_RUNS")
+	}
+	if *archiveDir == "" {
+		*archiveDir = cfg.RetentionArchiveDir
+	}
+
+	db := openCurrentDatabase(cfg)
+	defer db.Close()
+
+	retentionService, err := services.NewRetentionService(repository.NewRetentionRepository(db), cfg.Retention, *archiveDir)
+	if err != nil {
+		log.Fatalf("Invalid retention rules: %v", err)
+	}
+
+	var results []services.RetentionResult
+	if *ttl {
+		results, err = retentionService.ApplyRowLevelTTL(context.Background())
+	} else {
+		results, err = retentionService.Prune(context.Background(), *dryRun)
+	}
+
+	for _, result := range results {
+		switch {
+		case result.RowLevelTTL:
+			fmt.Printf("%-16s keep %-10s row-level TTL configured\n", result.Table, formatAge(result.MaxAge))
+		case *dryRun:
+			fmt.Printf("%-16s keep %-10s %d rows older than %s would be deleted\n",
+				result.Table, formatAge(result.MaxAge), result.Expired, result.Cutoff.Format(time.RFC3339))
+		default:
+			fmt.Printf("%-16s keep %-10s %d rows deleted", result.Table, formatAge(result.MaxAge), result.Deleted)
+			if result.ArchivePath != "" {
+				fmt.Printf(", archived to %s", result.ArchivePath)
+			}
+			fmt.Println()
+		}
+	}
+
+	if err != nil {
+		log.Fatalf("Retention failed: %v", err)
+	}
+}
+
+func rebuildConsensus(args []string) {
+	flags := flag.NewFlagSet("rebuild-consensus", flag.ExitOnError)
+	flags.Parse(args)
+
+	// Load configuration
+	cfg := config.Load()
+
+	db := openCurrentDatabase(cfg)
+	defer db.Close()
+
+	tickers, err := repository.NewStockRepository(db).RebuildConsensus(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to rebuild consensus: %v", err)
+	}
+	fmt.Printf("ticker_consensus rebuilt for %d tickers\n", tickers)
+}
+
+func importSecurities(args []string) {
+	flags := flag.NewFlagSet("import-securities", flag.ExitOnError)
+	file := flags.String("file", "", "CSV with ticker and sector columns and optional industry and company (default SECURITIES_PATH)")
+	flags.Parse(args)
+
+	// Load configuration
+	cfg := config.Load()
+	if *file == "" {
+		*file = cfg.SecuritiesPath
+	}
+	if *file == "" {
+		log.Fatalf("No securities file; pass -file or set SECURITIES_PATH")
+	}
+
+	securities, err := services.LoadSecurities(*file)
+	if err != nil {
+		log.Fatalf("Failed to load securities: %v", err)
+	}
+
+	db := openCurrentDatabase(cfg)
+	defer db.Close()
+
+	if err := repository.NewSecurityRepository(db).UpsertSecurities(context.Background(), securities); err != nil {
+		log.Fatalf("Failed to store securities: %v", err)
+	}
+	fmt.Printf("securities updated for %d tickers\n", len(securities))
+}
+
+// openCurrentDatabase connects and refuses to touch a schema that is behind.
+func openCurrentDatabase(cfg *config.Config) *repository.Database {
+	db, err := repository.NewDatabase(cfg.DatabaseURL, repository.DatabaseOptionsFromConfig(cfg))
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	migrator, err := repository.NewMigrator(db)
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
+	}
+	if err := migrator.EnsureCurrent(context.Background()); err != nil {
+		log.Fatalf("Failed to verify database schema: %v (run `go run cmd/migrate/main.go up`)", err)
+	}
+
+	return db
+}
+
+func formatAge(age time.Duration) string {
+	days := age / (24 * time.Hour)
+	if days*24*time.Hour == age {
+		return fmt.Sprintf("%dd", days)
+	}
+	return age.String()
+}
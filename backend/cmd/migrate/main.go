@@ -1,27 +1,120 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log"
+	"os"
+	"strconv"
 
 	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/config"
 	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository/migrations"
 )
 
+const usage = `Usage: migrate [flags] <command> [arg]
+
+Commands:
+  up             Apply all pending migrations (default)
+  down N         Roll back the N most recently applied migrations
+  status         List migrations and whether they are applied
+  goto V         Migrate up or down to version V (0 rolls back everything)
+  create NAME    Create an empty up/down migration pair in -dir
+
+Flags:
+`
+
 func main() {
+	dir := flag.String("dir", "internal/repository/migrations", "directory where `create` writes new migration files")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	command := flag.Arg(0)
+	if command == "" {
+		command = "up"
+	}
+
+	// create only touches the source tree, no database needed
+	if command == "create" {
+		if flag.Arg(1) == "" {
+			flag.Usage()
+			os.Exit(2)
+		}
+		paths, err := migrations.Create(*dir, flag.Arg(1))
+		if err != nil {
+			log.Fatalf("Failed to create migration: %v", err)
+		}
+		for _, path := range paths {
+			log.Printf("Created %s", path)
+		}
+		return
+	}
+
 	// Load configuration
 	cfg := config.Load()
 
 	// Initialize database
-	db, err := repository.NewDatabase(cfg.DatabaseURL)
+	db, err := repository.NewDatabase(cfg.DatabaseURL, repository.DatabaseOptionsFromConfig(cfg))
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 	defer db.Close()
 
-	// Run migrations
-	if err := db.InitSchema(); err != nil {
-		log.Fatalf("Failed to run migrations: %v", err)
+	ctx := context.Background()
+	migrator, err := repository.NewMigrator(db)
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
 	}
 
-	log.Println("Migrations completed successfully")
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			log.Fatalf("Failed to run migrations: %v", err)
+		}
+		log.Printf("Migrations completed successfully (%d applied)", applied)
+
+	case "down":
+		n, err := strconv.Atoi(flag.Arg(1))
+		if err != nil || n <= 0 {
+			log.Fatalf("down requires a positive number of migrations, got %q", flag.Arg(1))
+		}
+		reverted, err := migrator.Down(ctx, n)
+		if err != nil {
+			log.Fatalf("Failed to roll back migrations: %v", err)
+		}
+		log.Printf("Rolled back %d migrations", reverted)
+
+	case "goto":
+		version, err := strconv.ParseInt(flag.Arg(1), 10, 64)
+		if err != nil || version < 0 {
+			log.Fatalf("goto requires a migration version, got %q", flag.Arg(1))
+		}
+		changed, err := migrator.Goto(ctx, version)
+		if err != nil {
+			log.Fatalf("Failed to migrate to version %d: %v", version, err)
+		}
+		log.Printf("Migrated to version %d (%d migrations run)", version, changed)
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatalf("Failed to read migration status: %v", err)
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.Applied {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-40s %s\n", status.Version, status.Name, state)
+		}
+
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
 }
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
 	"log"
 
 	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/api"
 	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/config"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
 	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository"
 	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/services"
 )
@@ -14,29 +16,119 @@ func main() {
 	cfg := config.Load()
 
 	// Initialize database
-	db, err := repository.NewDatabase(cfg.DatabaseURL)
+	db, err := repository.NewDatabase(cfg.DatabaseURL, repository.DatabaseOptionsFromConfig(cfg))
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 	defer db.Close()
 
-	// Initialize database schema
-	if err := db.InitSchema(); err != nil {
-		log.Fatalf("Failed to initialize database schema: %v", err)
+	// Refuse to start on an outdated schema; migrations are applied by cmd/migrate
+	migrator, err := repository.NewMigrator(db)
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
+	}
+	if err := migrator.EnsureCurrent(context.Background()); err != nil {
+		log.Fatalf("Failed to verify database schema: %v (run `go run cmd/migrate/main.go up`)", err)
 	}
 
 	// Initialize repositories
 	stockRepo := repository.NewStockRepository(db)
+	if tickers, err := stockRepo.BackfillConsensus(context.Background()); err != nil {
+		log.Fatalf("Failed to backfill ticker consensus: %v", err)
+	} else if tickers > 0 {
+		log.Printf("Backfilled ticker consensus for %d tickers", tickers)
+	}
 
 	// Initialize services
-	stockService := services.NewStockService(stockRepo, cfg.StockAPIURL, cfg.StockAPIKey)
-	recommendationService := services.NewRecommendationService(stockService)
+	stockService := services.NewStockService(stockRepo, cfg.StockAPIURL, cfg.StockAPIKey, cfg.SyncWriteMode)
+	if cfg.SyncKeepPayloads {
+		stockService.KeepPayloads(repository.NewPayloadRepository(db))
+	}
+	strategies, err := services.NewStrategyRegistry(services.NewDefaultStrategy(), services.NewConsensusStrategy())
+	if err != nil {
+		log.Fatalf("Failed to register scoring strategies: %v", err)
+	}
+	profiles, err := services.NewScoringProfileStore(cfg.ScoringProfilePath)
+	if err != nil {
+		log.Fatalf("Failed to load scoring profile: %v", err)
+	}
+	profiles.StartWatching(context.Background(), cfg.ScoringProfileReloadInterval)
+	log.Printf("Using scoring profile %s", profiles.Active().Version)
+	prices, err := loadPriceHistory(cfg)
+	if err != nil {
+		log.Fatalf("Failed to load price history: %v", err)
+	}
+	credibilityService, err := newCredibilityService(cfg, stockService, profiles, prices)
+	if err != nil {
+		log.Fatalf("Failed to load brokerage credibility: %v", err)
+	}
+	if err := credibilityService.Refresh(context.Background()); err != nil {
+		log.Printf("Failed to compute brokerage credibility: %v", err)
+	}
+	credibilityService.StartRefreshing(context.Background(), cfg.CredibilityRefreshInterval)
+	securityService, err := newSecurityService(cfg, db)
+	if err != nil {
+		log.Fatalf("Failed to load securities: %v", err)
+	}
+	if err := securityService.Refresh(context.Background()); err != nil {
+		log.Printf("Failed to read securities: %v", err)
+	}
+	securityService.StartRefreshing(context.Background(), cfg.SecuritiesRefreshInterval)
+	anomalyService, err := services.NewAnomalyService(repository.NewAnomalyRepository(db), stockService, securityService, services.AnomalySettings{
+		Window:          cfg.AnomalyWindow,
+		BaselineWindows: cfg.AnomalyBaselineWindows,
+		Threshold:       cfg.AnomalyZThreshold,
+		MinEvents:       cfg.AnomalyMinEvents,
+	})
+	if err != nil {
+		log.Fatalf("Invalid anomaly settings: %v", err)
+	}
+	if _, err := anomalyService.Detect(context.Background()); err != nil {
+		log.Printf("Failed to detect analyst anomalies: %v", err)
+	}
+	anomalyService.StartSchedule(context.Background(), cfg.AnomalyInterval)
+	if cfg.AnomalyAfterSync {
+		stockService.OnSyncCompleted(func(ctx context.Context, run *models.SyncRun) {
+			if _, err := anomalyService.Detect(ctx); err != nil {
+				log.Printf("Error detecting anomalies after sync %s: %v", run.ID, err)
+			}
+		})
+	}
+	recommendationService := services.NewRecommendationService(stockService, strategies, profiles, credibilityService, securityService, anomalyService)
+	backtestService := services.NewBacktestService(stockService, strategies, profiles, prices)
+	snapshotService, err := services.NewSnapshotService(repository.NewSnapshotRepository(db), recommendationService, cfg.SnapshotStrategies, cfg.SnapshotSize)
+	if err != nil {
+		log.Fatalf("Invalid snapshot settings: %v", err)
+	}
+	snapshotService.StartSchedule(context.Background(), cfg.SnapshotInterval)
+	if cfg.SnapshotAfterSync {
+		stockService.OnSyncCompleted(func(ctx context.Context, run *models.SyncRun) {
+			if _, err := snapshotService.TakeSnapshots(ctx, models.SnapshotSourceSync); err != nil {
+				log.Printf("Error taking snapshots after sync %s: %v", run.ID, err)
+			}
+		})
+	}
+
+	// Background retention is optional; cmd/maintenance prune runs it on demand
+	if cfg.RetentionInterval > 0 {
+		retentionService, err := services.NewRetentionService(repository.NewRetentionRepository(db), cfg.Retention, cfg.RetentionArchiveDir)
+		if err != nil {
+			log.Fatalf("Invalid retention rules: %v", err)
+		}
+		retentionService.StartBackground(context.Background(), cfg.RetentionInterval)
+	}
 
 	// Initialize handlers
 	stockHandler := api.NewStockHandler(stockService, recommendationService)
+	brokerageHandler := api.NewBrokerageHandler(credibilityService)
+	backtestHandler := api.NewBacktestHandler(backtestService)
+	snapshotHandler := api.NewSnapshotHandler(snapshotService)
+	anomalyHandler := api.NewAnomalyHandler(anomalyService)
+	screenHandler := api.NewScreenHandler(services.NewScreenService(repository.NewScreenRepository(db), stockService, securityService))
+	adminHandler := api.NewAdminHandler(db, profiles)
 
 	// Setup router
-	router := api.SetupRouter(stockHandler, cfg.AllowedOrigins)
+	router := api.SetupRouter(stockHandler, brokerageHandler, backtestHandler, snapshotHandler, anomalyHandler, screenHandler, adminHandler, cfg.AllowedOrigins, cfg.AdminToken)
 
 	// Start server
 	log.Printf("Server starting on port %s...", cfg.Port)
@@ -44,3 +136,37 @@ func main() {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
+
+// loadPriceHistory loads the optional daily price file.
+func loadPriceHistory(cfg *config.Config) (*services.PriceHistory, error) {
+	if cfg.PriceHistoryPath == "" {
+		return nil, nil
+	}
+	return services.LoadPriceHistory(cfg.PriceHistoryPath)
+}
+
+// newCredibilityService loads the optional tiers file.
+func newCredibilityService(cfg *config.Config, stocks services.StockLister, profiles *services.ScoringProfileStore, prices *services.PriceHistory) (*services.CredibilityService, error) {
+	var tiers *services.CredibilityTiers
+	if cfg.CredibilityTiersPath != "" {
+		var err error
+		if tiers, err = services.LoadCredibilityTiers(cfg.CredibilityTiersPath); err != nil {
+			return nil, err
+		}
+	}
+
+	return services.NewCredibilityService(stocks, profiles, tiers, prices, cfg.CredibilityHorizon), nil
+}
+
+// newSecurityService loads the optional securities file.
+func newSecurityService(cfg *config.Config, db *repository.Database) (*services.SecurityService, error) {
+	var file []models.Security
+	if cfg.SecuritiesPath != "" {
+		var err error
+		if file, err = services.LoadSecurities(cfg.SecuritiesPath); err != nil {
+			return nil, err
+		}
+	}
+
+	return services.NewSecurityService(repository.NewSecurityRepository(db), file), nil
+}
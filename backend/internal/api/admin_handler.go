@@ -0,0 +1,59 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// maxProfileSize bounds an uploaded scoring profile.
+const maxProfileSize = 1 << 20
+
+// AdminHandler serves operational endpoints under /api/admin. They expose the
+// database and change every score, so the router only mounts them behind
+// adminAuthMiddleware.
+type AdminHandler struct {
+	db       *repository.Database
+	profiles *services.ScoringProfileStore
+}
+
+func NewAdminHandler(db *repository.Database, profiles *services.ScoringProfileStore) *AdminHandler {
+	return &AdminHandler{db: db, profiles: profiles}
+}
+
+func (h *AdminHandler) GetDatabaseStats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.db.Stats())
+}
+
+func (h *AdminHandler) GetScoringProfile(c *gin.Context) {
+	c.JSON(http.StatusOK, h.profiles.Active())
+}
+
+// PutScoringProfile activates a YAML or JSON profile sent as the body.
+func (h *AdminHandler) PutScoringProfile(c *gin.Context) {
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxProfileSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile, err := h.profiles.Replace(data)
+	if errors.Is(err, services.ErrInvalidProfile) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrProfileVersionConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// AnomalyHandler serves detected analyst anomalies under /api/anomalies.
+type AnomalyHandler struct {
+	anomalyService *services.AnomalyService
+}
+
+func NewAnomalyHandler(anomalyService *services.AnomalyService) *AnomalyHandler {
+	return &AnomalyHandler{anomalyService: anomalyService}
+}
+
+// GetAnomalies lists anomalies, latest windows and strongest first,
+// optionally by scope, subject, kind, direction and the windows of the last
+// ?days=.
+func (h *AnomalyHandler) GetAnomalies(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	filter := repository.AnomalyFilter{
+		Scope:     c.Query("scope"),
+		Subject:   c.Query("subject"),
+		Kind:      c.Query("kind"),
+		Direction: c.Query("direction"),
+	}
+	if value := c.Query("days"); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil || days <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'days' must be a positive number"})
+			return
+		}
+		filter.Since = time.Now().AddDate(0, 0, -days)
+	}
+
+	anomalies, err := h.anomalyService.List(c.Request.Context(), filter, limit, offset)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":   anomalies,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// DetectAnomalies checks the latest window now instead of waiting for the
+// schedule or the next sync.
+func (h *AnomalyHandler) DetectAnomalies(c *gin.Context) {
+	anomalies, err := h.anomalyService.Detect(c.Request.Context())
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": anomalies})
+}
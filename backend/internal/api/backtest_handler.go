@@ -0,0 +1,94 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// BacktestHandler runs backtests as background jobs under /api/backtests.
+type BacktestHandler struct {
+	backtestService *services.BacktestService
+}
+
+func NewBacktestHandler(backtestService *services.BacktestService) *BacktestHandler {
+	return &BacktestHandler{backtestService: backtestService}
+}
+
+// BacktestRequest is models.BacktestRequest with dates as YYYY-MM-DD; every
+// field is optional.
+type BacktestRequest struct {
+	Strategy    string `json:"strategy"`
+	From        string `json:"from"`
+	To          string `json:"to"`
+	StepDays    int    `json:"step_days"`
+	HorizonDays int    `json:"horizon_days"`
+	TopN        int    `json:"top_n"`
+	Benchmark   string `json:"benchmark"`
+}
+
+// StartBacktest validates the request and answers with the job to poll.
+func (h *BacktestHandler) StartBacktest(c *gin.Context) {
+	var body BacktestRequest
+	// An empty body runs the defaults
+	if err := c.ShouldBindJSON(&body); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	req := models.BacktestRequest{
+		Strategy:    body.Strategy,
+		StepDays:    body.StepDays,
+		HorizonDays: body.HorizonDays,
+		TopN:        body.TopN,
+		Benchmark:   body.Benchmark,
+	}
+	for _, date := range []struct {
+		value  string
+		target *time.Time
+	}{{body.From, &req.From}, {body.To, &req.To}} {
+		if date.value == "" {
+			continue
+		}
+		parsed, err := services.ParseDate(date.value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Dates must be YYYY-MM-DD"})
+			return
+		}
+		*date.target = parsed
+	}
+
+	job, err := h.backtestService.Start(req)
+	switch {
+	case errors.Is(err, services.ErrInvalidBacktest), errors.Is(err, services.ErrUnknownStrategy):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrNoPriceHistory):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+func (h *BacktestHandler) GetBacktest(c *gin.Context) {
+	job, err := h.backtestService.GetJob(c.Param("id"))
+	if errors.Is(err, services.ErrBacktestNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Backtest not found"})
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// BrokerageHandler serves brokerage credibility under /api/brokerages.
+type BrokerageHandler struct {
+	credibilityService *services.CredibilityService
+}
+
+func NewBrokerageHandler(credibilityService *services.CredibilityService) *BrokerageHandler {
+	return &BrokerageHandler{credibilityService: credibilityService}
+}
+
+func (h *BrokerageHandler) GetBrokerages(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": h.credibilityService.All(),
+	})
+}
+
+// GetCredibility looks a brokerage up by id ("goldman-sachs") or name.
+func (h *BrokerageHandler) GetCredibility(c *gin.Context) {
+	credibility, err := h.credibilityService.Get(c.Param("id"))
+	if errors.Is(err, services.ErrBrokerageNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Brokerage not found"})
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, credibility)
+}
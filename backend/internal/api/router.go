@@ -1,12 +1,15 @@
 package api
 
 import (
+	"crypto/subtle"
+	"net/http"
 	"strings"
 
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository"
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRouter(handler *StockHandler, allowedOrigins string) *gin.Engine {
+func SetupRouter(handler *StockHandler, brokerageHandler *BrokerageHandler, backtestHandler *BacktestHandler, snapshotHandler *SnapshotHandler, anomalyHandler *AnomalyHandler, screenHandler *ScreenHandler, adminHandler *AdminHandler, allowedOrigins, adminToken string) *gin.Engine {
 	router := gin.Default()
 
 	// CORS middleware
@@ -21,15 +24,54 @@ func SetupRouter(handler *StockHandler, allowedOrigins string) *gin.Engine {
 
 	// API routes
 	api := router.Group("/api")
+	api.Use(freshReadsMiddleware())
 	{
 		// Stocks routes
 		api.GET("/stocks", handler.GetStocks)
 		api.GET("/stocks/:id", handler.GetStockByID)
+		api.GET("/stocks/:id/history", handler.GetStockHistory)
 		api.GET("/stocks/search", handler.SearchStocks)
+		api.GET("/tickers", handler.GetTickers)
+		api.GET("/tickers/:ticker", handler.GetTicker)
+		api.GET("/tickers/:ticker/targets", handler.GetTickerTargets)
 		api.POST("/sync", handler.SyncStocks)
+		api.GET("/sync/runs/:id", handler.GetSyncRun)
 
 		// Recommendations route
 		api.GET("/recommendations", handler.GetRecommendations)
+		api.GET("/recommendations/strategies", handler.GetStrategies)
+		api.GET("/recommendations/avoid", handler.GetAvoidList)
+		api.GET("/recommendations/snapshots", snapshotHandler.GetSnapshots)
+		api.POST("/recommendations/snapshots", snapshotHandler.TakeSnapshot)
+		api.GET("/recommendations/snapshots/diff", snapshotHandler.DiffSnapshots)
+		api.GET("/recommendations/snapshots/:id", snapshotHandler.GetSnapshot)
+
+		// Brokerages routes
+		api.GET("/brokerages", brokerageHandler.GetBrokerages)
+		api.GET("/brokerages/:id/credibility", brokerageHandler.GetCredibility)
+
+		// Anomaly routes
+		api.GET("/anomalies", anomalyHandler.GetAnomalies)
+		api.POST("/anomalies/detect", anomalyHandler.DetectAnomalies)
+
+		// Screen routes
+		api.GET("/screens", screenHandler.GetScreens)
+		api.GET("/screens/:name", screenHandler.GetScreen)
+		api.PUT("/screens/:name", screenHandler.PutScreen)
+		api.DELETE("/screens/:name", screenHandler.DeleteScreen)
+		api.GET("/screens/:name/results", screenHandler.GetScreenResults)
+
+		// Backtest routes
+		api.POST("/backtests", backtestHandler.StartBacktest)
+		api.GET("/backtests/:id", backtestHandler.GetBacktest)
+
+		// Admin routes, only served when an admin token is configured
+		if adminToken != "" {
+			admin := api.Group("/admin", adminAuthMiddleware(adminToken))
+			admin.GET("/db/stats", adminHandler.GetDatabaseStats)
+			admin.GET("/scoring-profile", adminHandler.GetScoringProfile)
+			admin.PUT("/scoring-profile", adminHandler.PutScoringProfile)
+		}
 	}
 
 	return router
@@ -45,7 +87,7 @@ func corsMiddleware(allowedOrigins string) gin.HandlerFunc {
 		}
 
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, X-Fresh-Read, Authorization, accept, origin, Cache-Control, X-Requested-With")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
@@ -57,6 +99,30 @@ func corsMiddleware(allowedOrigins string) gin.HandlerFunc {
 	}
 }
 
+// freshReadsMiddleware lets a client opt out of replica and follower reads
+// with ?fresh=true or an X-Fresh-Read: true header, e.g. right after a sync.
+func freshReadsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Query("fresh") == "true" || strings.EqualFold(c.GetHeader("X-Fresh-Read"), "true") {
+			c.Request = c.Request.WithContext(repository.WithFreshReads(c.Request.Context()))
+		}
+		c.Next()
+	}
+}
+
+// adminAuthMiddleware requires an Authorization: Bearer header carrying
+// token.
+func adminAuthMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		given, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "admin token required"})
+			return
+		}
+		c.Next()
+	}
+}
+
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
 		if strings.TrimSpace(s) == item {
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+func TestAdminRoutesRequireToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	profiles, err := services.NewScoringProfileStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin := NewAdminHandler(nil, profiles)
+
+	tests := []struct {
+		name          string
+		token         string
+		authorization string
+		want          int
+	}{
+		{"disabled without a token", "", "Bearer anything", http.StatusNotFound},
+		{"missing header", "secret", "", http.StatusUnauthorized},
+		{"wrong token", "secret", "Bearer nope", http.StatusUnauthorized},
+		{"not a bearer", "secret", "secret", http.StatusUnauthorized},
+		{"right token", "secret", "Bearer secret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := SetupRouter(nil, nil, nil, nil, nil, nil, admin, "*", tt.token)
+			req := httptest.NewRequest(http.MethodGet, "/api/admin/scoring-profile", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestFreshReadsMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name   string
+		target string
+		header string
+		want   bool
+	}{
+		{"stale by default", "/", "", false},
+		{"fresh query", "/?fresh=true", "", true},
+		{"other query value", "/?fresh=1", "", false},
+		{"fresh header", "/", "TRUE", true},
+		{"other header value", "/", "yes", false},
+	}
+	for _, tt := range tests {
+		router := gin.New()
+		router.Use(freshReadsMiddleware())
+		var fresh bool
+		router.GET("/", func(c *gin.Context) {
+			fresh = repository.FreshReadsRequested(c.Request.Context())
+		})
+
+		req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+		if tt.header != "" {
+			req.Header.Set("X-Fresh-Read", tt.header)
+		}
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		if fresh != tt.want {
+			t.Errorf("%s: fresh reads %v, want %v", tt.name, fresh, tt.want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// ScreenHandler serves saved screens under /api/screens.
+type ScreenHandler struct {
+	screenService *services.ScreenService
+}
+
+func NewScreenHandler(screenService *services.ScreenService) *ScreenHandler {
+	return &ScreenHandler{screenService: screenService}
+}
+
+// GetScreens lists saved screens by name.
+func (h *ScreenHandler) GetScreens(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	screens, err := h.screenService.List(c.Request.Context(), limit, offset)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":   screens,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+func (h *ScreenHandler) GetScreen(c *gin.Context) {
+	screen, err := h.screenService.Get(c.Request.Context(), c.Param("name"))
+	if errors.Is(err, repository.ErrScreenNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Screen not found"})
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, screen)
+}
+
+// PutScreen saves the screen named in the path from a body of
+// {"expression": ..., "description": ...}. Expressions that do not parse or
+// type-check are rejected with the position of the problem.
+func (h *ScreenHandler) PutScreen(c *gin.Context) {
+	var body struct {
+		Expression  string `json:"expression"`
+		Description string `json:"description"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	screen, created, err := h.screenService.Save(c.Request.Context(), c.Param("name"), body.Expression, body.Description)
+	if errors.Is(err, services.ErrInvalidScreen) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, screen)
+}
+
+func (h *ScreenHandler) DeleteScreen(c *gin.Context) {
+	err := h.screenService.Delete(c.Request.Context(), c.Param("name"))
+	if errors.Is(err, repository.ErrScreenNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Screen not found"})
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetScreenResults runs a saved screen and returns a page of the tickers
+// passing it.
+func (h *ScreenHandler) GetScreenResults(c *gin.Context) {
+	limit, offset := 50, 0
+	for _, param := range []struct {
+		name   string
+		target *int
+		min    int
+	}{{"limit", &limit, 1}, {"offset", &offset, 0}} {
+		value := c.Query(param.name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < param.min {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter '" + param.name + "' must be a number of at least " + strconv.Itoa(param.min)})
+			return
+		}
+		*param.target = n
+	}
+
+	results, err := h.screenService.Results(c.Request.Context(), c.Param("name"), limit, offset)
+	if errors.Is(err, repository.ErrScreenNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Screen not found"})
+		return
+	}
+	if errors.Is(err, services.ErrInvalidScreen) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrNoSectorData) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// SnapshotHandler serves recommendation snapshots under
+// /api/recommendations/snapshots.
+type SnapshotHandler struct {
+	snapshotService *services.SnapshotService
+}
+
+func NewSnapshotHandler(snapshotService *services.SnapshotService) *SnapshotHandler {
+	return &SnapshotHandler{snapshotService: snapshotService}
+}
+
+// GetSnapshots lists snapshots newest first, optionally by strategy, side
+// and source.
+func (h *SnapshotHandler) GetSnapshots(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	filter := repository.SnapshotFilter{
+		Strategy: c.Query("strategy"),
+		Side:     c.Query("side"),
+		Source:   c.Query("source"),
+	}
+
+	snapshots, err := h.snapshotService.List(c.Request.Context(), filter, limit, offset)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":   snapshots,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+func (h *SnapshotHandler) GetSnapshot(c *gin.Context) {
+	snapshot, err := h.snapshotService.Get(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, repository.ErrSnapshotNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Snapshot not found"})
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, snapshot)
+}
+
+// TakeSnapshot records the current rankings of every snapshotted strategy.
+func (h *SnapshotHandler) TakeSnapshot(c *gin.Context) {
+	snapshots, err := h.snapshotService.TakeSnapshots(c.Request.Context(), models.SnapshotSourceManual)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": snapshots})
+}
+
+// DiffSnapshots compares ?from= with ?to=, or without them the two latest
+// snapshots of ?strategy= and ?side=.
+func (h *SnapshotHandler) DiffSnapshots(c *gin.Context) {
+	from, to := c.Query("from"), c.Query("to")
+	if (from == "") != (to == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameters 'from' and 'to' go together"})
+		return
+	}
+
+	diff, err := h.snapshotService.Diff(c.Request.Context(), from, to, c.Query("strategy"), c.Query("side"))
+	if errors.Is(err, repository.ErrSnapshotNotFound) || errors.Is(err, services.ErrNotEnoughSnapshots) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
@@ -1,10 +1,17 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository"
 	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/services"
 	"github.com/gin-gonic/gin"
 )
@@ -25,13 +32,13 @@ func (h *StockHandler) GetStocks(c *gin.Context) {
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
 	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
 
-	stocks, err := h.stockService.GetAllStocks(limit, offset)
+	stocks, err := h.stockService.GetAllStocks(c.Request.Context(), limit, offset)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	total, _ := h.stockService.GetTotalCount()
+	total, _ := h.stockService.GetTotalCount(c.Request.Context())
 
 	c.JSON(http.StatusOK, gin.H{
 		"data":   stocks,
@@ -44,15 +51,38 @@ func (h *StockHandler) GetStocks(c *gin.Context) {
 func (h *StockHandler) GetStockByID(c *gin.Context) {
 	id := c.Param("id")
 
-	stock, err := h.stockService.GetStockByID(id)
-	if err != nil {
+	stock, err := h.stockService.GetStockByID(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrStockNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Stock not found"})
 		return
 	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
 
 	c.JSON(http.StatusOK, stock)
 }
 
+func (h *StockHandler) GetStockHistory(c *gin.Context) {
+	id := c.Param("id")
+
+	revisions, err := h.stockService.GetStockHistory(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrStockNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Stock not found"})
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"stock_id": id,
+		"data":     revisions,
+	})
+}
+
 func (h *StockHandler) SearchStocks(c *gin.Context) {
 	query := c.Query("q")
 	if query == "" {
@@ -60,9 +90,9 @@ func (h *StockHandler) SearchStocks(c *gin.Context) {
 		return
 	}
 
-	stocks, err := h.stockService.SearchStocks(query)
+	stocks, err := h.stockService.SearchStocks(c.Request.Context(), query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -71,40 +101,235 @@ func (h *StockHandler) SearchStocks(c *gin.Context) {
 	})
 }
 
+func (h *StockHandler) GetTickers(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	tickers, err := h.stockService.GetAllConsensus(c.Request.Context(), limit, offset)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	total, _ := h.stockService.GetTickerCount(c.Request.Context())
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":   tickers,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+func (h *StockHandler) GetTicker(c *gin.Context) {
+	consensus, err := h.stockService.GetConsensus(c.Request.Context(), c.Param("ticker"))
+	if errors.Is(err, repository.ErrTickerNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ticker not found"})
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, consensus)
+}
+
+// GetTickerTargets returns the spread of the latest brokerage targets on a
+// ticker, in ?currency= or the one most of them use.
+func (h *StockHandler) GetTickerTargets(c *gin.Context) {
+	spread, err := h.stockService.GetTargetSpread(c.Request.Context(), c.Param("ticker"), strings.ToUpper(c.Query("currency")))
+	if errors.Is(err, repository.ErrTickerNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ticker not found"})
+		return
+	}
+	if errors.Is(err, services.ErrInvalidCurrency) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, spread)
+}
+
 type SyncRequest struct {
 	Pages int `json:"pages"`
+	// ResumeRunID continues a failed run from its checkpoint instead of
+	// starting a new one
+	ResumeRunID string `json:"resume_run_id"`
 }
 
 func (h *StockHandler) SyncStocks(c *gin.Context) {
 	var req SyncRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		// Si no hay body o está mal formado, usar valor por defecto
-		req.Pages = 0
+		req = SyncRequest{}
+	}
+
+	var run *models.SyncRun
+	var err error
+	if req.ResumeRunID != "" {
+		run, err = h.stockService.ResumeSync(c.Request.Context(), req.ResumeRunID)
+	} else {
+		run, err = h.stockService.StartSync(c.Request.Context(), req.Pages)
+	}
+	if errors.Is(err, repository.ErrSyncRunNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sync run not found"})
+		return
+	}
+	if errors.Is(err, services.ErrSyncRunNotResumable) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
 	}
 
+	// The sync outlives this request, so it gets its own context
 	go func() {
-		if err := h.stockService.FetchAndStoreStocks(req.Pages); err != nil {
+		if err := h.stockService.RunSync(context.Background(), run); err != nil {
 			// Log error but don't block the response
 			log.Printf("Sync error: %v", err)
 		}
 	}()
 
 	c.JSON(http.StatusAccepted, gin.H{
-		"message": "Stock synchronization started",
-		"pages":   req.Pages,
+		"message":    "Stock synchronization started",
+		"run_id":     run.ID,
+		"pages":      run.MaxPages,
+		"write_mode": run.WriteMode,
 	})
 }
 
+func (h *StockHandler) GetSyncRun(c *gin.Context) {
+	run, err := h.stockService.GetSyncRun(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, repository.ErrSyncRunNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Sync run not found"})
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
 func (h *StockHandler) GetRecommendations(c *gin.Context) {
+	h.getRecommendations(c, c.Query("side"))
+}
+
+// GetAvoidList ranks the tickers with the strongest bearish signals.
+func (h *StockHandler) GetAvoidList(c *gin.Context) {
+	h.getRecommendations(c, models.SideSell)
+}
+
+func (h *StockHandler) getRecommendations(c *gin.Context, side string) {
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	query := services.RecommendationQuery{
+		Strategy: c.Query("strategy"),
+		Side:     side,
+		Limit:    limit,
+		Cursor:   c.Query("cursor"),
+		Filter: services.RecommendationFilter{
+			Sectors:        queryList(c, "sector"),
+			Brokerages:     queryList(c, "brokerage"),
+			ActionClasses:  queryList(c, "action"),
+			ExcludeTickers: queryList(c, "exclude"),
+		},
+	}
+	if value := c.Query("min_score"); value != "" {
+		minScore, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter 'min_score' must be a number"})
+			return
+		}
+		query.Filter.MinScore = minScore
+	}
+
+	// Optional paging, filters and diversification limits
+	var maxAgeDays int
+	for _, param := range []struct {
+		name   string
+		target *int
+	}{
+		{"offset", &query.Offset},
+		{"min_analysts", &query.Filter.MinAnalysts},
+		{"max_age_days", &maxAgeDays},
+		{"max_per_sector", &query.Diversification.MaxPerSector},
+		{"max_per_industry", &query.Diversification.MaxPerIndustry},
+		{"min_sectors", &query.Diversification.MinSectors},
+	} {
+		value := c.Query(param.name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Query parameter '%s' must be a number", param.name)})
+			return
+		}
+		*param.target = n
+	}
+	query.Filter.MaxAge = time.Duration(maxAgeDays) * 24 * time.Hour
 
-	recommendations, err := h.recommendationService.GetRecommendations(limit)
+	recommendations, err := h.recommendationService.GetRecommendations(c.Request.Context(), query)
+	if errors.Is(err, services.ErrUnknownStrategy) || errors.Is(err, services.ErrInvalidSide) || errors.Is(err, services.ErrInvalidDiversification) ||
+		errors.Is(err, services.ErrInvalidFilter) || errors.Is(err, services.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrNoSectorData) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
+	c.JSON(http.StatusOK, recommendations)
+}
+
+// queryList reads a list parameter given as ?name=a,b, as ?name=a&name=b or
+// both.
+func queryList(c *gin.Context, name string) []string {
+	var values []string
+	for _, param := range c.QueryArray(name) {
+		for _, value := range strings.Split(param, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+func (h *StockHandler) GetStrategies(c *gin.Context) {
+	var strategies []gin.H
+	for _, strategy := range h.recommendationService.Strategies() {
+		strategies = append(strategies, gin.H{
+			"name":    strategy.Name(),
+			"version": strategy.Version(),
+		})
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"data": recommendations,
+		"default": services.DefaultStrategyName,
+		"data":    strategies,
 	})
 }
+
+// respondError maps service errors to a status code. Statements cut off by
+// their timeout become 504 so clients can tell them from real failures.
+func respondError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, repository.ErrQueryTimeout) || errors.Is(err, context.DeadlineExceeded) {
+		status = http.StatusGatewayTimeout
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+func TestQueryTimeoutRespondsGatewayTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := repository.NewDatabase("sqlite://:memory:", repository.DatabaseOptions{
+		Timeouts: repository.QueryTimeouts{Read: time.Nanosecond},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	migrator, err := repository.NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	stocks := services.NewStockService(repository.NewStockRepository(db), "", "", models.SyncWriteAtomic)
+	router := SetupRouter(NewStockHandler(stocks, nil), nil, nil, nil, nil, nil, nil, "*", "")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/stocks", nil))
+
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status %d, want %d: %s", rec.Code, http.StatusGatewayTimeout, rec.Body)
+	}
+	var body struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(body.Error, repository.ErrQueryTimeout.Error()) {
+		t.Errorf("error %q, want it to start with %q", body.Error, repository.ErrQueryTimeout)
+	}
+}
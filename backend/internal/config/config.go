@@ -1,19 +1,89 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Port           string
-	Environment    string
-	DatabaseURL    string
-	StockAPIURL    string
-	StockAPIKey    string
-	AllowedOrigins string
+	Port        string
+	Environment string
+	DatabaseURL string
+	// DatabaseReadURL optionally points read-only queries at a replica
+	DatabaseReadURL string
+	StockAPIURL     string
+	StockAPIKey     string
+	AllowedOrigins  string
+	// AdminToken is the bearer token of /api/admin; empty disables it
+	AdminToken string
+
+	// Connection pool and resilience
+	DBMaxOpenConns         int
+	DBMaxIdleConns         int
+	DBConnMaxLifetime      time.Duration
+	DBConnMaxIdleTime      time.Duration
+	DBConnectRetries       int
+	DBConnectBackoff       time.Duration
+	DBSerializationRetries int
+	DBFollowerReads        bool
+
+	// Per-statement timeouts by operation class
+	DBReadTimeout   time.Duration
+	DBWriteTimeout  time.Duration
+	DBSearchTimeout time.Duration
+
+	// Retention maps a prunable table (stocks, stock_revisions, sync_runs,
+	// sync_payloads, recommendation_snapshots, analyst_anomalies) to how long
+	// its rows are kept. Tables without an entry are kept forever.
+	Retention           map[string]time.Duration
+	RetentionInterval   time.Duration
+	RetentionArchiveDir string
+
+	// SyncWriteMode is "atomic" (each provider page is stored all-or-nothing)
+	// or "best_effort" (failed rows are skipped and listed in the sync run)
+	SyncWriteMode string
+	// SyncKeepPayloads stores every raw provider response in sync_payloads
+	SyncKeepPayloads bool
+
+	// ScoringProfilePath is a YAML or JSON scoring profile; empty uses the
+	// built-in weights. The file is re-read when it changes.
+	ScoringProfilePath           string
+	ScoringProfileReloadInterval time.Duration
+
+	// Brokerage credibility comes from manual tiers (a YAML file) or from
+	// how brokerages' calls played out in a daily price CSV
+	CredibilityTiersPath       string
+	PriceHistoryPath           string
+	CredibilityHorizon         time.Duration
+	CredibilityRefreshInterval time.Duration
+
+	// SecuritiesPath is a CSV of ticker sectors and industries, laid over the
+	// securities table, which is re-read every SecuritiesRefreshInterval
+	SecuritiesPath            string
+	SecuritiesRefreshInterval time.Duration
+
+	// Recommendation snapshots record the top SnapshotSize tickers of each
+	// strategy every SnapshotInterval and, optionally, after each sync
+	SnapshotStrategies []string
+	SnapshotSize       int
+	SnapshotInterval   time.Duration
+	SnapshotAfterSync  bool
+
+	// Anomaly detection compares the latest AnomalyWindow of analyst events
+	// with the AnomalyBaselineWindows before it, every AnomalyInterval and,
+	// optionally, after each sync
+	AnomalyWindow          time.Duration
+	AnomalyBaselineWindows int
+	AnomalyZThreshold      float64
+	AnomalyMinEvents       int
+	AnomalyInterval        time.Duration
+	AnomalyAfterSync       bool
 }
 
 func Load() *Config {
@@ -23,13 +93,169 @@ func Load() *Config {
 	}
 
 	return &Config{
-		Port:           getEnv("PORT", "8080"),
-		Environment:    getEnv("ENV", "development"),
-		DatabaseURL:    getEnv("DATABASE_URL", ""),
-		StockAPIURL:    getEnv("STOCK_API_URL", ""),
-		StockAPIKey:    getEnv("STOCK_API_KEY", ""),
-		AllowedOrigins: getEnv("ALLOWED_ORIGINS", "*"),
+		Port:            getEnv("PORT", "8080"),
+		Environment:     getEnv("ENV", "development"),
+		DatabaseURL:     getEnv("DATABASE_URL", ""),
+		DatabaseReadURL: getEnv("DATABASE_READ_URL", ""),
+		StockAPIURL:     getEnv("STOCK_API_URL", ""),
+		StockAPIKey:     getEnv("STOCK_API_KEY", ""),
+		AllowedOrigins:  getEnv("ALLOWED_ORIGINS", "*"),
+		AdminToken:      getEnv("ADMIN_TOKEN", ""),
+
+		DBMaxOpenConns:         getInt("DB_MAX_OPEN_CONNS", 25),
+		DBMaxIdleConns:         getInt("DB_MAX_IDLE_CONNS", 10),
+		DBConnMaxLifetime:      getDuration("DB_CONN_MAX_LIFETIME", 30*time.Minute),
+		DBConnMaxIdleTime:      getDuration("DB_CONN_MAX_IDLE_TIME", 5*time.Minute),
+		DBConnectRetries:       getInt("DB_CONNECT_RETRIES", 10),
+		DBConnectBackoff:       getDuration("DB_CONNECT_BACKOFF", time.Second),
+		DBSerializationRetries: getInt("DB_SERIALIZATION_RETRIES", 5),
+		DBFollowerReads:        getEnv("DB_FOLLOWER_READS", "false") == "true",
+
+		DBReadTimeout:   getDuration("DB_READ_TIMEOUT", 5*time.Second),
+		DBWriteTimeout:  getDuration("DB_WRITE_TIMEOUT", 10*time.Second),
+		DBSearchTimeout: getDuration("DB_SEARCH_TIMEOUT", 10*time.Second),
+
+		Retention:           loadRetention(),
+		RetentionInterval:   getDuration("RETENTION_INTERVAL", 0),
+		RetentionArchiveDir: getEnv("RETENTION_ARCHIVE_DIR", ""),
+
+		SyncWriteMode:    getChoice("SYNC_WRITE_MODE", "atomic", "best_effort"),
+		SyncKeepPayloads: getEnv("SYNC_KEEP_PAYLOADS", "false") == "true",
+
+		ScoringProfilePath:           getEnv("SCORING_PROFILE_PATH", ""),
+		ScoringProfileReloadInterval: getDuration("SCORING_PROFILE_RELOAD_INTERVAL", 30*time.Second),
+
+		CredibilityTiersPath:       getEnv("CREDIBILITY_TIERS_PATH", ""),
+		PriceHistoryPath:           getEnv("PRICE_HISTORY_PATH", ""),
+		CredibilityHorizon:         getDuration("CREDIBILITY_HORIZON", 90*24*time.Hour),
+		CredibilityRefreshInterval: getDuration("CREDIBILITY_REFRESH_INTERVAL", time.Hour),
+
+		SecuritiesPath:            getEnv("SECURITIES_PATH", ""),
+		SecuritiesRefreshInterval: getDuration("SECURITIES_REFRESH_INTERVAL", time.Hour),
+
+		SnapshotStrategies: getList("SNAPSHOT_STRATEGIES", "default"),
+		SnapshotSize:       getInt("SNAPSHOT_SIZE", 50),
+		SnapshotInterval:   getDuration("SNAPSHOT_INTERVAL", 24*time.Hour),
+		SnapshotAfterSync:  getEnv("SNAPSHOT_AFTER_SYNC", "true") == "true",
+
+		AnomalyWindow:          getDuration("ANOMALY_WINDOW", 7*24*time.Hour),
+		AnomalyBaselineWindows: getInt("ANOMALY_BASELINE_WINDOWS", 12),
+		AnomalyZThreshold:      getFloat("ANOMALY_Z_THRESHOLD", 3),
+		AnomalyMinEvents:       getInt("ANOMALY_MIN_EVENTS", 3),
+		AnomalyInterval:        getDuration("ANOMALY_INTERVAL", 24*time.Hour),
+		AnomalyAfterSync:       getEnv("ANOMALY_AFTER_SYNC", "true") == "true",
+	}
+}
+
+func loadRetention() map[string]time.Duration {
+	retention := make(map[string]time.Duration)
+	for table, key := range map[string]string{
+		"stocks":                   "RETENTION_STOCKS",
+		"stock_revisions":          "RETENTION_STOCK_REVISIONS",
+		"sync_runs":                "RETENTION_SYNC_RUNS",
+		"sync_payloads":            "RETENTION_SYNC_PAYLOADS",
+		"recommendation_snapshots": "RETENTION_RECOMMENDATION_SNAPSHOTS",
+		"analyst_anomalies":        "RETENTION_ANALYST_ANOMALIES",
+	} {
+		if maxAge := getDuration(key, 0); maxAge > 0 {
+			retention[table] = maxAge
+		}
+	}
+	return retention
+}
+
+// getChoice reads one of the allowed values; the first one is the default.
+func getChoice(key string, allowed ...string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return allowed[0]
+	}
+
+	for _, choice := range allowed {
+		if value == choice {
+			return value
+		}
+	}
+	log.Printf("Invalid %s %q, using default %q", key, value, allowed[0])
+	return allowed[0]
+}
+
+func getInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Printf("Invalid %s %q, using default %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+func getFloat(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < 0 {
+		log.Printf("Invalid %s %q, using default %g", key, value, defaultValue)
+		return defaultValue
+	}
+	return f
+}
+
+// getDuration reads a Go duration ("720h") or a whole number of days, weeks
+// or years ("90d", "2w", "3y"). Invalid values fall back to the default.
+func getDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	duration, err := ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid %s %q, using default: %v", key, value, err)
+		return defaultValue
+	}
+	return duration
+}
+
+// ParseDuration extends time.ParseDuration with d (days), w (weeks) and
+// y (365 days) units for retention-style values.
+func ParseDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	units := map[string]time.Duration{
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+		"y": 365 * 24 * time.Hour,
+	}
+
+	for suffix, unit := range units {
+		if number, found := strings.CutSuffix(value, suffix); found {
+			n, err := strconv.Atoi(number)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid duration %q", value)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+
+	return time.ParseDuration(value)
+}
+
+// getList reads a comma-separated list, skipping blank items.
+func getList(key, defaultValue string) []string {
+	var items []string
+	for _, item := range strings.Split(getEnv(key, defaultValue), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
+	return items
 }
 
 func getEnv(key, defaultValue string) string {
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// What an anomaly was measured over
+const (
+	AnomalyScopeTicker = "ticker"
+	AnomalyScopeSector = "sector"
+)
+
+// Anomaly kinds: a burst of analyst events, or a swing in their direction
+// (bullish events minus bearish ones)
+const (
+	AnomalyKindVolume    = "volume"
+	AnomalyKindDirection = "direction"
+)
+
+// Directions of the events behind an anomaly
+const (
+	DirectionBullish = "bullish"
+	DirectionBearish = "bearish"
+	DirectionMixed   = "mixed"
+)
+
+// AnalystAnomaly is unusual analyst activity on one ticker or sector: the
+// window's Observed count against the mean and standard deviation of the
+// same count over the preceding windows. A direction anomaly is Bullish or
+// Bearish by the sign of its ZScore; a volume anomaly names the side most
+// of the window's events took.
+type AnalystAnomaly struct {
+	ID             string    `json:"id"`
+	Scope          string    `json:"scope"`
+	Subject        string    `json:"subject"`
+	Kind           string    `json:"kind"`
+	Direction      string    `json:"direction"`
+	WindowStart    time.Time `json:"window_start"`
+	WindowEnd      time.Time `json:"window_end"`
+	Observed       float64   `json:"observed"`
+	BaselineMean   float64   `json:"baseline_mean"`
+	BaselineStdDev float64   `json:"baseline_stddev"`
+	ZScore         float64   `json:"z_score"`
+	Events         int       `json:"events"`
+	Bullish        int       `json:"bullish"`
+	Bearish        int       `json:"bearish"`
+	DetectedAt     time.Time `json:"detected_at"`
+}
@@ -0,0 +1,76 @@
+package models
+
+import "time"
+
+// Backtest job statuses
+const (
+	BacktestRunning   = "running"
+	BacktestCompleted = "completed"
+	BacktestFailed    = "failed"
+)
+
+// BacktestRequest describes which rankings to replay: one every StepDays
+// from From to To, keeping the TopN tickers and holding each for
+// HorizonDays. Benchmark is a ticker of the price file; empty compares
+// against an equal-weight basket of every ticker with prices.
+type BacktestRequest struct {
+	Strategy    string    `json:"strategy"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	StepDays    int       `json:"step_days"`
+	HorizonDays int       `json:"horizon_days"`
+	TopN        int       `json:"top_n"`
+	Benchmark   string    `json:"benchmark"`
+}
+
+// BacktestPick is one ticker of a replayed ranking. Return is nil when the
+// price file does not cover the holding period.
+type BacktestPick struct {
+	Ticker string   `json:"ticker"`
+	Score  float64  `json:"score"`
+	Return *float64 `json:"return"`
+}
+
+// BacktestPeriod is the ranking a strategy would have produced from the
+// events known by the end of Date and how it performed over the horizon,
+// bought at the first close after Date.
+type BacktestPeriod struct {
+	Date            time.Time      `json:"date"`
+	Picks           []BacktestPick `json:"picks"`
+	AverageReturn   *float64       `json:"average_return"`
+	BenchmarkReturn *float64       `json:"benchmark_return"`
+}
+
+// BacktestSummary aggregates every pick with a known return. A hit is a
+// pick that went up; BeatRate is the share that outperformed the benchmark
+// over the same period.
+type BacktestSummary struct {
+	Periods                int      `json:"periods"`
+	Picks                  int      `json:"picks"`
+	Hits                   int      `json:"hits"`
+	HitRate                *float64 `json:"hit_rate"`
+	BeatRate               *float64 `json:"beat_rate"`
+	AverageReturn          *float64 `json:"average_return"`
+	BenchmarkAverageReturn *float64 `json:"benchmark_average_return"`
+	ExcessReturn           *float64 `json:"excess_return"`
+}
+
+// BacktestResult is the outcome of a replay.
+type BacktestResult struct {
+	BacktestRequest
+	StrategyVersion string           `json:"strategy_version"`
+	ProfileVersion  string           `json:"profile_version"`
+	Summary         BacktestSummary  `json:"summary"`
+	Periods         []BacktestPeriod `json:"periods"`
+}
+
+// BacktestJob tracks a backtest run in the background for the API.
+type BacktestJob struct {
+	ID          string          `json:"id"`
+	Status      string          `json:"status"`
+	Request     BacktestRequest `json:"request"`
+	Result      *BacktestResult `json:"result,omitempty"`
+	Error       string          `json:"error,omitempty"`
+	StartedAt   time.Time       `json:"started_at"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty"`
+}
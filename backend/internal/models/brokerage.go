@@ -0,0 +1,47 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Credibility sources, from most to least specific
+const (
+	CredibilitySourceTier    = "tier"
+	CredibilitySourceHistory = "history"
+	CredibilitySourceDefault = "default"
+)
+
+// BrokerageCredibility is how much a brokerage's signals are trusted: 1 is
+// neutral, above 1 amplifies and below 1 dampens them.
+type BrokerageCredibility struct {
+	ID          string  `json:"id"`
+	Brokerage   string  `json:"brokerage"`
+	Credibility float64 `json:"credibility"`
+	Source      string  `json:"source"`
+	Tier        string  `json:"tier,omitempty"`
+	// Events and Hits count the directional calls checked against prices
+	Events    int       `json:"events"`
+	Hits      int       `json:"hits"`
+	HitRate   *float64  `json:"hit_rate"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// BrokerageID turns a brokerage name into a URL-safe id, e.g.
+// "Morgan Stanley & Co." becomes "morgan-stanley-co".
+func BrokerageID(name string) string {
+	var id strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && id.Len() > 0 {
+				id.WriteByte('-')
+			}
+			id.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return id.String()
+}
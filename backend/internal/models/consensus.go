@@ -0,0 +1,132 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// BrokerageRating is the most recent view of one brokerage on a ticker
+type BrokerageRating struct {
+	Brokerage  string    `json:"brokerage"`
+	StockID    string    `json:"stock_id"`
+	RatingFrom string    `json:"rating_from"`
+	Rating     string    `json:"rating"`
+	TargetFrom string    `json:"target_from"`
+	Target     string    `json:"target"`
+	Action     string    `json:"action"`
+	Time       time.Time `json:"time"`
+}
+
+// TickerConsensus aggregates the latest view of every brokerage covering a
+// ticker. It is maintained as events are stored, so reads never have to
+// rebuild it from raw events.
+type TickerConsensus struct {
+	Ticker             string            `json:"ticker" db:"ticker"`
+	Company            string            `json:"company" db:"company"`
+	Brokerages         []BrokerageRating `json:"brokerages" db:"brokerage_ratings"`
+	RatingDistribution map[string]int    `json:"rating_distribution" db:"rating_distribution"`
+	CoveringBrokerages int               `json:"covering_brokerages" db:"covering_brokerages"`
+	MeanTarget         *float64          `json:"mean_target" db:"mean_target"`
+	MedianTarget       *float64          `json:"median_target" db:"median_target"`
+	HighTarget         *float64          `json:"high_target" db:"high_target"`
+	LowTarget          *float64          `json:"low_target" db:"low_target"`
+	// TargetCurrency is the currency of the target statistics, the one most
+	// covering brokerages write targets in; empty when none can be read
+	TargetCurrency string    `json:"target_currency" db:"target_currency"`
+	LatestStockID  string    `json:"latest_stock_id" db:"latest_stock_id"`
+	LastActionTime time.Time `json:"last_action_time" db:"last_action_time"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+	// Latest is the most recent event on the ticker, loaded with the row
+	Latest *Stock `json:"latest,omitempty"`
+}
+
+// Apply folds one stored event into the consensus. An event replaces a
+// brokerage's view only when it is at least as recent; among events with the
+// same time the lowest id wins. Apply returns false, leaving the consensus
+// untouched, when the event used to belong to another brokerage: that
+// brokerage's previous view is unknown and the consensus has to be rebuilt
+// from the ticker's events.
+func (c *TickerConsensus) Apply(stock *Stock) bool {
+	for _, view := range c.Brokerages {
+		if view.StockID == stock.ID && view.Brokerage != stock.Brokerage {
+			return false
+		}
+	}
+
+	c.Ticker = stock.Ticker
+	view := BrokerageRating{
+		Brokerage:  stock.Brokerage,
+		StockID:    stock.ID,
+		RatingFrom: stock.RatingFrom,
+		Rating:     stock.RatingTo,
+		TargetFrom: stock.TargetFrom,
+		Target:     stock.TargetTo,
+		Action:     stock.Action,
+		Time:       stock.Time.UTC(),
+	}
+
+	i := sort.Search(len(c.Brokerages), func(i int) bool {
+		return c.Brokerages[i].Brokerage >= stock.Brokerage
+	})
+	switch {
+	case i < len(c.Brokerages) && c.Brokerages[i].Brokerage == stock.Brokerage:
+		if newerEvent(view.Time, view.StockID, c.Brokerages[i].Time, c.Brokerages[i].StockID) {
+			c.Brokerages[i] = view
+		}
+	default:
+		c.Brokerages = append(c.Brokerages, BrokerageRating{})
+		copy(c.Brokerages[i+1:], c.Brokerages[i:])
+		c.Brokerages[i] = view
+	}
+
+	if c.LatestStockID == "" || newerEvent(view.Time, view.StockID, c.LastActionTime, c.LatestStockID) {
+		c.LatestStockID = stock.ID
+		c.LastActionTime = view.Time
+		c.Company = stock.Company
+	}
+
+	c.recompute()
+	return true
+}
+
+// NewTickerConsensus builds the consensus of one ticker from all its events.
+func NewTickerConsensus(stocks []Stock) *TickerConsensus {
+	c := &TickerConsensus{}
+	for i := range stocks {
+		c.Apply(&stocks[i])
+	}
+	return c
+}
+
+func (c *TickerConsensus) recompute() {
+	c.CoveringBrokerages = len(c.Brokerages)
+	c.RatingDistribution = make(map[string]int)
+
+	for _, view := range c.Brokerages {
+		if view.Rating != "" {
+			c.RatingDistribution[view.Rating]++
+		}
+	}
+
+	// Targets in other currencies are left out rather than converted
+	c.TargetCurrency = MainTargetCurrency(c.Brokerages)
+	var targets []float64
+	for _, view := range c.Brokerages {
+		if amount, currency, err := ParseTarget(view.Target); err == nil && currency == c.TargetCurrency {
+			targets = append(targets, amount)
+		}
+	}
+	if len(targets) == 0 {
+		c.TargetCurrency = ""
+	}
+	stats := NewTargetStats(targets)
+	c.MeanTarget, c.MedianTarget, c.HighTarget, c.LowTarget = stats.Mean, stats.Median, stats.High, stats.Low
+}
+
+// newerEvent orders events by time, newest first, then by id
+func newerEvent(aTime time.Time, aID string, bTime time.Time, bID string) bool {
+	if aTime.Equal(bTime) {
+		return aID <= bID
+	}
+	return aTime.After(bTime)
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func consensusEvents() []Stock {
+	base := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	event := func(id, brokerage, rating, target string, offset time.Duration) Stock {
+		return Stock{
+			ID: id, Ticker: "AAPL", Company: "Apple Inc. " + id, Brokerage: brokerage,
+			RatingFrom: "Hold", RatingTo: rating, TargetFrom: "$100.00", TargetTo: target,
+			Action: "target raised by", Time: base.Add(offset),
+		}
+	}
+	return []Stock{
+		event("a1", "Alpha", "Buy", "$120.00", 0),
+		event("a2", "Alpha", "Hold", "$110.00", 24*time.Hour),
+		event("b1", "Beta", "Sell", "$90.00", 12*time.Hour),
+		event("c1", "Gamma", "Buy", "", 36*time.Hour),
+		// Same time as c1: the lower id is the latest
+		event("c0", "Gamma", "Outperform", "$130.00", 36*time.Hour),
+		event("d1", "Delta", "", "N/A", -24*time.Hour),
+	}
+}
+
+func TestApplyMatchesRebuildInAnyOrder(t *testing.T) {
+	events := consensusEvents()
+	want := NewTickerConsensus(events)
+
+	orders := [][]int{{5, 4, 3, 2, 1, 0}, {2, 0, 4, 1, 5, 3}, {3, 1, 5, 0, 2, 4}}
+	for _, order := range orders {
+		// What updateConsensus reads back before folding in each event
+		c := &TickerConsensus{}
+		for _, i := range order {
+			stored := &TickerConsensus{
+				Ticker: c.Ticker, Company: c.Company, Brokerages: c.Brokerages,
+				LatestStockID: c.LatestStockID, LastActionTime: c.LastActionTime,
+			}
+			if !stored.Apply(&events[i]) {
+				t.Fatalf("order %v: Apply(%s) asked for a rebuild", order, events[i].ID)
+			}
+			c = stored
+		}
+
+		if !reflect.DeepEqual(c, want) {
+			t.Errorf("order %v:\n got %+v\nwant %+v", order, c, want)
+		}
+	}
+
+	if want.LatestStockID != "c0" || want.Company != "Apple Inc. c0" {
+		t.Errorf("latest event %s (%s), want c0", want.LatestStockID, want.Company)
+	}
+	if want.CoveringBrokerages != 4 {
+		t.Errorf("covering brokerages %d, want 4", want.CoveringBrokerages)
+	}
+	if !reflect.DeepEqual(want.RatingDistribution, map[string]int{"Hold": 1, "Sell": 1, "Outperform": 1}) {
+		t.Errorf("rating distribution %v", want.RatingDistribution)
+	}
+	if *want.MeanTarget != 110 || *want.MedianTarget != 110 || *want.HighTarget != 130 || *want.LowTarget != 90 {
+		t.Errorf("targets mean %v median %v high %v low %v, want 110 110 130 90",
+			*want.MeanTarget, *want.MedianTarget, *want.HighTarget, *want.LowTarget)
+	}
+}
+
+func TestApplyRefusesEventMovedToAnotherBrokerage(t *testing.T) {
+	events := consensusEvents()
+	c := NewTickerConsensus(events)
+	before := *c
+
+	moved := events[2]
+	moved.Brokerage = "Epsilon"
+	if c.Apply(&moved) {
+		t.Fatal("Apply should refuse an event that changed brokerage")
+	}
+	if !reflect.DeepEqual(*c, before) {
+		t.Error("a refused Apply changed the consensus")
+	}
+}
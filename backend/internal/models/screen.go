@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Screen is a saved expression of the screen language, run by name.
+type Screen struct {
+	Name        string    `json:"name"`
+	Expression  string    `json:"expression"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ScreenMatch is a ticker that passed a screen, with the values of the
+// fields the screen reads; unknown values are null.
+type ScreenMatch struct {
+	Ticker  string                 `json:"ticker"`
+	Company string                 `json:"company"`
+	Values  map[string]interface{} `json:"values"`
+	Latest  *Stock                 `json:"latest,omitempty"`
+}
+
+// ScreenResults is one page of the tickers passing a screen, in ticker
+// order. Prefiltered tells whether part of the expression ran in SQL.
+type ScreenResults struct {
+	Screen      Screen        `json:"screen"`
+	Fields      []string      `json:"fields"`
+	AsOf        time.Time     `json:"as_of"`
+	Prefiltered bool          `json:"prefiltered"`
+	Total       int           `json:"total"`
+	Limit       int           `json:"limit"`
+	Offset      int           `json:"offset"`
+	Data        []ScreenMatch `json:"data"`
+}
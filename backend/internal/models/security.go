@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Security is the reference data of a ticker that analyst events do not
+// carry, such as its sector and industry.
+type Security struct {
+	Ticker    string    `json:"ticker"`
+	Company   string    `json:"company"`
+	Sector    string    `json:"sector"`
+	Industry  string    `json:"industry"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Diversification limits how concentrated a recommendation list may be.
+// Zero leaves a limit off.
+type Diversification struct {
+	MaxPerSector   int `json:"max_per_sector,omitempty"`
+	MaxPerIndustry int `json:"max_per_industry,omitempty"`
+	MinSectors     int `json:"min_sectors,omitempty"`
+}
+
+// Enabled reports whether any limit is set.
+func (d Diversification) Enabled() bool {
+	return d.MaxPerSector > 0 || d.MaxPerIndustry > 0 || d.MinSectors > 0
+}
+
+// SkippedRecommendation is a ticker that scored high enough for the list
+// but was left out to respect a diversification limit.
+type SkippedRecommendation struct {
+	Ticker   string  `json:"ticker"`
+	Score    float64 `json:"score"`
+	Sector   string  `json:"sector"`
+	Industry string  `json:"industry"`
+	Reason   string  `json:"reason"`
+}
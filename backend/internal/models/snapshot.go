@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// What took a recommendation snapshot
+const (
+	SnapshotSourceSync      = "sync"
+	SnapshotSourceScheduled = "scheduled"
+	SnapshotSourceManual    = "manual"
+)
+
+// RecommendationSnapshot is a ranking as it was served at TakenAt, kept so
+// rankings can be compared over time. Entries are only loaded for a single
+// snapshot.
+type RecommendationSnapshot struct {
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Side            string          `json:"side"`
+	Strategy        string          `json:"strategy"`
+	StrategyVersion string          `json:"strategy_version"`
+	ProfileVersion  string          `json:"profile_version"`
+	Size            int             `json:"size"`
+	TakenAt         time.Time       `json:"taken_at"`
+	Entries         []SnapshotEntry `json:"entries,omitempty"`
+}
+
+// SnapshotEntry is one ranked ticker of a snapshot; Rank starts at 1.
+type SnapshotEntry struct {
+	Rank    int           `json:"rank"`
+	Ticker  string        `json:"ticker"`
+	StockID string        `json:"stock_id"`
+	Score   float64       `json:"score"`
+	Reason  string        `json:"reason"`
+	Factors []ScoreFactor `json:"factors"`
+}
+
+// SnapshotChange is how one ticker moved between two snapshots. The from
+// side is nil for tickers that entered the ranking and the to side for
+// those that left it; Change is the number of places gained.
+type SnapshotChange struct {
+	Ticker    string   `json:"ticker"`
+	FromRank  *int     `json:"from_rank"`
+	ToRank    *int     `json:"to_rank"`
+	FromScore *float64 `json:"from_score"`
+	ToScore   *float64 `json:"to_score"`
+	Change    int      `json:"change"`
+}
+
+// SnapshotDiff compares two snapshots without their entries.
+type SnapshotDiff struct {
+	From      RecommendationSnapshot `json:"from"`
+	To        RecommendationSnapshot `json:"to"`
+	Entered   []SnapshotChange       `json:"entered"`
+	Exited    []SnapshotChange       `json:"exited"`
+	MovedUp   []SnapshotChange       `json:"moved_up"`
+	MovedDown []SnapshotChange       `json:"moved_down"`
+	Unchanged int                    `json:"unchanged"`
+}
@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // Stock represents a stock entity
 type Stock struct {
@@ -37,9 +40,183 @@ type APIStockItem struct {
 	Time       string `json:"time"`
 }
 
-// StockRecommendation represents a recommended stock
+// StockRecommendation represents a recommended stock. Score is the sum of
+// the points of its factors, and Reason names the factors that earned any.
+// Sector and Industry are empty for tickers without reference data.
 type StockRecommendation struct {
-	Stock  *Stock  `json:"stock"`
-	Score  float64 `json:"score"`
-	Reason string  `json:"reason"`
+	Stock    *Stock        `json:"stock"`
+	Score    float64       `json:"score"`
+	Reason   string        `json:"reason"`
+	Factors  []ScoreFactor `json:"factors"`
+	Sector   string        `json:"sector,omitempty"`
+	Industry string        `json:"industry,omitempty"`
+}
+
+// ScoreFactor is one term of a score: what the strategy looked at (Input),
+// the profile weight applied to it, and the points it earned after its cap
+// and any decay or credibility weighting
+type ScoreFactor struct {
+	Name   string   `json:"name"`
+	Label  string   `json:"label"`
+	Input  string   `json:"input"`
+	Weight float64  `json:"weight"`
+	Points float64  `json:"points"`
+	Cap    *float64 `json:"cap"`
+}
+
+// Recommendation sides: buy ranks the most bullish tickers first, sell (the
+// avoid list) the most bearish
+const (
+	SideBuy  = "buy"
+	SideSell = "sell"
+)
+
+// RecommendationList is a ranking together with the strategy and scoring
+// profile that produced it. Skipped lists, in rank order, the tickers left
+// out by the diversification limits. Total counts the tickers that passed
+// the filters; NextCursor, when set, fetches the page after Data.
+type RecommendationList struct {
+	Side            string                  `json:"side"`
+	Strategy        string                  `json:"strategy"`
+	StrategyVersion string                  `json:"strategy_version"`
+	ProfileVersion  string                  `json:"profile_version"`
+	Diversification *Diversification        `json:"diversification,omitempty"`
+	Data            []StockRecommendation   `json:"data"`
+	Skipped         []SkippedRecommendation `json:"skipped,omitempty"`
+	Total           int                     `json:"total"`
+	Offset          int                     `json:"offset"`
+	NextCursor      string                  `json:"next_cursor,omitempty"`
+}
+
+// Action classes group the provider's free-text actions
+const (
+	ActionUpgrade       = "upgrade"
+	ActionDowngrade     = "downgrade"
+	ActionTargetRaised  = "target_raised"
+	ActionTargetLowered = "target_lowered"
+	ActionInitiated     = "initiated"
+	ActionReiterated    = "reiterated"
+	ActionOther         = "other"
+)
+
+// ActionClasses lists every action class.
+var ActionClasses = []string{
+	ActionUpgrade, ActionDowngrade, ActionTargetRaised, ActionTargetLowered,
+	ActionInitiated, ActionReiterated, ActionOther,
+}
+
+// ActionClass classifies a provider action such as "target raised by".
+func ActionClass(action string) string {
+	action = strings.ToLower(action)
+	switch {
+	case strings.Contains(action, "upgraded"):
+		return ActionUpgrade
+	case strings.Contains(action, "downgraded"):
+		return ActionDowngrade
+	case strings.Contains(action, "raised"):
+		return ActionTargetRaised
+	case strings.Contains(action, "lowered"):
+		return ActionTargetLowered
+	case strings.Contains(action, "initiated"):
+		return ActionInitiated
+	case strings.Contains(action, "reiterated"):
+		return ActionReiterated
+	}
+	return ActionOther
+}
+
+// StockValues are the analyst fields that an upsert of the same event can overwrite
+type StockValues struct {
+	TargetFrom string `json:"target_from"`
+	TargetTo   string `json:"target_to"`
+	Action     string `json:"action"`
+	Brokerage  string `json:"brokerage"`
+	RatingFrom string `json:"rating_from"`
+	RatingTo   string `json:"rating_to"`
+}
+
+// Values returns the overwritable analyst fields of the stock
+func (s *Stock) Values() StockValues {
+	return StockValues{
+		TargetFrom: s.TargetFrom,
+		TargetTo:   s.TargetTo,
+		Action:     s.Action,
+		Brokerage:  s.Brokerage,
+		RatingFrom: s.RatingFrom,
+		RatingTo:   s.RatingTo,
+	}
+}
+
+// WithValues returns a copy of the stock carrying values instead of its own
+func (s *Stock) WithValues(values StockValues) Stock {
+	stock := *s
+	stock.TargetFrom = values.TargetFrom
+	stock.TargetTo = values.TargetTo
+	stock.Action = values.Action
+	stock.Brokerage = values.Brokerage
+	stock.RatingFrom = values.RatingFrom
+	stock.RatingTo = values.RatingTo
+	return stock
+}
+
+// StockRevision records a change the provider made to an already stored event
+type StockRevision struct {
+	ID        string      `json:"id" db:"id"`
+	StockID   string      `json:"stock_id" db:"stock_id"`
+	SyncRunID string      `json:"sync_run_id,omitempty" db:"sync_run_id"`
+	Old       StockValues `json:"old"`
+	New       StockValues `json:"new"`
+	ChangedAt time.Time   `json:"changed_at" db:"changed_at"`
+}
+
+// Sync run statuses
+const (
+	SyncRunRunning   = "running"
+	SyncRunCompleted = "completed"
+	SyncRunFailed    = "failed"
+)
+
+// Sync write modes decide what happens when a row of a page cannot be stored
+const (
+	// SyncWriteAtomic stores a page all-or-nothing; a failing row fails the run
+	SyncWriteAtomic = "atomic"
+	// SyncWriteBestEffort stores every row it can and lists the rest in the run
+	SyncWriteBestEffort = "best_effort"
+)
+
+// SyncRun records one execution of the provider synchronization. NextPage is
+// the checkpoint: the provider cursor of the first page not yet stored.
+type SyncRun struct {
+	ID         string      `json:"id" db:"id"`
+	Status     string      `json:"status" db:"status"`
+	WriteMode  string      `json:"write_mode" db:"write_mode"`
+	MaxPages   int         `json:"max_pages" db:"max_pages"`
+	Pages      int         `json:"pages" db:"pages"`
+	Fetched    int         `json:"fetched" db:"fetched"`
+	Stored     int         `json:"stored" db:"stored"`
+	Failed     int         `json:"failed" db:"failed"`
+	NextPage   string      `json:"next_page,omitempty" db:"next_page"`
+	FailedRows []FailedRow `json:"failed_rows,omitempty" db:"failed_rows"`
+	Error      string      `json:"error,omitempty" db:"error"`
+	StartedAt  time.Time   `json:"started_at" db:"started_at"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty" db:"finished_at"`
+}
+
+// SyncPayload is a provider response body kept verbatim, so a sync can be
+// audited or re-parsed after the parser changes.
+type SyncPayload struct {
+	ID        string    `json:"id" db:"id"`
+	SyncRunID string    `json:"sync_run_id" db:"sync_run_id"`
+	Page      int       `json:"page" db:"page"`
+	Body      string    `json:"body" db:"body"`
+	FetchedAt time.Time `json:"fetched_at" db:"fetched_at"`
+}
+
+// FailedRow is a provider row a best-effort sync could not store
+type FailedRow struct {
+	StockID string    `json:"stock_id"`
+	Ticker  string    `json:"ticker"`
+	Time    time.Time `json:"time"`
+	Page    int       `json:"page"`
+	Error   string    `json:"error"`
 }
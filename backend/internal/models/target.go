@@ -0,0 +1,245 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTargetCurrency is assumed for targets written without a currency;
+// the provider quotes US listings.
+const DefaultTargetCurrency = "USD"
+
+// currencySymbols maps the prefixes and suffixes targets are written with
+// to ISO codes, longest first so "HK$" wins over "$".
+var currencySymbols = []struct {
+	symbol   string
+	currency string
+}{
+	{"US$", "USD"}, {"CA$", "CAD"}, {"HK$", "HKD"}, {"C$", "CAD"}, {"A$", "AUD"}, {"R$", "BRL"},
+	{"$", "USD"}, {"€", "EUR"}, {"£", "GBP"}, {"¥", "JPY"}, {"₹", "INR"}, {"GBp", "GBX"}, {"p", "GBX"},
+}
+
+// ParseTarget reads a price target such as "$12.50", "€1.234,50",
+// "12.50 CAD" or "230p". Thousands separators are dropped and pence (GBX)
+// are converted to pounds. Empty, negative, ranged or otherwise unreadable
+// targets are an error.
+func ParseTarget(raw string) (float64, string, error) {
+	text := strings.TrimSpace(raw)
+	if text == "" {
+		return 0, "", errors.New("no target")
+	}
+
+	// ISO codes ("USD 12", "12 EUR"), then symbols ("$12", "230p")
+	currency := ""
+	if len(text) > 3 && IsCurrencyCode(text[:3]) {
+		currency, text = text[:3], strings.TrimSpace(text[3:])
+	} else if len(text) > 3 && IsCurrencyCode(text[len(text)-3:]) {
+		currency, text = text[len(text)-3:], strings.TrimSpace(text[:len(text)-3])
+	}
+	for _, symbol := range currencySymbols {
+		trimmed := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(text, symbol.symbol), symbol.symbol))
+		if trimmed != text {
+			if currency == "" {
+				currency = symbol.currency
+			}
+			text = trimmed
+			break
+		}
+	}
+	if currency == "" {
+		currency = DefaultTargetCurrency
+	}
+
+	amount, err := parseAmount(text)
+	if err != nil {
+		return 0, "", fmt.Errorf("unreadable target %q: %w", raw, err)
+	}
+	if currency == "GBX" {
+		currency, amount = "GBP", amount/100
+	}
+	return amount, currency, nil
+}
+
+// MainTargetCurrency is the currency most readable targets of views are
+// in, USD on a tie with it and otherwise the first code alphabetically.
+func MainTargetCurrency(views []BrokerageRating) string {
+	counts := map[string]int{}
+	for _, view := range views {
+		if _, currency, err := ParseTarget(view.Target); err == nil {
+			counts[currency]++
+		}
+	}
+
+	main := DefaultTargetCurrency
+	for currency, count := range counts {
+		if count > counts[main] || (count == counts[main] && main != DefaultTargetCurrency && currency < main) {
+			main = currency
+		}
+	}
+	return main
+}
+
+// TargetChangePct is the percentage move from one target to another. It is
+// only known when both can be read and are in the same currency.
+func TargetChangePct(from, to string) (float64, bool) {
+	fromAmount, fromCurrency, err := ParseTarget(from)
+	if err != nil {
+		return 0, false
+	}
+	toAmount, toCurrency, err := ParseTarget(to)
+	if err != nil || toCurrency != fromCurrency {
+		return 0, false
+	}
+	return (toAmount - fromAmount) / fromAmount * 100, true
+}
+
+// IsCurrencyCode reports whether text looks like an ISO code such as "USD".
+func IsCurrencyCode(text string) bool {
+	for _, r := range text {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return len(text) == 3
+}
+
+// parseAmount reads a positive number with "," or "." as thousands or
+// decimal separator: "1,234.5" and "1.234,5" are both 1234.5, and a lone
+// comma is read as thousands before three digits ("1,234") and as the
+// decimal point otherwise ("12,5").
+func parseAmount(text string) (float64, error) {
+	comma, dot := strings.LastIndex(text, ","), strings.LastIndex(text, ".")
+	switch {
+	case comma >= 0 && dot >= 0 && comma > dot:
+		text = strings.ReplaceAll(strings.ReplaceAll(text, ".", ""), ",", ".")
+	case comma >= 0 && dot >= 0:
+		text = strings.ReplaceAll(text, ",", "")
+	case comma >= 0 && len(text)-comma-1 == 3:
+		text = strings.ReplaceAll(text, ",", "")
+	case comma >= 0:
+		text = strings.Replace(text, ",", ".", 1)
+	}
+
+	amount, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, errors.New("not a number")
+	}
+	if math.IsNaN(amount) || math.IsInf(amount, 0) || amount <= 0 {
+		return 0, errors.New("not a positive amount")
+	}
+	return amount, nil
+}
+
+// TargetStats summarizes a set of price targets in one currency. Dispersion
+// is the standard deviation over the mean, so names of any price compare.
+// With no targets only Count is set.
+type TargetStats struct {
+	Count      int      `json:"count"`
+	Mean       *float64 `json:"mean"`
+	Median     *float64 `json:"median"`
+	High       *float64 `json:"high"`
+	Low        *float64 `json:"low"`
+	StdDev     *float64 `json:"stddev"`
+	Dispersion *float64 `json:"dispersion"`
+}
+
+// NewTargetStats computes the statistics of targets; the standard
+// deviation is the population one, zero for a single target.
+func NewTargetStats(targets []float64) TargetStats {
+	stats := TargetStats{Count: len(targets)}
+	if len(targets) == 0 {
+		return stats
+	}
+
+	sorted := append([]float64(nil), targets...)
+	sort.Float64s(sorted)
+	var sum float64
+	for _, target := range sorted {
+		sum += target
+	}
+	mean := sum / float64(len(sorted))
+	median := sorted[len(sorted)/2]
+	if len(sorted)%2 == 0 {
+		median = (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+	}
+	var squares float64
+	for _, target := range sorted {
+		squares += (target - mean) * (target - mean)
+	}
+	stddev := math.Sqrt(squares / float64(len(sorted)))
+	dispersion := stddev / mean
+	high, low := sorted[len(sorted)-1], sorted[0]
+
+	stats.Mean, stats.Median, stats.High, stats.Low = &mean, &median, &high, &low
+	stats.StdDev, stats.Dispersion = &stddev, &dispersion
+	return stats
+}
+
+// Sub returns the change from earlier to s, field by field; a field missing
+// on either side has no change.
+func (s TargetStats) Sub(earlier TargetStats) TargetStats {
+	diff := func(now, then *float64) *float64 {
+		if now == nil || then == nil {
+			return nil
+		}
+		change := *now - *then
+		return &change
+	}
+	return TargetStats{
+		Count:      s.Count - earlier.Count,
+		Mean:       diff(s.Mean, earlier.Mean),
+		Median:     diff(s.Median, earlier.Median),
+		High:       diff(s.High, earlier.High),
+		Low:        diff(s.Low, earlier.Low),
+		StdDev:     diff(s.StdDev, earlier.StdDev),
+		Dispersion: diff(s.Dispersion, earlier.Dispersion),
+	}
+}
+
+// BrokerageTarget is the latest target of one brokerage, read in Currency.
+type BrokerageTarget struct {
+	Brokerage string    `json:"brokerage"`
+	StockID   string    `json:"stock_id"`
+	Raw       string    `json:"raw"`
+	Target    float64   `json:"target"`
+	Currency  string    `json:"currency"`
+	Time      time.Time `json:"time"`
+}
+
+// ExcludedTarget is a brokerage's latest target left out of the
+// statistics, because it cannot be read or is in another currency.
+type ExcludedTarget struct {
+	Brokerage string    `json:"brokerage"`
+	StockID   string    `json:"stock_id"`
+	Raw       string    `json:"raw"`
+	Time      time.Time `json:"time"`
+	Reason    string    `json:"reason"`
+}
+
+// TargetChange compares the statistics as they stood Days ago with today's.
+// Change holds today's value minus the earlier one and MeanChangePct the
+// relative move of the mean.
+type TargetChange struct {
+	Days          int         `json:"days"`
+	AsOf          time.Time   `json:"as_of"`
+	Stats         TargetStats `json:"stats"`
+	Change        TargetStats `json:"change"`
+	MeanChangePct *float64    `json:"mean_change_pct"`
+}
+
+// TargetSpread is the spread of the latest target of every brokerage
+// covering a ticker, with how it moved over time.
+type TargetSpread struct {
+	Ticker   string            `json:"ticker"`
+	Currency string            `json:"currency"`
+	AsOf     time.Time         `json:"as_of"`
+	Stats    TargetStats       `json:"stats"`
+	Changes  []TargetChange    `json:"changes"`
+	Targets  []BrokerageTarget `json:"targets"`
+	Excluded []ExcludedTarget  `json:"excluded"`
+}
@@ -0,0 +1,150 @@
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		raw      string
+		amount   float64
+		currency string
+	}{
+		{"$12.50", 12.50, "USD"},
+		{"12.50", 12.50, "USD"},
+		{" $1,234.00 ", 1234, "USD"},
+		{"€12", 12, "EUR"},
+		{"€1.234,50", 1234.50, "EUR"},
+		{"12,50 €", 12.50, "EUR"},
+		{"£8.40", 8.40, "GBP"},
+		{"230p", 2.30, "GBP"},
+		{"GBp 230", 2.30, "GBP"},
+		{"¥3,500", 3500, "JPY"},
+		{"12 CAD", 12, "CAD"},
+		{"C$12.50", 12.50, "CAD"},
+		{"HK$45", 45, "HKD"},
+		{"USD 1,150", 1150, "USD"},
+		// The code wins over a symbol it is written with
+		{"$12 AUD", 12, "AUD"},
+	}
+	for _, tt := range tests {
+		amount, currency, err := ParseTarget(tt.raw)
+		if err != nil {
+			t.Errorf("ParseTarget(%q): %v", tt.raw, err)
+			continue
+		}
+		if math.Abs(amount-tt.amount) > 1e-9 || currency != tt.currency {
+			t.Errorf("ParseTarget(%q) = %v %s, want %v %s", tt.raw, amount, currency, tt.amount, tt.currency)
+		}
+	}
+
+	for _, raw := range []string{"", "  ", "N/A", "$", "$-5.00", "$0.00", "$10-$12", "12 USD USD", "$NaN", "$Inf"} {
+		if amount, currency, err := ParseTarget(raw); err == nil {
+			t.Errorf("ParseTarget(%q) = %v %s, want an error", raw, amount, currency)
+		}
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		text string
+		want float64
+	}{
+		{"12", 12},
+		{"12.5", 12.5},
+		{"1,234.5", 1234.5},
+		{"1.234,5", 1234.5},
+		{"1,234", 1234},
+		{"12,5", 12.5},
+		{"12,50", 12.5},
+		{"1,234,567", 1234567},
+		{"1.234.567,89", 1234567.89},
+	}
+	for _, tt := range tests {
+		got, err := parseAmount(tt.text)
+		if err != nil || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("parseAmount(%q) = %v, %v; want %v", tt.text, got, err, tt.want)
+		}
+	}
+
+	for _, text := range []string{"", "abc", "-1", "0", "1e400", "NaN", "12 34"} {
+		if got, err := parseAmount(text); err == nil {
+			t.Errorf("parseAmount(%q) = %v, want an error", text, got)
+		}
+	}
+}
+
+func TestTargetChangePct(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     float64
+		ok       bool
+	}{
+		{"$100.00", "$120.00", 20, true},
+		{"$1,000.00", "$900.00", -10, true},
+		{"€10", "12,50 €", 25, true},
+		{"200p", "£2.50", 25, true},
+		{"$100.00", "€120", 0, false},
+		{"", "$120.00", 0, false},
+		{"$100.00", "N/A", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := TargetChangePct(tt.from, tt.to)
+		if ok != tt.ok || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("TargetChangePct(%q, %q) = %v, %v; want %v, %v", tt.from, tt.to, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestMainTargetCurrency(t *testing.T) {
+	views := func(targets ...string) []BrokerageRating {
+		var views []BrokerageRating
+		for _, target := range targets {
+			views = append(views, BrokerageRating{Target: target})
+		}
+		return views
+	}
+	tests := []struct {
+		name    string
+		targets []string
+		want    string
+	}{
+		{"no targets", nil, "USD"},
+		{"unreadable targets only", []string{"", "N/A"}, "USD"},
+		{"most brokerages", []string{"€10", "€11", "$12"}, "EUR"},
+		{"USD on a tie with it", []string{"€10", "$12"}, "USD"},
+		{"alphabetical otherwise", []string{"£10", "€11", "N/A"}, "EUR"},
+	}
+	for _, tt := range tests {
+		if got := MainTargetCurrency(views(tt.targets...)); got != tt.want {
+			t.Errorf("%s: MainTargetCurrency = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestConsensusTargetsKeepOneCurrency(t *testing.T) {
+	base := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	event := func(brokerage, target string) Stock {
+		return Stock{ID: brokerage, Ticker: "SAP", Brokerage: brokerage, RatingTo: "Buy", TargetTo: target, Time: base}
+	}
+	c := NewTickerConsensus([]Stock{
+		event("Alpha", "€200"),
+		event("Beta", "€1.100,00"),
+		event("Gamma", "$250.00"),
+		event("Delta", "N/A"),
+	})
+
+	if c.TargetCurrency != "EUR" {
+		t.Errorf("TargetCurrency = %q, want EUR", c.TargetCurrency)
+	}
+	if c.MeanTarget == nil || *c.MeanTarget != 650 || *c.HighTarget != 1100 || *c.LowTarget != 200 {
+		t.Errorf("targets mean %v, high %v, low %v; want 650, 1100, 200 from the EUR targets", c.MeanTarget, c.HighTarget, c.LowTarget)
+	}
+
+	none := NewTickerConsensus([]Stock{event("Alpha", "N/A")})
+	if none.TargetCurrency != "" || none.MeanTarget != nil {
+		t.Errorf("a ticker without readable targets has currency %q and mean %v", none.TargetCurrency, none.MeanTarget)
+	}
+}
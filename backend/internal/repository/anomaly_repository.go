@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+)
+
+// AnomalyFilter narrows ListAnomalies; empty fields match every anomaly.
+type AnomalyFilter struct {
+	Scope     string
+	Subject   string
+	Kind      string
+	Direction string
+	// Since keeps anomalies whose window ends after it
+	Since time.Time
+}
+
+// AnomalyRepository stores detected analyst anomalies. Like snapshots they
+// only exist in SQL databases.
+type AnomalyRepository struct {
+	db *Database
+}
+
+func NewAnomalyRepository(db *Database) *AnomalyRepository {
+	return &AnomalyRepository{db: db}
+}
+
+// ReplaceWindow stores the anomalies detected in the window ending at
+// windowEnd, in place of those an earlier run found for the same window.
+func (r *AnomalyRepository) ReplaceWindow(ctx context.Context, windowEnd time.Time, anomalies []models.AnalystAnomaly) error {
+	ctx, cancel := r.db.withTimeout(ctx, OperationWrite)
+	defer cancel()
+
+	err := r.db.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM analyst_anomalies WHERE window_end = $1`, windowEnd.UTC()); err != nil {
+			return err
+		}
+		for i := range anomalies {
+			anomaly := &anomalies[i]
+			if anomaly.ID == "" {
+				anomaly.ID = newID()
+			}
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO analyst_anomalies (
+					id, scope, subject, kind, direction, window_start, window_end,
+					observed, baseline_mean, baseline_stddev, z_score,
+					events, bullish, bearish, detected_at
+				)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+			`, anomaly.ID, anomaly.Scope, anomaly.Subject, anomaly.Kind, anomaly.Direction,
+				anomaly.WindowStart.UTC(), anomaly.WindowEnd.UTC(),
+				anomaly.Observed, anomaly.BaselineMean, anomaly.BaselineStdDev, anomaly.ZScore,
+				anomaly.Events, anomaly.Bullish, anomaly.Bearish, anomaly.DetectedAt.UTC())
+			if err != nil {
+				return fmt.Errorf("error storing %s anomaly of %s %s: %w", anomaly.Kind, anomaly.Scope, anomaly.Subject, err)
+			}
+		}
+		return nil
+	})
+	return wrapTimeout(ctx, err)
+}
+
+// ListAnomalies returns anomalies of the latest windows first and, within a
+// window, the strongest first.
+func (r *AnomalyRepository) ListAnomalies(ctx context.Context, filter AnomalyFilter, limit, offset int) ([]models.AnalystAnomaly, error) {
+	ctx, cancel := r.db.withTimeout(ctx, OperationRead)
+	defer cancel()
+
+	var conditions []string
+	var args []interface{}
+	for _, condition := range []struct {
+		column, value string
+	}{{"scope", filter.Scope}, {"subject", filter.Subject}, {"kind", filter.Kind}, {"direction", filter.Direction}} {
+		if condition.value == "" {
+			continue
+		}
+		args = append(args, condition.value)
+		conditions = append(conditions, fmt.Sprintf("%s = $%d", condition.column, len(args)))
+	}
+	if !filter.Since.IsZero() {
+		args = append(args, filter.Since.UTC())
+		conditions = append(conditions, fmt.Sprintf("window_end > $%d", len(args)))
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, limit, offset)
+
+	query := fmt.Sprintf(`
+		SELECT id, scope, subject, kind, direction, window_start, window_end,
+			observed, baseline_mean, baseline_stddev, z_score,
+			events, bullish, bearish, detected_at
+		FROM analyst_anomalies%s
+		%s
+		ORDER BY window_end DESC, ABS(z_score) DESC, id
+		LIMIT $%d OFFSET $%d
+	`, r.db.staleClause(ctx), where, len(args)-1, len(args))
+
+	rows, err := r.db.reader(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, wrapTimeout(ctx, err)
+	}
+	defer rows.Close()
+
+	anomalies := []models.AnalystAnomaly{}
+	for rows.Next() {
+		var anomaly models.AnalystAnomaly
+		err := rows.Scan(&anomaly.ID, &anomaly.Scope, &anomaly.Subject, &anomaly.Kind, &anomaly.Direction,
+			&anomaly.WindowStart, &anomaly.WindowEnd,
+			&anomaly.Observed, &anomaly.BaselineMean, &anomaly.BaselineStdDev, &anomaly.ZScore,
+			&anomaly.Events, &anomaly.Bullish, &anomaly.Bearish, &anomaly.DetectedAt)
+		if err != nil {
+			return nil, err
+		}
+		anomalies = append(anomalies, anomaly)
+	}
+	return anomalies, wrapTimeout(ctx, rows.Err())
+}
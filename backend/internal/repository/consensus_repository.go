@@ -0,0 +1,339 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+)
+
+// ErrTickerNotFound is returned by GetConsensus when no event of the ticker
+// has been stored.
+var ErrTickerNotFound = errors.New("ticker not found")
+
+const consensusColumns = `
+	c.ticker, c.company, c.brokerage_ratings, c.rating_distribution, c.covering_brokerages,
+	c.mean_target, c.median_target, c.high_target, c.low_target, COALESCE(c.target_currency, ''),
+	c.latest_stock_id, c.last_action_time, c.updated_at,
+	s.id, s.ticker, s.company, s.target_from, s.target_to, s.action, s.brokerage, s.rating_from, s.rating_to,
+	s.time, s.last_updated, s.created_at`
+
+// updateConsensus folds a stored event into its ticker's consensus row. It
+// runs inside the upsert transaction so the row never disagrees with stocks.
+func (r *StockRepository) updateConsensus(ctx context.Context, tx *sql.Tx, stock *models.Stock) error {
+	consensus := &models.TickerConsensus{}
+
+	var brokerages string
+	err := tx.QueryRowContext(ctx, `
+		SELECT ticker, company, brokerage_ratings, latest_stock_id, last_action_time
+		FROM ticker_consensus
+		WHERE ticker = $1`+r.db.Dialect.forUpdate(),
+		stock.Ticker,
+	).Scan(&consensus.Ticker, &consensus.Company, &brokerages, &consensus.LatestStockID, &consensus.LastActionTime)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if err == nil {
+		if err := json.Unmarshal([]byte(brokerages), &consensus.Brokerages); err != nil {
+			return fmt.Errorf("error decoding consensus of %s: %w", stock.Ticker, err)
+		}
+	}
+
+	if !consensus.Apply(stock) {
+		stocks, err := r.tickerEvents(ctx, tx, stock.Ticker)
+		if err != nil {
+			return err
+		}
+		consensus = models.NewTickerConsensus(stocks)
+	}
+
+	return r.saveConsensus(ctx, tx, consensus)
+}
+
+func (r *StockRepository) tickerEvents(ctx context.Context, tx *sql.Tx, ticker string) ([]models.Stock, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, ticker, company, target_from, target_to, action, brokerage, rating_from, rating_to, time, last_updated, created_at
+		FROM stocks
+		WHERE ticker = $1
+	`, ticker)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return r.scanStocks(rows)
+}
+
+func (r *StockRepository) saveConsensus(ctx context.Context, tx *sql.Tx, consensus *models.TickerConsensus) error {
+	brokerages, err := json.Marshal(consensus.Brokerages)
+	if err != nil {
+		return err
+	}
+	distribution, err := json.Marshal(consensus.RatingDistribution)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO ticker_consensus (
+			ticker, company, brokerage_ratings, rating_distribution, covering_brokerages,
+			mean_target, median_target, high_target, low_target, target_currency,
+			latest_stock_id, last_action_time, updated_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT (ticker) DO UPDATE SET
+			company = EXCLUDED.company,
+			brokerage_ratings = EXCLUDED.brokerage_ratings,
+			rating_distribution = EXCLUDED.rating_distribution,
+			covering_brokerages = EXCLUDED.covering_brokerages,
+			mean_target = EXCLUDED.mean_target,
+			median_target = EXCLUDED.median_target,
+			high_target = EXCLUDED.high_target,
+			low_target = EXCLUDED.low_target,
+			target_currency = EXCLUDED.target_currency,
+			latest_stock_id = EXCLUDED.latest_stock_id,
+			last_action_time = EXCLUDED.last_action_time,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	_, err = tx.ExecContext(ctx, query,
+		consensus.Ticker, consensus.Company, string(brokerages), string(distribution), consensus.CoveringBrokerages,
+		nullFloat(consensus.MeanTarget), nullFloat(consensus.MedianTarget),
+		nullFloat(consensus.HighTarget), nullFloat(consensus.LowTarget), nullString(consensus.TargetCurrency),
+		consensus.LatestStockID, consensus.LastActionTime.UTC(), time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("error saving consensus of %s: %w", consensus.Ticker, err)
+	}
+	return nil
+}
+
+// ListConsensus returns ticker consensus rows with their latest event, most
+// recently active tickers first.
+func (r *StockRepository) ListConsensus(ctx context.Context, limit, offset int) ([]models.TickerConsensus, error) {
+	ctx, cancel := r.db.withTimeout(ctx, OperationRead)
+	defer cancel()
+
+	query := `
+		SELECT ` + consensusColumns + `
+		FROM ticker_consensus c
+		JOIN stocks s ON s.id = c.latest_stock_id` + r.db.staleClause(ctx) + `
+		ORDER BY c.last_action_time DESC, c.ticker
+		LIMIT $1 OFFSET $2
+	`
+
+	rows, err := r.db.reader(ctx).QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, wrapTimeout(ctx, err)
+	}
+	defer rows.Close()
+
+	var consensus []models.TickerConsensus
+	for rows.Next() {
+		c, err := scanConsensus(rows)
+		if err != nil {
+			return nil, wrapTimeout(ctx, err)
+		}
+		consensus = append(consensus, *c)
+	}
+	return consensus, wrapTimeout(ctx, rows.Err())
+}
+
+func (r *StockRepository) ListConsensusAfter(ctx context.Context, afterTicker string, limit int) ([]models.TickerConsensus, error) {
+	ctx, cancel := r.db.withTimeout(ctx, OperationRead)
+	defer cancel()
+
+	query := `
+		SELECT ` + consensusColumns + `
+		FROM ticker_consensus c
+		JOIN stocks s ON s.id = c.latest_stock_id` + r.db.staleClause(ctx) + `
+		WHERE c.ticker > $1
+		ORDER BY c.ticker
+		LIMIT $2
+	`
+
+	rows, err := r.db.reader(ctx).QueryContext(ctx, query, afterTicker, limit)
+	if err != nil {
+		return nil, wrapTimeout(ctx, err)
+	}
+	defer rows.Close()
+
+	var consensus []models.TickerConsensus
+	for rows.Next() {
+		c, err := scanConsensus(rows)
+		if err != nil {
+			return nil, wrapTimeout(ctx, err)
+		}
+		consensus = append(consensus, *c)
+	}
+	return consensus, wrapTimeout(ctx, rows.Err())
+}
+
+func (r *StockRepository) GetConsensus(ctx context.Context, ticker string) (*models.TickerConsensus, error) {
+	ctx, cancel := r.db.withTimeout(ctx, OperationRead)
+	defer cancel()
+
+	query := `
+		SELECT ` + consensusColumns + `
+		FROM ticker_consensus c
+		JOIN stocks s ON s.id = c.latest_stock_id` + r.db.staleClause(ctx) + `
+		WHERE c.ticker = $1
+	`
+
+	consensus, err := scanConsensus(r.db.reader(ctx).QueryRowContext(ctx, query, ticker))
+	if err == sql.ErrNoRows {
+		return nil, ErrTickerNotFound
+	}
+	if err != nil {
+		return nil, wrapTimeout(ctx, err)
+	}
+	return consensus, nil
+}
+
+func (r *StockRepository) CountConsensus(ctx context.Context) (int, error) {
+	ctx, cancel := r.db.withTimeout(ctx, OperationRead)
+	defer cancel()
+
+	var count int
+	query := "SELECT COUNT(*) FROM ticker_consensus" + r.db.staleClause(ctx)
+	err := r.db.reader(ctx).QueryRowContext(ctx, query).Scan(&count)
+	return count, wrapTimeout(ctx, err)
+}
+
+// RebuildConsensus recomputes every ticker's consensus from its stored
+// events, one transaction per ticker, and removes rows of tickers without
+// events. It backfills the table for data stored before it existed.
+func (r *StockRepository) RebuildConsensus(ctx context.Context) (int, error) {
+	tickers, err := r.distinctTickers(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, ticker := range tickers {
+		err := r.writeTx(ctx, func(tx *sql.Tx) error {
+			return r.refreshConsensus(ctx, tx, ticker)
+		})
+		if err != nil {
+			return i, fmt.Errorf("error rebuilding consensus of %s: %w", ticker, err)
+		}
+	}
+
+	err = r.writeTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "DELETE FROM ticker_consensus WHERE ticker NOT IN (SELECT ticker FROM stocks)")
+		return err
+	})
+	return len(tickers), err
+}
+
+// BackfillConsensus rebuilds ticker_consensus when it is empty while events
+// are stored, as after migrating a database that predates the table. It
+// returns the number of tickers rebuilt, 0 when there was nothing to do.
+func (r *StockRepository) BackfillConsensus(ctx context.Context) (int, error) {
+	readCtx, cancel := r.db.withTimeout(ctx, OperationRead)
+	defer cancel()
+
+	var missing bool
+	err := r.db.DB.QueryRowContext(readCtx, `
+		SELECT EXISTS (SELECT 1 FROM stocks) AND NOT EXISTS (SELECT 1 FROM ticker_consensus)
+	`).Scan(&missing)
+	if err != nil || !missing {
+		return 0, wrapTimeout(readCtx, err)
+	}
+	return r.RebuildConsensus(ctx)
+}
+
+// refreshConsensus recomputes ticker's consensus from its stored events
+// inside tx, removing the row when no event is left.
+func (r *StockRepository) refreshConsensus(ctx context.Context, tx *sql.Tx, ticker string) error {
+	stocks, err := r.tickerEvents(ctx, tx, ticker)
+	if err != nil {
+		return err
+	}
+	if len(stocks) == 0 {
+		_, err := tx.ExecContext(ctx, "DELETE FROM ticker_consensus WHERE ticker = $1", ticker)
+		return err
+	}
+	return r.saveConsensus(ctx, tx, models.NewTickerConsensus(stocks))
+}
+
+func (r *StockRepository) distinctTickers(ctx context.Context) ([]string, error) {
+	ctx, cancel := r.db.withTimeout(ctx, OperationRead)
+	defer cancel()
+
+	rows, err := r.db.DB.QueryContext(ctx, "SELECT DISTINCT ticker FROM stocks ORDER BY ticker")
+	if err != nil {
+		return nil, wrapTimeout(ctx, err)
+	}
+	defer rows.Close()
+
+	var tickers []string
+	for rows.Next() {
+		var ticker string
+		if err := rows.Scan(&ticker); err != nil {
+			return nil, err
+		}
+		tickers = append(tickers, ticker)
+	}
+	return tickers, wrapTimeout(ctx, rows.Err())
+}
+
+// writeTx runs fn in a write transaction bounded by the write timeout.
+func (r *StockRepository) writeTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	ctx, cancel := r.db.withTimeout(ctx, OperationWrite)
+	defer cancel()
+
+	return wrapTimeout(ctx, r.db.inTx(ctx, fn))
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanConsensus(row rowScanner) (*models.TickerConsensus, error) {
+	var consensus models.TickerConsensus
+	var latest models.Stock
+	var brokerages, distribution string
+	var mean, median, high, low sql.NullFloat64
+
+	err := row.Scan(
+		&consensus.Ticker, &consensus.Company, &brokerages, &distribution, &consensus.CoveringBrokerages,
+		&mean, &median, &high, &low, &consensus.TargetCurrency, &consensus.LatestStockID, &consensus.LastActionTime, &consensus.UpdatedAt,
+		&latest.ID, &latest.Ticker, &latest.Company, &latest.TargetFrom, &latest.TargetTo,
+		&latest.Action, &latest.Brokerage, &latest.RatingFrom, &latest.RatingTo,
+		&latest.Time, &latest.LastUpdated, &latest.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(brokerages), &consensus.Brokerages); err != nil {
+		return nil, fmt.Errorf("error decoding consensus of %s: %w", consensus.Ticker, err)
+	}
+	if err := json.Unmarshal([]byte(distribution), &consensus.RatingDistribution); err != nil {
+		return nil, fmt.Errorf("error decoding consensus of %s: %w", consensus.Ticker, err)
+	}
+	consensus.MeanTarget = floatPtr(mean)
+	consensus.MedianTarget = floatPtr(median)
+	consensus.HighTarget = floatPtr(high)
+	consensus.LowTarget = floatPtr(low)
+	consensus.Latest = &latest
+	return &consensus, nil
+}
+
+func nullFloat(f *float64) sql.NullFloat64 {
+	if f == nil {
+		return sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: *f, Valid: true}
+}
+
+func floatPtr(f sql.NullFloat64) *float64 {
+	if !f.Valid {
+		return nil
+	}
+	return &f.Float64
+}
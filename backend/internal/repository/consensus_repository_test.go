@@ -0,0 +1,42 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository/storetest"
+)
+
+func TestBackfillConsensus(t *testing.T) {
+	ctx := context.Background()
+	db := newMigratedDatabase(t)
+	stocks := repository.NewStockRepository(db)
+
+	if tickers, err := stocks.BackfillConsensus(ctx); err != nil || tickers != 0 {
+		t.Fatalf("BackfillConsensus on an empty database = %d, %v; want 0, nil", tickers, err)
+	}
+
+	for _, stock := range []struct{ ticker, company string }{{"AAPL", "Apple Inc."}, {"MSFT", "Microsoft Corp."}} {
+		event := storetest.NewStock(stock.ticker, stock.company, time.Hour)
+		if err := stocks.Create(ctx, &event, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if tickers, err := stocks.BackfillConsensus(ctx); err != nil || tickers != 0 {
+		t.Fatalf("BackfillConsensus with consensus in place = %d, %v; want 0, nil", tickers, err)
+	}
+
+	// Events stored before migration 0004 have no consensus rows
+	if _, err := db.DB.Exec("DELETE FROM ticker_consensus"); err != nil {
+		t.Fatal(err)
+	}
+	tickers, err := stocks.BackfillConsensus(ctx)
+	if err != nil || tickers != 2 {
+		t.Fatalf("BackfillConsensus = %d, %v; want 2, nil", tickers, err)
+	}
+	if count, _ := stocks.CountConsensus(ctx); count != 2 {
+		t.Errorf("%d consensus rows after backfill, want 2", count)
+	}
+}
@@ -1,63 +1,403 @@
 package repository
 
 import (
+	"context"
+	"crypto/rand"
 	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/config"
 	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
 )
 
+// Dialect identifies the SQL flavor behind a Database. Queries that differ
+// between engines ask the dialect instead of checking the driver directly.
+type Dialect string
+
+const (
+	// DialectPostgres covers CockroachDB and PostgreSQL
+	DialectPostgres Dialect = "postgres"
+	// DialectSQLite is the embedded single-file backend for offline use
+	DialectSQLite Dialect = "sqlite"
+)
+
+// ErrQueryTimeout marks statements cancelled because their operation class
+// timeout elapsed, as opposed to failing on their own.
+var ErrQueryTimeout = errors.New("database query timed out")
+
+// OperationClass groups statements that share a timeout.
+type OperationClass int
+
+const (
+	OperationRead OperationClass = iota
+	OperationWrite
+	OperationSearch
+)
+
+// QueryTimeouts bounds how long a single operation of each class may run.
+// A zero value means the caller's context alone decides.
+type QueryTimeouts struct {
+	Read   time.Duration
+	Write  time.Duration
+	Search time.Duration
+}
+
+// DatabaseOptions configures the connection pool, startup retries and
+// statement timeouts of a Database.
+type DatabaseOptions struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// ConnectRetries is how many extra attempts NewDatabase makes when the
+	// first ping fails, waiting ConnectBackoff, then twice as long, up to
+	// maxConnectBackoff between attempts.
+	ConnectRetries int
+	ConnectBackoff time.Duration
+
+	// SerializationRetries is how many times a transaction aborted with
+	// SQLSTATE 40001 is re-run before the error is returned.
+	SerializationRetries int
+
+	Timeouts QueryTimeouts
+
+	// ReadURL optionally names a read replica, with the same scheme as the
+	// primary, that serves read-only queries. FollowerReads instead lets
+	// CockroachDB answer them from the nearest replica with slightly stale
+	// data (AS OF SYSTEM TIME follower_read_timestamp()); it is ignored
+	// unless version() reports CockroachDB.
+	ReadURL       string
+	FollowerReads bool
+}
+
+const maxConnectBackoff = 30 * time.Second
+
+// DatabaseOptionsFromConfig reads the pool and resilience settings from cfg.
+func DatabaseOptionsFromConfig(cfg *config.Config) DatabaseOptions {
+	return DatabaseOptions{
+		MaxOpenConns:         cfg.DBMaxOpenConns,
+		MaxIdleConns:         cfg.DBMaxIdleConns,
+		ConnMaxLifetime:      cfg.DBConnMaxLifetime,
+		ConnMaxIdleTime:      cfg.DBConnMaxIdleTime,
+		ConnectRetries:       cfg.DBConnectRetries,
+		ConnectBackoff:       cfg.DBConnectBackoff,
+		SerializationRetries: cfg.DBSerializationRetries,
+		ReadURL:              cfg.DatabaseReadURL,
+		FollowerReads:        cfg.DBFollowerReads,
+		Timeouts: QueryTimeouts{
+			Read:   cfg.DBReadTimeout,
+			Write:  cfg.DBWriteTimeout,
+			Search: cfg.DBSearchTimeout,
+		},
+	}
+}
+
 type Database struct {
+	// DB is the primary; all writes and read-your-writes paths use it.
 	DB *sql.DB
+	// ReadDB serves stale-tolerant reads. It is DB when no replica is set.
+	ReadDB   *sql.DB
+	Dialect  Dialect
+	Timeouts QueryTimeouts
+
+	followerReads        bool
+	serializationRetries int
+	retriedTransactions  atomic.Int64
+	connectAttempts      int
+}
+
+// NewDatabase opens the database named by databaseURL. The scheme picks the
+// driver: postgres:// and postgresql:// use lib/pq, sqlite://path uses the
+// embedded SQLite engine (sqlite://:memory: for a throwaway database).
+// Until the first ping succeeds it retries with exponential backoff, so the
+// server can start before CockroachDB is ready in docker-compose.
+func NewDatabase(databaseURL string, options DatabaseOptions) (*Database, error) {
+	dialect, db, attempts, err := openPool(databaseURL, options)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Successfully connected to database (%s)", dialect)
+
+	database := &Database{
+		DB:                   db,
+		ReadDB:               db,
+		Dialect:              dialect,
+		Timeouts:             options.Timeouts,
+		serializationRetries: options.SerializationRetries,
+		connectAttempts:      attempts,
+	}
+
+	if options.ReadURL != "" {
+		readDialect, readDB, _, err := openPool(options.ReadURL, options)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("read replica: %w", err)
+		}
+		if readDialect != dialect {
+			db.Close()
+			readDB.Close()
+			return nil, fmt.Errorf("read replica dialect %s does not match primary %s", readDialect, dialect)
+		}
+		database.ReadDB = readDB
+		log.Println("Successfully connected to read replica")
+	}
+
+	if options.FollowerReads {
+		cockroach, err := database.isCockroachDB(context.Background())
+		if err != nil {
+			database.Close()
+			return nil, fmt.Errorf("error detecting CockroachDB for follower reads: %w", err)
+		}
+		if cockroach {
+			database.followerReads = true
+		} else {
+			log.Printf("Follower reads are only supported on CockroachDB; ignoring for %s", dialect)
+		}
+	}
+
+	return database, nil
 }
 
-func NewDatabase(databaseURL string) (*Database, error) {
-	db, err := sql.Open("postgres", databaseURL)
+// isCockroachDB reports whether the database is CockroachDB rather than
+// PostgreSQL, which share the postgres dialect.
+func (d *Database) isCockroachDB(ctx context.Context) (bool, error) {
+	if d.Dialect != DialectPostgres {
+		return false, nil
+	}
+
+	var version string
+	if err := d.DB.QueryRowContext(ctx, "SELECT version()").Scan(&version); err != nil {
+		return false, err
+	}
+	return strings.Contains(version, "CockroachDB"), nil
+}
+
+func openPool(databaseURL string, options DatabaseOptions) (Dialect, *sql.DB, int, error) {
+	dialect, driverName, dsn, err := parseDatabaseURL(databaseURL)
+	if err != nil {
+		return "", nil, 0, err
+	}
+
+	db, err := sql.Open(driverName, dsn)
 	if err != nil {
-		return nil, fmt.Errorf("error opening database: %w", err)
+		return "", nil, 0, fmt.Errorf("error opening database: %w", err)
 	}
 
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("error connecting to database: %w", err)
+	configurePool(db, dialect, options)
+
+	attempts, err := pingWithRetry(db, options.ConnectRetries, options.ConnectBackoff)
+	if err != nil {
+		db.Close()
+		return "", nil, attempts, fmt.Errorf("error connecting to database after %d attempts: %w", attempts, err)
 	}
 
-	log.Println("Successfully connected to database")
-	return &Database{DB: db}, nil
+	return dialect, db, attempts, nil
+}
+
+// configurePool applies the pool settings of options to db.
+func configurePool(db *sql.DB, dialect Dialect, options DatabaseOptions) {
+	if dialect == DialectSQLite {
+		// SQLite allows a single writer; one connection avoids SQLITE_BUSY and
+		// keeps :memory: databases from splitting across connections
+		db.SetMaxOpenConns(1)
+		return
+	}
+	db.SetMaxOpenConns(options.MaxOpenConns)
+	db.SetMaxIdleConns(options.MaxIdleConns)
+	db.SetConnMaxLifetime(options.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(options.ConnMaxIdleTime)
+}
+
+func pingWithRetry(db *sql.DB, retries int, backoff time.Duration) (int, error) {
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+
+	attempt := 1
+	for {
+		err := db.Ping()
+		if err == nil || attempt > retries {
+			return attempt, err
+		}
+
+		log.Printf("Database not ready (attempt %d/%d): %v; retrying in %s", attempt, retries+1, err, backoff)
+		time.Sleep(backoff)
+
+		attempt++
+		backoff *= 2
+		if backoff > maxConnectBackoff {
+			backoff = maxConnectBackoff
+		}
+	}
+}
+
+// PoolStats is a snapshot of the connection pool and retry counters.
+type PoolStats struct {
+	Dialect              Dialect `json:"dialect"`
+	MaxOpenConnections   int     `json:"max_open_connections"`
+	OpenConnections      int     `json:"open_connections"`
+	InUse                int     `json:"in_use"`
+	Idle                 int     `json:"idle"`
+	WaitCount            int64   `json:"wait_count"`
+	WaitDurationMs       int64   `json:"wait_duration_ms"`
+	MaxIdleClosed        int64   `json:"max_idle_closed"`
+	MaxIdleTimeClosed    int64   `json:"max_idle_time_closed"`
+	MaxLifetimeClosed    int64   `json:"max_lifetime_closed"`
+	ConnectAttempts      int     `json:"connect_attempts"`
+	SerializationRetries int64   `json:"serialization_retries"`
+	FollowerReads        bool    `json:"follower_reads"`
+
+	// ReadReplica is the pool of the read replica, when one is configured
+	ReadReplica *PoolStats `json:"read_replica,omitempty"`
+}
+
+func (d *Database) Stats() PoolStats {
+	stats := poolStats(d.DB)
+	stats.Dialect = d.Dialect
+	stats.ConnectAttempts = d.connectAttempts
+	stats.SerializationRetries = d.retriedTransactions.Load()
+	stats.FollowerReads = d.followerReads
+	if d.ReadDB != d.DB {
+		replica := poolStats(d.ReadDB)
+		replica.Dialect = d.Dialect
+		stats.ReadReplica = &replica
+	}
+	return stats
+}
+
+func poolStats(db *sql.DB) PoolStats {
+	stats := db.Stats()
+	return PoolStats{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDurationMs:     stats.WaitDuration.Milliseconds(),
+		MaxIdleClosed:      stats.MaxIdleClosed,
+		MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+	}
 }
 
 func (d *Database) Close() error {
+	if d.ReadDB != nil && d.ReadDB != d.DB {
+		d.ReadDB.Close()
+	}
 	return d.DB.Close()
 }
 
-func (d *Database) InitSchema() error {
-	query := `
-	CREATE TABLE IF NOT EXISTS stocks (
-		id VARCHAR(255) PRIMARY KEY,
-		ticker VARCHAR(50) NOT NULL,
-		company VARCHAR(255) NOT NULL,
-		target_from VARCHAR(50),
-		target_to VARCHAR(50),
-		action VARCHAR(100),
-		brokerage VARCHAR(255),
-		rating_from VARCHAR(50),
-		rating_to VARCHAR(50),
-		time TIMESTAMP NOT NULL,
-		last_updated TIMESTAMP NOT NULL,
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		UNIQUE(ticker, time)
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_stocks_ticker ON stocks(ticker);
-	CREATE INDEX IF NOT EXISTS idx_stocks_time ON stocks(time);
-	CREATE INDEX IF NOT EXISTS idx_stocks_last_updated ON stocks(last_updated);
-	`
-
-	_, err := d.DB.Exec(query)
-	if err != nil {
-		return fmt.Errorf("error creating schema: %w", err)
+// withTimeout derives the context for one operation of the given class.
+func (d *Database) withTimeout(ctx context.Context, class OperationClass) (context.Context, context.CancelFunc) {
+	var timeout time.Duration
+	switch class {
+	case OperationRead:
+		timeout = d.Timeouts.Read
+	case OperationWrite:
+		timeout = d.Timeouts.Write
+	case OperationSearch:
+		timeout = d.Timeouts.Search
 	}
 
-	log.Println("Database schema initialized successfully")
-	return nil
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// wrapTimeout tags err with ErrQueryTimeout when ctx ran out of time. Drivers
+// report cancelled statements in their own words (lib/pq returns SQLSTATE
+// 57014), so the context is the reliable signal.
+func wrapTimeout(ctx context.Context, err error) error {
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) && !errors.Is(err, ErrQueryTimeout) {
+		return fmt.Errorf("%w: %w", ErrQueryTimeout, err)
+	}
+	return err
+}
+
+// caseInsensitiveLike returns the operator for case-insensitive pattern
+// matching. SQLite's LIKE is already case-insensitive for ASCII.
+func (d Dialect) caseInsensitiveLike() string {
+	if d == DialectSQLite {
+		return "LIKE"
+	}
+	return "ILIKE"
+}
+
+// forUpdate returns the row-locking clause for a SELECT inside a transaction.
+// SQLite has no row locks; its single writer already serializes transactions.
+func (d Dialect) forUpdate() string {
+	if d == DialectSQLite {
+		return ""
+	}
+	return " FOR UPDATE"
+}
+
+// newID returns a random 32-character hex identifier, the same shape as the
+// hashed stock ids.
+func newID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("error generating id: %v", err))
+	}
+	return hex.EncodeToString(b[:])
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+func parseDatabaseURL(databaseURL string) (Dialect, string, string, error) {
+	if databaseURL == "" {
+		return "", "", "", fmt.Errorf("DATABASE_URL is not set")
+	}
+
+	scheme, rest, found := strings.Cut(databaseURL, "://")
+	if !found {
+		return "", "", "", fmt.Errorf("DATABASE_URL must start with a scheme such as postgresql:// or sqlite://")
+	}
+
+	switch strings.ToLower(scheme) {
+	case "postgres", "postgresql":
+		return DialectPostgres, "postgres", databaseURL, nil
+
+	case "sqlite", "sqlite3":
+		path, rawQuery, _ := strings.Cut(rest, "?")
+		if path == "" {
+			return "", "", "", fmt.Errorf("sqlite DATABASE_URL needs a file path, e.g. sqlite://stocks.db")
+		}
+
+		params, err := url.ParseQuery(rawQuery)
+		if err != nil {
+			return "", "", "", fmt.Errorf("invalid sqlite DATABASE_URL options: %w", err)
+		}
+		// Store times in a sortable text format and wait on locks instead of failing
+		params.Set("_time_format", "sqlite")
+		params.Add("_pragma", "busy_timeout(5000)")
+		params.Add("_pragma", "foreign_keys(1)")
+		if path != ":memory:" {
+			params.Add("_pragma", "journal_mode(WAL)")
+		}
+
+		return DialectSQLite, "sqlite", "file:" + path + "?" + params.Encode(), nil
+
+	default:
+		return "", "", "", fmt.Errorf("unsupported DATABASE_URL scheme %q", scheme)
+	}
 }
@@ -0,0 +1,598 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+)
+
+// MemoryStockStore is an in-process StockStore with the same upsert, ordering
+// and search semantics as StockRepository. It is meant for tests and demos.
+type MemoryStockStore struct {
+	mu     sync.RWMutex
+	byID   map[string]*models.Stock
+	byKey  map[string]string
+	sorted []*models.Stock
+	now    func() time.Time
+
+	revisions map[string][]models.StockRevision
+	// revisionLog holds every revision by (ChangedAt, ID)
+	revisionLog []models.StockRevision
+	syncRuns    map[string]models.SyncRun
+	consensus   map[string]*models.TickerConsensus
+}
+
+func NewMemoryStockStore() *MemoryStockStore {
+	return &MemoryStockStore{
+		byID:  make(map[string]*models.Stock),
+		byKey: make(map[string]string),
+		now:   time.Now,
+
+		revisions: make(map[string][]models.StockRevision),
+		syncRuns:  make(map[string]models.SyncRun),
+		consensus: make(map[string]*models.TickerConsensus),
+	}
+}
+
+func (s *MemoryStockStore) Create(ctx context.Context, stock *models.Stock, syncRunID string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.createLocked(stock, syncRunID)
+}
+
+// createLocked applies one upsert with s.mu held. It only fails before
+// changing anything, so a failing row leaves the store untouched.
+func (s *MemoryStockStore) createLocked(stock *models.Stock, syncRunID string) error {
+	key := eventKey(stock.Ticker, stock.Time)
+
+	// ON CONFLICT (ticker, time) DO UPDATE
+	if id, exists := s.byKey[key]; exists {
+		existing := s.byID[id]
+		if old := existing.Values(); old != stock.Values() {
+			revision := models.StockRevision{
+				ID:        newID(),
+				StockID:   id,
+				SyncRunID: syncRunID,
+				Old:       old,
+				New:       stock.Values(),
+				ChangedAt: stock.LastUpdated,
+			}
+			s.revisions[id] = append(s.revisions[id], revision)
+			s.insertRevisionLog(revision)
+		}
+
+		existing.TargetFrom = stock.TargetFrom
+		existing.TargetTo = stock.TargetTo
+		existing.Action = stock.Action
+		existing.Brokerage = stock.Brokerage
+		existing.RatingFrom = stock.RatingFrom
+		existing.RatingTo = stock.RatingTo
+		existing.LastUpdated = stock.LastUpdated
+		s.applyConsensus(existing)
+		return nil
+	}
+
+	if _, exists := s.byID[stock.ID]; exists {
+		return fmt.Errorf("duplicate key value violates unique constraint: id %q already exists", stock.ID)
+	}
+
+	stored := *stock
+	stored.CreatedAt = s.now()
+	s.byID[stored.ID] = &stored
+	s.byKey[key] = stored.ID
+	s.insertSorted(&stored)
+	s.applyConsensus(&stored)
+	return nil
+}
+
+// applyConsensus folds a stored event into its ticker's consensus, like the
+// SQL repository does in the upsert transaction.
+func (s *MemoryStockStore) applyConsensus(stock *models.Stock) {
+	consensus, exists := s.consensus[stock.Ticker]
+	if !exists {
+		consensus = &models.TickerConsensus{}
+		s.consensus[stock.Ticker] = consensus
+	}
+
+	if !consensus.Apply(stock) {
+		var events []models.Stock
+		for _, other := range s.sorted {
+			if other.Ticker == stock.Ticker {
+				events = append(events, *other)
+			}
+		}
+		consensus = models.NewTickerConsensus(events)
+		s.consensus[stock.Ticker] = consensus
+	}
+	consensus.UpdatedAt = s.now()
+}
+
+func (s *MemoryStockStore) GetAll(ctx context.Context, limit, offset int) ([]models.Stock, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if limit < 0 || offset < 0 {
+		return nil, fmt.Errorf("limit and offset must not be negative")
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if offset >= len(s.sorted) {
+		return nil, nil
+	}
+
+	end := offset + limit
+	if end > len(s.sorted) {
+		end = len(s.sorted)
+	}
+
+	return copyStocks(s.sorted[offset:end]), nil
+}
+
+func (s *MemoryStockStore) ListStocksAfter(ctx context.Context, afterTime time.Time, afterID string, limit int) ([]models.Stock, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if limit < 0 {
+		return nil, fmt.Errorf("limit must not be negative")
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// s.sorted is newest first with ties in id order, so the page starts
+	// with the events tied with the cursor and then walks back from it one
+	// timestamp at a time.
+	end := sort.Search(len(s.sorted), func(i int) bool {
+		return !s.sorted[i].Time.After(afterTime)
+	})
+	tied := sort.Search(len(s.sorted), func(i int) bool {
+		other := s.sorted[i]
+		return other.Time.Before(afterTime) || (other.Time.Equal(afterTime) && other.ID > afterID)
+	})
+
+	var page []*models.Stock
+	for i := tied; i < len(s.sorted) && len(page) < limit && s.sorted[i].Time.Equal(afterTime); i++ {
+		page = append(page, s.sorted[i])
+	}
+	for end > 0 && len(page) < limit {
+		start := end - 1
+		for start > 0 && s.sorted[start-1].Time.Equal(s.sorted[start].Time) {
+			start--
+		}
+		for _, stock := range s.sorted[start:end] {
+			if len(page) == limit {
+				break
+			}
+			page = append(page, stock)
+		}
+		end = start
+	}
+	return copyStocks(page), nil
+}
+
+func (s *MemoryStockStore) ListTickerEvents(ctx context.Context, ticker string) ([]models.Stock, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var events []*models.Stock
+	for _, stock := range s.sorted {
+		if stock.Ticker == ticker {
+			events = append(events, stock)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Time.Equal(events[j].Time) {
+			return events[i].ID < events[j].ID
+		}
+		return events[i].Time.Before(events[j].Time)
+	})
+	return copyStocks(events), nil
+}
+
+func (s *MemoryStockStore) GetByID(ctx context.Context, id string) (*models.Stock, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stock, exists := s.byID[id]
+	if !exists {
+		return nil, ErrStockNotFound
+	}
+
+	found := *stock
+	return &found, nil
+}
+
+func (s *MemoryStockStore) Search(ctx context.Context, query string) ([]models.Stock, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	needle := strings.ToLower(query)
+	var matches []*models.Stock
+	for _, stock := range s.sorted {
+		if strings.Contains(strings.ToLower(stock.Ticker), needle) ||
+			strings.Contains(strings.ToLower(stock.Company), needle) {
+			matches = append(matches, stock)
+		}
+	}
+
+	return copyStocks(matches), nil
+}
+
+func (s *MemoryStockStore) Count(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.byID), nil
+}
+
+func (s *MemoryStockStore) ListConsensus(ctx context.Context, limit, offset int) ([]models.TickerConsensus, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if limit < 0 || offset < 0 {
+		return nil, fmt.Errorf("limit and offset must not be negative")
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	all := make([]*models.TickerConsensus, 0, len(s.consensus))
+	for _, consensus := range s.consensus {
+		all = append(all, consensus)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].LastActionTime.Equal(all[j].LastActionTime) {
+			return all[i].Ticker < all[j].Ticker
+		}
+		return all[i].LastActionTime.After(all[j].LastActionTime)
+	})
+
+	if offset >= len(all) {
+		return nil, nil
+	}
+	end := offset + limit
+	if end > len(all) {
+		end = len(all)
+	}
+
+	page := make([]models.TickerConsensus, 0, end-offset)
+	for _, consensus := range all[offset:end] {
+		page = append(page, s.copyConsensus(consensus))
+	}
+	return page, nil
+}
+
+func (s *MemoryStockStore) ListConsensusAfter(ctx context.Context, afterTicker string, limit int) ([]models.TickerConsensus, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if limit < 0 {
+		return nil, fmt.Errorf("limit must not be negative")
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tickers := make([]string, 0, len(s.consensus))
+	for ticker := range s.consensus {
+		if ticker > afterTicker {
+			tickers = append(tickers, ticker)
+		}
+	}
+	sort.Strings(tickers)
+
+	if len(tickers) > limit {
+		tickers = tickers[:limit]
+	}
+	page := make([]models.TickerConsensus, 0, len(tickers))
+	for _, ticker := range tickers {
+		page = append(page, s.copyConsensus(s.consensus[ticker]))
+	}
+	return page, nil
+}
+
+func (s *MemoryStockStore) GetConsensus(ctx context.Context, ticker string) (*models.TickerConsensus, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	consensus, exists := s.consensus[ticker]
+	if !exists {
+		return nil, ErrTickerNotFound
+	}
+
+	found := s.copyConsensus(consensus)
+	return &found, nil
+}
+
+func (s *MemoryStockStore) CountConsensus(ctx context.Context) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.consensus), nil
+}
+
+// copyConsensus returns a copy that shares no slices or maps with the store,
+// with Latest loaded like the SQL join does.
+func (s *MemoryStockStore) copyConsensus(consensus *models.TickerConsensus) models.TickerConsensus {
+	copied := *consensus
+	copied.Brokerages = append([]models.BrokerageRating(nil), consensus.Brokerages...)
+	copied.RatingDistribution = make(map[string]int, len(consensus.RatingDistribution))
+	for rating, count := range consensus.RatingDistribution {
+		copied.RatingDistribution[rating] = count
+	}
+	if latest, exists := s.byID[consensus.LatestStockID]; exists {
+		stock := *latest
+		copied.Latest = &stock
+	}
+	return copied
+}
+
+func (s *MemoryStockStore) GetRevisions(ctx context.Context, stockID string) ([]models.StockRevision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stored := s.revisions[stockID]
+	if len(stored) == 0 {
+		return nil, nil
+	}
+
+	revisions := make([]models.StockRevision, len(stored))
+	copy(revisions, stored)
+	sort.SliceStable(revisions, func(i, j int) bool {
+		if revisions[i].ChangedAt.Equal(revisions[j].ChangedAt) {
+			return revisions[i].ID < revisions[j].ID
+		}
+		return revisions[i].ChangedAt.After(revisions[j].ChangedAt)
+	})
+	return revisions, nil
+}
+
+func (s *MemoryStockStore) ListRevisionsAfter(ctx context.Context, afterTime time.Time, afterID string, limit int) ([]models.StockRevision, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if limit < 0 {
+		return nil, fmt.Errorf("limit must not be negative")
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	start := sort.Search(len(s.revisionLog), func(i int) bool {
+		return revisionAfter(s.revisionLog[i], afterTime, afterID)
+	})
+	end := min(start+limit, len(s.revisionLog))
+	if start == end {
+		return nil, nil
+	}
+	return append([]models.StockRevision(nil), s.revisionLog[start:end]...), nil
+}
+
+// insertRevisionLog keeps revisionLog ordered; revisions are recorded in
+// sync order, not change order, since ChangedAt comes from the provider.
+func (s *MemoryStockStore) insertRevisionLog(revision models.StockRevision) {
+	i := sort.Search(len(s.revisionLog), func(i int) bool {
+		return revisionAfter(s.revisionLog[i], revision.ChangedAt, revision.ID)
+	})
+	s.revisionLog = append(s.revisionLog, models.StockRevision{})
+	copy(s.revisionLog[i+1:], s.revisionLog[i:])
+	s.revisionLog[i] = revision
+}
+
+// revisionAfter reports whether revision sorts after (changedAt, id).
+func revisionAfter(revision models.StockRevision, changedAt time.Time, id string) bool {
+	if revision.ChangedAt.Equal(changedAt) {
+		return revision.ID > id
+	}
+	return revision.ChangedAt.After(changedAt)
+}
+
+func (s *MemoryStockStore) CreateSyncRun(ctx context.Context, run *models.SyncRun) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if run.ID == "" {
+		run.ID = newID()
+	}
+	if _, exists := s.syncRuns[run.ID]; exists {
+		return fmt.Errorf("duplicate key value violates unique constraint: sync run %q already exists", run.ID)
+	}
+
+	s.syncRuns[run.ID] = *run
+	return nil
+}
+
+func (s *MemoryStockStore) UpdateSyncRun(ctx context.Context, run *models.SyncRun) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, exists := s.syncRuns[run.ID]
+	if !exists {
+		return ErrSyncRunNotFound
+	}
+
+	// Mirror the SQL UPDATE: identity, limits, mode and start time are immutable
+	updated := *run
+	updated.MaxPages = existing.MaxPages
+	updated.WriteMode = existing.WriteMode
+	updated.StartedAt = existing.StartedAt
+	updated.FailedRows = append([]models.FailedRow(nil), run.FailedRows...)
+	s.syncRuns[run.ID] = updated
+	return nil
+}
+
+func (s *MemoryStockStore) SavePage(ctx context.Context, run *models.SyncRun, stocks []models.Stock, nextPage string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, exists := s.syncRuns[run.ID]
+	if !exists {
+		return ErrSyncRunNotFound
+	}
+
+	saved := *run
+	saved.FailedRows = append([]models.FailedRow(nil), run.FailedRows...)
+	saved.Pages++
+
+	if run.WriteMode != models.SyncWriteBestEffort {
+		// Validate the whole page first so that nothing is applied on failure
+		if err := s.checkPage(stocks); err != nil {
+			return err
+		}
+	}
+
+	for i := range stocks {
+		stock := &stocks[i]
+		if err := s.createLocked(stock, run.ID); err != nil {
+			saved.Failed++
+			saved.FailedRows = append(saved.FailedRows, models.FailedRow{
+				StockID: stock.ID,
+				Ticker:  stock.Ticker,
+				Time:    stock.Time.UTC(),
+				Page:    saved.Pages,
+				Error:   err.Error(),
+			})
+			continue
+		}
+		saved.Stored++
+	}
+
+	saved.Fetched += len(stocks)
+	saved.NextPage = nextPage
+
+	stored := saved
+	stored.MaxPages = existing.MaxPages
+	stored.WriteMode = existing.WriteMode
+	stored.StartedAt = existing.StartedAt
+	s.syncRuns[run.ID] = stored
+
+	*run = saved
+	return nil
+}
+
+// checkPage reports the first row of stocks that createLocked would reject,
+// taking earlier rows of the same page into account.
+func (s *MemoryStockStore) checkPage(stocks []models.Stock) error {
+	pendingKeys := make(map[string]bool)
+	pendingIDs := make(map[string]bool)
+
+	for _, stock := range stocks {
+		key := eventKey(stock.Ticker, stock.Time)
+		if _, exists := s.byKey[key]; exists || pendingKeys[key] {
+			continue
+		}
+		if _, exists := s.byID[stock.ID]; exists || pendingIDs[stock.ID] {
+			return fmt.Errorf("error storing stock %s (%s): duplicate key value violates unique constraint: id %q already exists",
+				stock.ID, stock.Ticker, stock.ID)
+		}
+		pendingKeys[key] = true
+		pendingIDs[stock.ID] = true
+	}
+	return nil
+}
+
+func (s *MemoryStockStore) GetSyncRun(ctx context.Context, id string) (*models.SyncRun, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	run, exists := s.syncRuns[id]
+	if !exists {
+		return nil, ErrSyncRunNotFound
+	}
+	run.FailedRows = append([]models.FailedRow(nil), run.FailedRows...)
+	return &run, nil
+}
+
+// insertSorted keeps s.sorted ordered by time DESC, then id for a stable order
+// between events that share a timestamp.
+func (s *MemoryStockStore) insertSorted(stock *models.Stock) {
+	i := sort.Search(len(s.sorted), func(i int) bool {
+		other := s.sorted[i]
+		if other.Time.Equal(stock.Time) {
+			return other.ID > stock.ID
+		}
+		return other.Time.Before(stock.Time)
+	})
+
+	s.sorted = append(s.sorted, nil)
+	copy(s.sorted[i+1:], s.sorted[i:])
+	s.sorted[i] = stock
+}
+
+// eventKey mirrors UNIQUE(ticker, time); TIMESTAMP columns keep microseconds.
+func eventKey(ticker string, t time.Time) string {
+	return fmt.Sprintf("%s|%d", ticker, t.UnixMicro())
+}
+
+func copyStocks(stocks []*models.Stock) []models.Stock {
+	if len(stocks) == 0 {
+		return nil
+	}
+
+	copied := make([]models.Stock, len(stocks))
+	for i, stock := range stocks {
+		copied[i] = *stock
+	}
+	return copied
+}
@@ -0,0 +1,148 @@
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// files holds the SQL migrations compiled into the binary, one directory per
+// SQL dialect. Every dialect directory carries the same version numbers.
+//
+//go:embed postgres/*.sql sqlite/*.sql
+var files embed.FS
+
+// Dialects lists the dialect directories, in the order Create writes them.
+var Dialects = []string{"postgres", "sqlite"}
+
+// For returns the embedded migrations for a dialect.
+func For(dialect string) ([]Migration, error) {
+	sub, err := fs.Sub(files, dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations, err := Load(sub)
+	if err != nil {
+		return nil, err
+	}
+	if len(migrations) == 0 {
+		return nil, fmt.Errorf("no migrations for dialect %q", dialect)
+	}
+	return migrations, nil
+}
+
+// Migration is a numbered schema change with its up and down scripts.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+var fileNamePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Load reads every NNNN_name.up.sql / NNNN_name.down.sql pair in fsys and
+// returns them sorted by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("error reading migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("error reading migration %s: %w", entry.Name(), err)
+		}
+
+		migration, exists := byVersion[version]
+		if !exists {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		} else if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	var migrations []Migration
+	for _, migration := range byVersion {
+		if strings.TrimSpace(migration.Up) == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Create writes an empty up/down pair into every dialect directory under dir
+// using the next free version number and returns the paths of the new files.
+func Create(dir, name string) ([]string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(name, "_")
+	name = strings.Trim(name, "_")
+	if name == "" {
+		return nil, fmt.Errorf("migration name is required")
+	}
+
+	var next int64 = 1
+	for _, dialect := range Dialects {
+		existing, err := Load(os.DirFS(filepath.Join(dir, dialect)))
+		if err != nil {
+			return nil, err
+		}
+		if len(existing) > 0 && existing[len(existing)-1].Version >= next {
+			next = existing[len(existing)-1].Version + 1
+		}
+	}
+
+	base := fmt.Sprintf("%04d_%s", next, name)
+	var paths []string
+	for _, dialect := range Dialects {
+		paths = append(paths,
+			filepath.Join(dir, dialect, base+".up.sql"),
+			filepath.Join(dir, dialect, base+".down.sql"),
+		)
+	}
+
+	for _, path := range paths {
+		header := fmt.Sprintf("-- %s\n", filepath.Base(path))
+		if err := os.WriteFile(path, []byte(header), 0o644); err != nil {
+			return nil, fmt.Errorf("error creating %s: %w", path, err)
+		}
+	}
+
+	return paths, nil
+}
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS stocks (
+	id VARCHAR(255) PRIMARY KEY,
+	ticker VARCHAR(50) NOT NULL,
+	company VARCHAR(255) NOT NULL,
+	target_from VARCHAR(50),
+	target_to VARCHAR(50),
+	action VARCHAR(100),
+	brokerage VARCHAR(255),
+	rating_from VARCHAR(50),
+	rating_to VARCHAR(50),
+	time TIMESTAMP NOT NULL,
+	last_updated TIMESTAMP NOT NULL,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE(ticker, time)
+);
+
+CREATE INDEX IF NOT EXISTS idx_stocks_ticker ON stocks(ticker);
+CREATE INDEX IF NOT EXISTS idx_stocks_time ON stocks(time);
+CREATE INDEX IF NOT EXISTS idx_stocks_last_updated ON stocks(last_updated);
@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS sync_runs (
+	id VARCHAR(64) PRIMARY KEY,
+	status VARCHAR(20) NOT NULL,
+	max_pages INT NOT NULL DEFAULT 0,
+	pages INT NOT NULL DEFAULT 0,
+	fetched INT NOT NULL DEFAULT 0,
+	stored INT NOT NULL DEFAULT 0,
+	failed INT NOT NULL DEFAULT 0,
+	error TEXT,
+	started_at TIMESTAMP NOT NULL,
+	finished_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_sync_runs_started_at ON sync_runs(started_at);
+
+CREATE TABLE IF NOT EXISTS stock_revisions (
+	id VARCHAR(64) PRIMARY KEY,
+	stock_id VARCHAR(255) NOT NULL,
+	sync_run_id VARCHAR(64),
+	old_target_from VARCHAR(50),
+	old_target_to VARCHAR(50),
+	old_action VARCHAR(100),
+	old_brokerage VARCHAR(255),
+	old_rating_from VARCHAR(50),
+	old_rating_to VARCHAR(50),
+	new_target_from VARCHAR(50),
+	new_target_to VARCHAR(50),
+	new_action VARCHAR(100),
+	new_brokerage VARCHAR(255),
+	new_rating_from VARCHAR(50),
+	new_rating_to VARCHAR(50),
+	changed_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_revisions_stock_id ON stock_revisions(stock_id, changed_at);
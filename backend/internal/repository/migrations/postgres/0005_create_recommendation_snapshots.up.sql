@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS recommendation_snapshots (
+	id VARCHAR(64) PRIMARY KEY,
+	source VARCHAR(20) NOT NULL,
+	side VARCHAR(10) NOT NULL,
+	strategy VARCHAR(50) NOT NULL,
+	strategy_version VARCHAR(50) NOT NULL,
+	profile_version VARCHAR(100) NOT NULL,
+	size INT NOT NULL DEFAULT 0,
+	taken_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_recommendation_snapshots_strategy ON recommendation_snapshots(strategy, side, taken_at);
+
+CREATE TABLE IF NOT EXISTS recommendation_snapshot_entries (
+	snapshot_id VARCHAR(64) NOT NULL REFERENCES recommendation_snapshots(id) ON DELETE CASCADE,
+	rank INT NOT NULL,
+	ticker VARCHAR(50) NOT NULL,
+	stock_id VARCHAR(255) NOT NULL,
+	score DOUBLE PRECISION NOT NULL,
+	reason TEXT NOT NULL,
+	factors TEXT NOT NULL,
+	PRIMARY KEY (snapshot_id, rank)
+);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS securities (
+	ticker VARCHAR(50) PRIMARY KEY,
+	company VARCHAR(255) NOT NULL DEFAULT '',
+	sector VARCHAR(100) NOT NULL DEFAULT '',
+	industry VARCHAR(150) NOT NULL DEFAULT '',
+	updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_securities_sector ON securities(sector);
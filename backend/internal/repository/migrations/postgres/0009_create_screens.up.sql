@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS screens (
+	name VARCHAR(64) PRIMARY KEY,
+	expression TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,2 @@
+-- Backtests page through every revision by (changed_at, id); retention prunes by changed_at
+CREATE INDEX IF NOT EXISTS idx_stock_revisions_changed_at_id ON stock_revisions(changed_at, id);
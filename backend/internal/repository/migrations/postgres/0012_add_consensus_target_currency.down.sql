@@ -0,0 +1 @@
+ALTER TABLE ticker_consensus DROP COLUMN target_currency;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS stock_revisions;
+DROP TABLE IF EXISTS sync_runs;
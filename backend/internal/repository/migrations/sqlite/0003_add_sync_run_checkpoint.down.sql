@@ -0,0 +1,3 @@
+ALTER TABLE sync_runs DROP COLUMN failed_rows;
+ALTER TABLE sync_runs DROP COLUMN next_page;
+ALTER TABLE sync_runs DROP COLUMN write_mode;
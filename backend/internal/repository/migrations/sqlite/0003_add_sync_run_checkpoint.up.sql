@@ -0,0 +1,3 @@
+ALTER TABLE sync_runs ADD COLUMN write_mode VARCHAR(20) NOT NULL DEFAULT 'best_effort';
+ALTER TABLE sync_runs ADD COLUMN next_page TEXT;
+ALTER TABLE sync_runs ADD COLUMN failed_rows TEXT;
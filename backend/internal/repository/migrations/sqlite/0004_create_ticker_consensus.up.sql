@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS ticker_consensus (
+	ticker VARCHAR(50) PRIMARY KEY,
+	company VARCHAR(255) NOT NULL,
+	brokerage_ratings TEXT NOT NULL,
+	rating_distribution TEXT NOT NULL,
+	covering_brokerages INT NOT NULL DEFAULT 0,
+	mean_target REAL,
+	median_target REAL,
+	high_target REAL,
+	low_target REAL,
+	latest_stock_id VARCHAR(255) NOT NULL,
+	last_action_time TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_ticker_consensus_last_action_time ON ticker_consensus(last_action_time);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS recommendation_snapshot_entries;
+DROP TABLE IF EXISTS recommendation_snapshots;
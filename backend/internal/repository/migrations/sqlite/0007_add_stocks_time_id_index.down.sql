@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS idx_stocks_time ON stocks(time);
+DROP INDEX IF EXISTS idx_stocks_time_id;
@@ -0,0 +1,3 @@
+-- Keyset scans order events by (time, id); the new index also serves time alone
+CREATE INDEX IF NOT EXISTS idx_stocks_time_id ON stocks(time, id);
+DROP INDEX IF EXISTS idx_stocks_time;
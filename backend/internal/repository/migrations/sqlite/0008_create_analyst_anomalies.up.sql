@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS analyst_anomalies (
+	id VARCHAR(64) PRIMARY KEY,
+	scope VARCHAR(10) NOT NULL,
+	subject VARCHAR(255) NOT NULL,
+	kind VARCHAR(20) NOT NULL,
+	direction VARCHAR(10) NOT NULL,
+	window_start TIMESTAMP NOT NULL,
+	window_end TIMESTAMP NOT NULL,
+	observed REAL NOT NULL,
+	baseline_mean REAL NOT NULL,
+	baseline_stddev REAL NOT NULL,
+	z_score REAL NOT NULL,
+	events INT NOT NULL,
+	bullish INT NOT NULL,
+	bearish INT NOT NULL,
+	detected_at TIMESTAMP NOT NULL,
+	UNIQUE (scope, subject, kind, window_end)
+);
+
+CREATE INDEX IF NOT EXISTS idx_analyst_anomalies_window_end ON analyst_anomalies(window_end);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS sync_payloads (
+	id VARCHAR(64) PRIMARY KEY,
+	sync_run_id VARCHAR(64) NOT NULL REFERENCES sync_runs(id) ON DELETE CASCADE,
+	page INT NOT NULL,
+	body TEXT NOT NULL,
+	fetched_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_sync_payloads_fetched_at ON sync_payloads(fetched_at);
+CREATE INDEX IF NOT EXISTS idx_sync_payloads_sync_run_id ON sync_payloads(sync_run_id, page);
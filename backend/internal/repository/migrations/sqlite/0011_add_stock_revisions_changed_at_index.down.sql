@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_stock_revisions_changed_at_id;
@@ -0,0 +1,4 @@
+ALTER TABLE ticker_consensus ADD COLUMN target_currency VARCHAR(3);
+-- Target statistics used to mix currencies; the server rebuilds the emptied
+-- table when it starts, or run `maintenance rebuild-consensus`
+DELETE FROM ticker_consensus;
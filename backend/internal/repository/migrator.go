@@ -0,0 +1,368 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository/migrations"
+)
+
+const (
+	// migrationLockTimeout is how long Up/Down/Goto wait for another migrator to finish
+	migrationLockTimeout = 30 * time.Second
+	// migrationLockStaleAfter is when a lock left behind by a crashed migrator is ignored
+	migrationLockStaleAfter = 15 * time.Minute
+)
+
+// ErrSchemaOutdated is returned by EnsureCurrent when pending migrations exist.
+var ErrSchemaOutdated = errors.New("database schema is out of date")
+
+// MigrationStatus reports whether a known migration has been applied.
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+type Migrator struct {
+	db         *Database
+	migrations []migrations.Migration
+	owner      string
+}
+
+func NewMigrator(db *Database) (*Migrator, error) {
+	loaded, err := migrations.For(string(db.Dialect))
+	if err != nil {
+		return nil, err
+	}
+
+	host, _ := os.Hostname()
+	return &Migrator{
+		db:         db,
+		migrations: loaded,
+		owner:      fmt.Sprintf("%s:%d", host, os.Getpid()),
+	}, nil
+}
+
+// Latest returns the highest migration version embedded in the binary.
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// CurrentVersion returns the highest applied migration version, or 0 if none.
+// It only reads: a database without schema_migrations is at version 0.
+func (m *Migrator) CurrentVersion(ctx context.Context) (int64, error) {
+	exists, err := m.hasMigrationTable(ctx)
+	if err != nil || !exists {
+		return 0, err
+	}
+
+	var version sql.NullInt64
+	if err := m.db.DB.QueryRowContext(ctx, "SELECT MAX(version) FROM schema_migrations").Scan(&version); err != nil {
+		return 0, fmt.Errorf("error reading schema version: %w", err)
+	}
+	return version.Int64, nil
+}
+
+// EnsureCurrent fails with ErrSchemaOutdated when any migration compiled
+// into the binary has not been applied, including one skipped below the
+// latest applied version. It never changes the schema.
+func (m *Migrator) EnsureCurrent(ctx context.Context) error {
+	applied := map[int64]time.Time{}
+	exists, err := m.hasMigrationTable(ctx)
+	if err != nil {
+		return err
+	}
+	if exists {
+		if applied, err = m.readApplied(ctx); err != nil {
+			return err
+		}
+	}
+
+	var missing []int64
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			missing = append(missing, migration.Version)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: migrations %v not applied", ErrSchemaOutdated, missing)
+	}
+	for version := range applied {
+		if m.find(version) == nil {
+			log.Printf("Database has migration %d, which this binary does not know", version)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if appliedAt, ok := applied[migration.Version]; ok {
+			appliedAt := appliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// Up applies every pending migration and returns how many were applied.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	return m.Goto(ctx, m.Latest())
+}
+
+// Down rolls back the n most recently applied migrations.
+func (m *Migrator) Down(ctx context.Context, n int) (int, error) {
+	if n <= 0 {
+		return 0, fmt.Errorf("number of migrations to roll back must be positive")
+	}
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var target int64
+	count := 0
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		if _, ok := applied[m.migrations[i].Version]; !ok {
+			continue
+		}
+		count++
+		if count > n {
+			target = m.migrations[i].Version
+			break
+		}
+	}
+
+	return m.Goto(ctx, target)
+}
+
+// Goto migrates up or down until exactly the migrations up to version are
+// applied. Version 0 rolls back everything.
+func (m *Migrator) Goto(ctx context.Context, version int64) (int, error) {
+	if version != 0 && m.find(version) == nil {
+		return 0, fmt.Errorf("unknown migration version %d", version)
+	}
+
+	if err := m.ensureTables(ctx); err != nil {
+		return 0, err
+	}
+
+	if err := m.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer m.unlock()
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+
+	// Roll back newest first
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok || migration.Version <= version {
+			continue
+		}
+		if err := m.apply(ctx, migration, false); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	// Then apply oldest first
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok || migration.Version > version {
+			continue
+		}
+		if err := m.apply(ctx, migration, true); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+func (m *Migrator) apply(ctx context.Context, migration migrations.Migration, up bool) error {
+	direction := "up"
+	script := migration.Up
+	if !up {
+		direction = "down"
+		script = migration.Down
+		if script == "" {
+			return fmt.Errorf("migration %d_%s has no down script", migration.Version, migration.Name)
+		}
+	}
+
+	tx, err := m.db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("error running migration %d_%s (%s): %w", migration.Version, migration.Name, direction, err)
+	}
+
+	if up {
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)",
+			migration.Version, migration.Name, time.Now().UTC())
+	} else {
+		_, err = tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("error recording migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	log.Printf("Migration %d_%s %s", migration.Version, migration.Name, direction)
+	return nil
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int64]time.Time, error) {
+	if err := m.ensureTables(ctx); err != nil {
+		return nil, err
+	}
+	return m.readApplied(ctx)
+}
+
+// readApplied reads schema_migrations, which must exist.
+func (m *Migrator) readApplied(ctx context.Context) (map[int64]time.Time, error) {
+	rows, err := m.db.DB.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("error reading applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+func (m *Migrator) find(version int64) *migrations.Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+// hasMigrationTable reports whether schema_migrations exists, without
+// creating it.
+func (m *Migrator) hasMigrationTable(ctx context.Context) (bool, error) {
+	query := `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = 'schema_migrations'`
+	if m.db.Dialect == DialectSQLite {
+		query = `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'`
+	}
+
+	var count int
+	if err := m.db.DB.QueryRowContext(ctx, query).Scan(&count); err != nil {
+		return false, fmt.Errorf("error looking for schema_migrations: %w", err)
+	}
+	return count > 0, nil
+}
+
+func (m *Migrator) ensureTables(ctx context.Context) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS schema_migrations_lock (
+		id INT PRIMARY KEY,
+		owner VARCHAR(255) NOT NULL,
+		locked_at TIMESTAMP NOT NULL
+	);
+	`
+
+	if _, err := m.db.DB.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("error creating migration tables: %w", err)
+	}
+	return nil
+}
+
+// lock takes the single-row migration lock so that two migrators (for example
+// two deploys starting at once) never run scripts concurrently.
+func (m *Migrator) lock(ctx context.Context) error {
+	deadline := time.Now().Add(migrationLockTimeout)
+
+	for {
+		result, err := m.db.DB.ExecContext(ctx,
+			"INSERT INTO schema_migrations_lock (id, owner, locked_at) VALUES (1, $1, $2) ON CONFLICT (id) DO NOTHING",
+			m.owner, time.Now().UTC())
+		if err != nil {
+			return fmt.Errorf("error acquiring migration lock: %w", err)
+		}
+		if affected, _ := result.RowsAffected(); affected == 1 {
+			return nil
+		}
+
+		var owner string
+		var lockedAt time.Time
+		err = m.db.DB.QueryRowContext(ctx, "SELECT owner, locked_at FROM schema_migrations_lock WHERE id = 1").Scan(&owner, &lockedAt)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("error reading migration lock: %w", err)
+		}
+
+		if err == nil && time.Since(lockedAt) > migrationLockStaleAfter {
+			log.Printf("Removing stale migration lock held by %s since %s", owner, lockedAt.Format(time.RFC3339))
+			if _, err := m.db.DB.ExecContext(ctx, "DELETE FROM schema_migrations_lock WHERE id = 1 AND owner = $1", owner); err != nil {
+				return fmt.Errorf("error removing stale migration lock: %w", err)
+			}
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("migration lock held by %s since %s", owner, lockedAt.Format(time.RFC3339))
+		}
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (m *Migrator) unlock() {
+	if _, err := m.db.DB.Exec("DELETE FROM schema_migrations_lock WHERE id = 1 AND owner = $1", m.owner); err != nil {
+		log.Printf("Error releasing migration lock: %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository"
+)
+
+// newTestDatabase opens an empty in-memory SQLite database, closed when the
+// test ends.
+func newTestDatabase(t testing.TB) *repository.Database {
+	t.Helper()
+	db, err := repository.NewDatabase("sqlite://:memory:", repository.DatabaseOptions{})
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func tableExists(t *testing.T, db *repository.Database, table string) bool {
+	t.Helper()
+	var count int
+	err := db.DB.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = $1", table).Scan(&count)
+	if err != nil {
+		t.Fatalf("checking table %s: %v", table, err)
+	}
+	return count == 1
+}
+
+func TestMigratorUpDownGoto(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDatabase(t)
+	migrator, err := repository.NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest := migrator.Latest()
+
+	if err := migrator.EnsureCurrent(ctx); !errors.Is(err, repository.ErrSchemaOutdated) {
+		t.Fatalf("EnsureCurrent on an empty database = %v, want ErrSchemaOutdated", err)
+	}
+	if version, err := migrator.CurrentVersion(ctx); err != nil || version != 0 {
+		t.Errorf("CurrentVersion on an empty database = %d, %v; want 0, nil", version, err)
+	}
+	if tableExists(t, db, "schema_migrations") {
+		t.Error("checking the version of an empty database created schema_migrations")
+	}
+
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if int64(applied) != latest {
+		t.Errorf("Up applied %d migrations, want %d", applied, latest)
+	}
+	if err := migrator.EnsureCurrent(ctx); err != nil {
+		t.Errorf("EnsureCurrent after Up: %v", err)
+	}
+	if applied, err := migrator.Up(ctx); err != nil || applied != 0 {
+		t.Errorf("second Up = %d, %v; want 0, nil", applied, err)
+	}
+
+	if _, err := migrator.Down(ctx, 1); err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	if version, _ := migrator.CurrentVersion(ctx); version != latest-1 {
+		t.Errorf("version after Down(1) = %d, want %d", version, latest-1)
+	}
+
+	if _, err := migrator.Goto(ctx, 3); err != nil {
+		t.Fatalf("Goto(3): %v", err)
+	}
+	if version, _ := migrator.CurrentVersion(ctx); version != 3 {
+		t.Errorf("version after Goto(3) = %d, want 3", version)
+	}
+	if !tableExists(t, db, "sync_runs") || tableExists(t, db, "ticker_consensus") {
+		t.Error("Goto(3) should keep sync_runs (0002) and drop ticker_consensus (0004)")
+	}
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if status.Applied != (status.Version <= 3) {
+			t.Errorf("migration %d applied = %v after Goto(3)", status.Version, status.Applied)
+		}
+	}
+
+	if _, err := migrator.Goto(ctx, 0); err != nil {
+		t.Fatalf("Goto(0): %v", err)
+	}
+	if tableExists(t, db, "stocks") {
+		t.Error("Goto(0) should drop stocks")
+	}
+
+	// Every down script undid its up script, so the whole chain applies again
+	if applied, err := migrator.Up(ctx); err != nil || int64(applied) != latest {
+		t.Errorf("Up after Goto(0) = %d, %v; want %d, nil", applied, err, latest)
+	}
+}
+
+func TestEnsureCurrentFindsASkippedMigration(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDatabase(t)
+	migrator, err := repository.NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// The latest version stays applied, only one below it is missing
+	if _, err := db.DB.Exec("DELETE FROM schema_migrations WHERE version = 5"); err != nil {
+		t.Fatal(err)
+	}
+	if version, _ := migrator.CurrentVersion(ctx); version != migrator.Latest() {
+		t.Fatalf("version = %d, want %d", version, migrator.Latest())
+	}
+
+	err = migrator.EnsureCurrent(ctx)
+	if !errors.Is(err, repository.ErrSchemaOutdated) || !strings.Contains(err.Error(), "[5]") {
+		t.Errorf("EnsureCurrent without migration 5 = %v, want ErrSchemaOutdated naming it", err)
+	}
+}
+
+func TestMigratorGotoUnknownVersion(t *testing.T) {
+	migrator, err := repository.NewMigrator(newTestDatabase(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Goto(context.Background(), migrator.Latest()+1); err == nil {
+		t.Error("Goto past the latest migration should fail")
+	}
+	if _, err := migrator.Down(context.Background(), 0); err == nil {
+		t.Error("Down(0) should fail")
+	}
+}
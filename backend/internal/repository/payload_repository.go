@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+)
+
+// PayloadRepository keeps the raw provider responses of syncs. Like
+// snapshots, payloads only exist in SQL databases.
+type PayloadRepository struct {
+	db *Database
+}
+
+func NewPayloadRepository(db *Database) *PayloadRepository {
+	return &PayloadRepository{db: db}
+}
+
+// SavePayload stores one provider response, assigning its id when empty.
+func (r *PayloadRepository) SavePayload(ctx context.Context, payload *models.SyncPayload) error {
+	ctx, cancel := r.db.withTimeout(ctx, OperationWrite)
+	defer cancel()
+
+	if payload.ID == "" {
+		payload.ID = newID()
+	}
+	_, err := r.db.DB.ExecContext(ctx, `
+		INSERT INTO sync_payloads (id, sync_run_id, page, body, fetched_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, payload.ID, payload.SyncRunID, payload.Page, payload.Body, payload.FetchedAt.UTC())
+	return wrapTimeout(ctx, err)
+}
+
+// ListPayloads returns the payloads of a sync run in page order.
+func (r *PayloadRepository) ListPayloads(ctx context.Context, syncRunID string) ([]models.SyncPayload, error) {
+	ctx, cancel := r.db.withTimeout(ctx, OperationRead)
+	defer cancel()
+
+	rows, err := r.db.reader(ctx).QueryContext(ctx, `
+		SELECT id, sync_run_id, page, body, fetched_at
+		FROM sync_payloads`+r.db.staleClause(ctx)+`
+		WHERE sync_run_id = $1
+		ORDER BY page
+	`, syncRunID)
+	if err != nil {
+		return nil, wrapTimeout(ctx, err)
+	}
+	defer rows.Close()
+
+	payloads := []models.SyncPayload{}
+	for rows.Next() {
+		var payload models.SyncPayload
+		if err := rows.Scan(&payload.ID, &payload.SyncRunID, &payload.Page, &payload.Body, &payload.FetchedAt); err != nil {
+			return nil, err
+		}
+		payloads = append(payloads, payload)
+	}
+	return payloads, wrapTimeout(ctx, rows.Err())
+}
@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+type freshReadsKey struct{}
+
+// WithFreshReads marks ctx as requiring up-to-date data: reads made with it
+// go to the primary and skip follower reads.
+func WithFreshReads(ctx context.Context) context.Context {
+	return context.WithValue(ctx, freshReadsKey{}, true)
+}
+
+// FreshReadsRequested reports whether WithFreshReads was applied to ctx.
+func FreshReadsRequested(ctx context.Context) bool {
+	fresh, _ := ctx.Value(freshReadsKey{}).(bool)
+	return fresh
+}
+
+// reader returns the pool for a stale-tolerant read.
+func (d *Database) reader(ctx context.Context) *sql.DB {
+	if FreshReadsRequested(ctx) || d.ReadDB == nil {
+		return d.DB
+	}
+	return d.ReadDB
+}
+
+// staleClause goes right after the table name of a stale-tolerant read and
+// lets CockroachDB serve it from the nearest replica when follower reads
+// are enabled.
+func (d *Database) staleClause(ctx context.Context) string {
+	if !d.followerReads || FreshReadsRequested(ctx) {
+		return ""
+	}
+	return " AS OF SYSTEM TIME follower_read_timestamp()"
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+func TestReadRouting(t *testing.T) {
+	primary, err := sql.Open("sqlite", "file::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer primary.Close()
+	replica, err := sql.Open("sqlite", "file::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replica.Close()
+
+	stale := context.Background()
+	fresh := WithFreshReads(stale)
+	const clause = " AS OF SYSTEM TIME follower_read_timestamp()"
+	tests := []struct {
+		name   string
+		db     *Database
+		ctx    context.Context
+		reader *sql.DB
+		clause string
+	}{
+		{"no replica", &Database{DB: primary}, stale, primary, ""},
+		{"replica", &Database{DB: primary, ReadDB: replica}, stale, replica, ""},
+		{"fresh skips the replica", &Database{DB: primary, ReadDB: replica}, fresh, primary, ""},
+		{"follower reads", &Database{DB: primary, ReadDB: primary, followerReads: true}, stale, primary, clause},
+		{"fresh skips follower reads", &Database{DB: primary, ReadDB: replica, followerReads: true}, fresh, primary, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.db.reader(tt.ctx); got != tt.reader {
+			t.Errorf("%s: read from the wrong pool", tt.name)
+		}
+		if got := tt.db.staleClause(tt.ctx); got != tt.clause {
+			t.Errorf("%s: staleClause = %q, want %q", tt.name, got, tt.clause)
+		}
+	}
+}
+
+func TestFollowerReadsNeedCockroachDB(t *testing.T) {
+	db, err := NewDatabase("sqlite://:memory:", DatabaseOptions{FollowerReads: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if db.Stats().FollowerReads || db.staleClause(context.Background()) != "" {
+		t.Error("follower reads were enabled on SQLite")
+	}
+}
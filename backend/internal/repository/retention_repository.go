@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// retentionBatchSize bounds how many rows one DELETE removes, which keeps
+// CockroachDB transactions small when pruning large tables.
+const retentionBatchSize = 500
+
+// RetentionTarget is a table that retention rules can prune by age.
+type RetentionTarget struct {
+	Table      string
+	TimeColumn string
+	KeyColumn  string
+	// Consensus marks stock events: ticker_consensus is derived from them,
+	// so the consensus of every pruned ticker is recomputed with the delete.
+	Consensus bool
+}
+
+// RetentionTargets lists the prunable tables by name.
+var RetentionTargets = map[string]RetentionTarget{
+	"stocks":          {Table: "stocks", TimeColumn: "time", KeyColumn: "id", Consensus: true},
+	"stock_revisions": {Table: "stock_revisions", TimeColumn: "changed_at", KeyColumn: "id"},
+	"sync_runs":       {Table: "sync_runs", TimeColumn: "started_at", KeyColumn: "id"},
+	// Payloads also go with their sync run through ON DELETE CASCADE
+	"sync_payloads": {Table: "sync_payloads", TimeColumn: "fetched_at", KeyColumn: "id"},
+	// Entries go with their snapshot through ON DELETE CASCADE
+	"recommendation_snapshots": {Table: "recommendation_snapshots", TimeColumn: "taken_at", KeyColumn: "id"},
+	"analyst_anomalies":        {Table: "analyst_anomalies", TimeColumn: "window_end", KeyColumn: "id"},
+}
+
+// ArchiveFunc receives a batch of rows, keyed by column name, before they are
+// deleted. Returning an error stops pruning without deleting the batch.
+type ArchiveFunc func(rows []map[string]interface{}) error
+
+// RetentionRepository counts and deletes rows older than a cutoff. Like the
+// Migrator it works on the SQL database directly.
+type RetentionRepository struct {
+	db *Database
+}
+
+func NewRetentionRepository(db *Database) *RetentionRepository {
+	return &RetentionRepository{db: db}
+}
+
+func (r *RetentionRepository) target(table string) (RetentionTarget, error) {
+	target, ok := RetentionTargets[table]
+	if !ok {
+		return RetentionTarget{}, fmt.Errorf("table %q does not support retention", table)
+	}
+	return target, nil
+}
+
+// CountExpired returns how many rows of table are older than cutoff.
+func (r *RetentionRepository) CountExpired(ctx context.Context, table string, cutoff time.Time) (int, error) {
+	target, err := r.target(table)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := r.db.withTimeout(ctx, OperationRead)
+	defer cancel()
+
+	var count int
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s < $1", target.Table, target.TimeColumn)
+	err = r.db.DB.QueryRowContext(ctx, query, cutoff.UTC()).Scan(&count)
+	return count, wrapTimeout(ctx, err)
+}
+
+// DeleteExpired removes rows of table older than cutoff in batches, handing
+// each batch to archive first when it is not nil. It returns the number of
+// rows deleted.
+func (r *RetentionRepository) DeleteExpired(ctx context.Context, table string, cutoff time.Time, archive ArchiveFunc) (int, error) {
+	target, err := r.target(table)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for {
+		rows, err := r.selectExpired(ctx, target, cutoff)
+		if err != nil {
+			return deleted, err
+		}
+		if len(rows) == 0 {
+			return deleted, nil
+		}
+
+		if archive != nil {
+			if err := archive(rows); err != nil {
+				return deleted, fmt.Errorf("error archiving %s: %w", table, err)
+			}
+		}
+
+		keys := make([]interface{}, len(rows))
+		placeholders := make([]string, len(rows))
+		for i, row := range rows {
+			keys[i] = row[target.KeyColumn]
+			placeholders[i] = fmt.Sprintf("$%d", i+1)
+		}
+
+		query := fmt.Sprintf("DELETE FROM %s WHERE %s IN (%s)",
+			target.Table, target.KeyColumn, strings.Join(placeholders, ", "))
+		affected, err := r.deleteBatch(ctx, target, query, keys, batchTickers(target, rows))
+		if err != nil {
+			return deleted, fmt.Errorf("error deleting from %s: %w", table, err)
+		}
+		// The same rows would be selected again forever
+		if affected == 0 {
+			return deleted, fmt.Errorf("error deleting from %s: none of %d expired rows was deleted", table, len(rows))
+		}
+		deleted += affected
+	}
+}
+
+// deleteBatch runs one batch delete and, for stock events, recomputes the
+// consensus of the tickers it touched in the same transaction, so no
+// consensus row is left pointing at a deleted event.
+func (r *RetentionRepository) deleteBatch(ctx context.Context, target RetentionTarget, query string, keys []interface{}, tickers []string) (int, error) {
+	ctx, cancel := r.db.withTimeout(ctx, OperationWrite)
+	defer cancel()
+
+	stocks := NewStockRepository(r.db)
+	var affected int64
+	err := r.db.inTx(ctx, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, query, keys...)
+		if err != nil {
+			return err
+		}
+		if affected, err = result.RowsAffected(); err != nil {
+			return err
+		}
+		for _, ticker := range tickers {
+			if err := stocks.refreshConsensus(ctx, tx, ticker); err != nil {
+				return fmt.Errorf("error refreshing consensus of %s: %w", ticker, err)
+			}
+		}
+		return nil
+	})
+	return int(affected), wrapTimeout(ctx, err)
+}
+
+// batchTickers returns the distinct tickers of a batch of stock events, or
+// nil for targets without consensus.
+func batchTickers(target RetentionTarget, rows []map[string]interface{}) []string {
+	if !target.Consensus {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var tickers []string
+	for _, row := range rows {
+		ticker := fmt.Sprint(row["ticker"])
+		if !seen[ticker] {
+			seen[ticker] = true
+			tickers = append(tickers, ticker)
+		}
+	}
+	sort.Strings(tickers)
+	return tickers
+}
+
+func (r *RetentionRepository) selectExpired(ctx context.Context, target RetentionTarget, cutoff time.Time) ([]map[string]interface{}, error) {
+	ctx, cancel := r.db.withTimeout(ctx, OperationRead)
+	defer cancel()
+
+	query := fmt.Sprintf("SELECT * FROM %s WHERE %s < $1 ORDER BY %s LIMIT %d",
+		target.Table, target.TimeColumn, target.TimeColumn, retentionBatchSize)
+
+	rows, err := r.db.DB.QueryContext(ctx, query, cutoff.UTC())
+	if err != nil {
+		return nil, wrapTimeout(ctx, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []map[string]interface{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			if b, ok := values[i].([]byte); ok {
+				values[i] = string(b)
+			}
+			row[column] = values[i]
+		}
+		result = append(result, row)
+	}
+
+	return result, wrapTimeout(ctx, rows.Err())
+}
+
+// SupportsRowLevelTTL reports whether the database is CockroachDB, which can
+// expire rows itself through row-level TTL.
+func (r *RetentionRepository) SupportsRowLevelTTL(ctx context.Context) (bool, error) {
+	return r.db.isCockroachDB(ctx)
+}
+
+// ApplyRowLevelTTL configures CockroachDB row-level TTL so that rows of table
+// expire maxAge after their time column, deleted by a daily background job.
+func (r *RetentionRepository) ApplyRowLevelTTL(ctx context.Context, table string, maxAge time.Duration) error {
+	target, err := r.target(table)
+	if err != nil {
+		return err
+	}
+
+	seconds := int64(maxAge / time.Second)
+	expression := fmt.Sprintf("((%s AT TIME ZONE 'UTC') + INTERVAL '%d seconds')", target.TimeColumn, seconds)
+	query := fmt.Sprintf("ALTER TABLE %s SET (ttl_expiration_expression = '%s', ttl_job_cron = '@daily')",
+		target.Table, strings.ReplaceAll(expression, "'", "''"))
+
+	if _, err := r.db.DB.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("error configuring row-level TTL on %s: %w", table, err)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository/storetest"
+)
+
+func TestDeleteExpiredBatchesAndRefreshesConsensus(t *testing.T) {
+	ctx := context.Background()
+	db := newMigratedDatabase(t)
+	stocks := repository.NewStockRepository(db)
+
+	var events []models.Stock
+	// 1,100 expired events of one ticker, so pruning takes several batches
+	for i := 0; i < 1100; i++ {
+		events = append(events, storetest.NewStock("BULK", "Bulk Corp", -time.Duration(i+1)*time.Hour))
+	}
+	events = append(events, storetest.NewStock("BULK", "Bulk Corp", time.Hour))
+	// A ticker whose only event expires
+	events = append(events, storetest.NewStock("GONE", "Gone Inc", -24*time.Hour))
+	// A ticker that loses one of its two brokerages
+	oldView := storetest.NewStock("MIX", "Mix Ltd", -48*time.Hour)
+	oldView.Brokerage = "Old Brokerage"
+	events = append(events, oldView, storetest.NewStock("MIX", "Mix Ltd", time.Hour))
+	for i := range events {
+		if err := stocks.Create(ctx, &events[i], ""); err != nil {
+			t.Fatalf("Create(%s): %v", events[i].ID, err)
+		}
+	}
+
+	cutoff := events[len(events)-1].Time.Add(-time.Hour)
+	retention := repository.NewRetentionRepository(db)
+	if expired, err := retention.CountExpired(ctx, "stocks", cutoff); err != nil || expired != 1102 {
+		t.Fatalf("CountExpired = %d, %v; want 1102, nil", expired, err)
+	}
+
+	var batches []int
+	deleted, err := retention.DeleteExpired(ctx, "stocks", cutoff, func(rows []map[string]interface{}) error {
+		batches = append(batches, len(rows))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("DeleteExpired: %v", err)
+	}
+	if deleted != 1102 {
+		t.Errorf("deleted %d rows, want 1102", deleted)
+	}
+	if fmt.Sprint(batches) != "[500 500 102]" {
+		t.Errorf("archived batches of %v, want [500 500 102]", batches)
+	}
+	if count, _ := stocks.Count(ctx); count != 2 {
+		t.Errorf("%d events left, want 2", count)
+	}
+
+	// Every remaining consensus row still joins to its latest event
+	consensus, err := stocks.ListConsensusAfter(ctx, "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tickers []string
+	for _, c := range consensus {
+		tickers = append(tickers, c.Ticker)
+	}
+	if fmt.Sprint(tickers) != "[BULK MIX]" {
+		t.Errorf("consensus tickers = %v, want [BULK MIX]", tickers)
+	}
+	if _, err := stocks.GetConsensus(ctx, "GONE"); !errors.Is(err, repository.ErrTickerNotFound) {
+		t.Errorf("GetConsensus(GONE) = %v, want ErrTickerNotFound", err)
+	}
+	mix, err := stocks.GetConsensus(ctx, "MIX")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mix.CoveringBrokerages != 1 {
+		t.Errorf("MIX covered by %d brokerages after prune, want 1", mix.CoveringBrokerages)
+	}
+}
+
+func TestDeleteExpiredArchiveErrorKeepsBatch(t *testing.T) {
+	ctx := context.Background()
+	db := newMigratedDatabase(t)
+	stock := storetest.NewStock("AAPL", "Apple Inc.", -time.Hour)
+	if err := repository.NewStockRepository(db).Create(ctx, &stock, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	retention := repository.NewRetentionRepository(db)
+	failing := errors.New("disk full")
+	deleted, err := retention.DeleteExpired(ctx, "stocks", stock.Time.Add(time.Hour), func([]map[string]interface{}) error {
+		return failing
+	})
+	if !errors.Is(err, failing) || deleted != 0 {
+		t.Errorf("DeleteExpired = %d, %v; want 0, %v", deleted, err, failing)
+	}
+	if expired, _ := retention.CountExpired(ctx, "stocks", stock.Time.Add(time.Hour)); expired != 1 {
+		t.Errorf("%d expired rows left, want 1", expired)
+	}
+}
+
+func TestSyncPayloadRetention(t *testing.T) {
+	ctx := context.Background()
+	db := newMigratedDatabase(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	run := &models.SyncRun{ID: "run-1", Status: models.SyncRunRunning, WriteMode: models.SyncWriteAtomic, MaxPages: 2, StartedAt: now.Add(-48 * time.Hour)}
+	if err := repository.NewStockRepository(db).CreateSyncRun(ctx, run); err != nil {
+		t.Fatal(err)
+	}
+	payloads := repository.NewPayloadRepository(db)
+	for page, fetchedAt := range []time.Time{now.Add(-48 * time.Hour), now} {
+		payload := &models.SyncPayload{SyncRunID: run.ID, Page: page + 1, Body: `{"items":[]}`, FetchedAt: fetchedAt}
+		if err := payloads.SavePayload(ctx, payload); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	retention := repository.NewRetentionRepository(db)
+	if deleted, err := retention.DeleteExpired(ctx, "sync_payloads", now.Add(-24*time.Hour), nil); err != nil || deleted != 1 {
+		t.Fatalf("DeleteExpired(sync_payloads) = %d, %v; want 1, nil", deleted, err)
+	}
+	kept, err := payloads.ListPayloads(ctx, run.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kept) != 1 || kept[0].Page != 2 {
+		t.Fatalf("kept payloads %+v, want page 2 only", kept)
+	}
+
+	// Pruning the run takes its remaining payloads along
+	if _, err := retention.DeleteExpired(ctx, "sync_runs", now.Add(-24*time.Hour), nil); err != nil {
+		t.Fatal(err)
+	}
+	if kept, _ := payloads.ListPayloads(ctx, run.ID); len(kept) != 0 {
+		t.Errorf("%d payloads outlived their sync run", len(kept))
+	}
+}
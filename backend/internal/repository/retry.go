@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// isSerializationFailure reports whether err is SQLSTATE 40001, which
+// CockroachDB returns when a transaction must be retried by the client.
+func isSerializationFailure(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "40001"
+}
+
+// retrySerializable runs fn and re-runs it while it fails with a
+// serialization failure, up to the configured number of retries, with a
+// short jittered backoff between attempts.
+func (d *Database) retrySerializable(ctx context.Context, fn func() error) error {
+	backoff := 10 * time.Millisecond
+
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || !isSerializationFailure(err) || attempt >= d.serializationRetries {
+			return err
+		}
+
+		d.retriedTransactions.Add(1)
+		log.Printf("Retrying transaction after serialization failure (attempt %d): %v", attempt+1, err)
+
+		wait := backoff + time.Duration(rand.Int63n(int64(backoff)))
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// inTx runs fn in a transaction, committing on success and re-running the
+// whole transaction on serialization failures.
+func (d *Database) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return d.retrySerializable(ctx, func() error {
+		tx, err := d.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+}
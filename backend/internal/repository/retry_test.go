@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/config"
+	"github.com/lib/pq"
+)
+
+func TestRetrySerializable(t *testing.T) {
+	serialization := &pq.Error{Code: "40001", Message: "restart transaction"}
+	other := errors.New("constraint violated")
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		errs    []error
+		want    error
+		calls   int
+		retried int64
+	}{
+		{"gives up after the retries", context.Background(), []error{serialization, serialization, serialization, serialization}, serialization, 3, 2},
+		{"succeeds on a retry", context.Background(), []error{serialization, nil}, nil, 2, 1},
+		{"other errors are returned", context.Background(), []error{other, nil}, other, 1, 0},
+		{"a cancelled context stops retrying", cancelled, []error{serialization, nil}, context.Canceled, 1, 1},
+	}
+	for _, tt := range tests {
+		db := &Database{serializationRetries: 2}
+		calls := 0
+		err := db.retrySerializable(tt.ctx, func() error {
+			calls++
+			return tt.errs[calls-1]
+		})
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: error %v, want %v", tt.name, err, tt.want)
+		}
+		if calls != tt.calls {
+			t.Errorf("%s: ran %d times, want %d", tt.name, calls, tt.calls)
+		}
+		if db.retriedTransactions.Load() != tt.retried {
+			t.Errorf("%s: counted %d retries, want %d", tt.name, db.retriedTransactions.Load(), tt.retried)
+		}
+	}
+}
+
+func TestPoolSettingsFromConfig(t *testing.T) {
+	t.Setenv("DB_MAX_OPEN_CONNS", "7")
+	t.Setenv("DB_MAX_IDLE_CONNS", "3")
+	t.Setenv("DB_CONN_MAX_LIFETIME", "5m")
+	t.Setenv("DB_SERIALIZATION_RETRIES", "4")
+	options := DatabaseOptionsFromConfig(config.Load())
+	if options.MaxOpenConns != 7 || options.MaxIdleConns != 3 || options.ConnMaxLifetime != 5*time.Minute || options.SerializationRetries != 4 {
+		t.Fatalf("options %+v do not match the environment", options)
+	}
+
+	// sql.Open does not connect, so no server is needed
+	db, err := sql.Open("postgres", "postgres://localhost:1/none?sslmode=disable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	configurePool(db, DialectPostgres, options)
+	if got := db.Stats().MaxOpenConnections; got != 7 {
+		t.Errorf("MaxOpenConnections = %d, want 7", got)
+	}
+
+	sqlite, err := NewDatabase("sqlite://:memory:", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlite.Close()
+	if got := sqlite.Stats().MaxOpenConnections; got != 1 {
+		t.Errorf("SQLite MaxOpenConnections = %d, want 1", got)
+	}
+}
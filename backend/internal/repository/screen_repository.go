@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+)
+
+// ErrScreenNotFound is returned when no screen has the given name.
+var ErrScreenNotFound = errors.New("screen not found")
+
+// ScreenRepository stores saved screens and runs their SQL prefilters.
+// Like snapshots, screens only exist in SQL databases.
+type ScreenRepository struct {
+	db *Database
+}
+
+func NewScreenRepository(db *Database) *ScreenRepository {
+	return &ScreenRepository{db: db}
+}
+
+// SaveScreen creates the screen or replaces the expression and description
+// of the one with its name, keeping its creation time. It reports whether
+// the screen is new.
+func (r *ScreenRepository) SaveScreen(ctx context.Context, screen *models.Screen) (bool, error) {
+	ctx, cancel := r.db.withTimeout(ctx, OperationWrite)
+	defer cancel()
+
+	created := false
+	err := r.db.inTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, `SELECT created_at FROM screens WHERE name = $1`, screen.Name).Scan(&screen.CreatedAt)
+		if err == sql.ErrNoRows {
+			created = true
+			screen.CreatedAt = screen.UpdatedAt
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO screens (name, expression, description, created_at, updated_at)
+				VALUES ($1, $2, $3, $4, $5)
+			`, screen.Name, screen.Expression, screen.Description, screen.CreatedAt.UTC(), screen.UpdatedAt.UTC())
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			UPDATE screens SET expression = $1, description = $2, updated_at = $3
+			WHERE name = $4
+		`, screen.Expression, screen.Description, screen.UpdatedAt.UTC(), screen.Name)
+		return err
+	})
+	return created, wrapTimeout(ctx, err)
+}
+
+func (r *ScreenRepository) GetScreen(ctx context.Context, name string) (*models.Screen, error) {
+	ctx, cancel := r.db.withTimeout(ctx, OperationRead)
+	defer cancel()
+
+	screen, err := scanScreen(r.db.reader(ctx).QueryRowContext(ctx, `
+		SELECT name, expression, description, created_at, updated_at
+		FROM screens`+r.db.staleClause(ctx)+`
+		WHERE name = $1
+	`, name))
+	if err == sql.ErrNoRows {
+		return nil, ErrScreenNotFound
+	}
+	if err != nil {
+		return nil, wrapTimeout(ctx, err)
+	}
+	return screen, nil
+}
+
+// ListScreens returns saved screens by name.
+func (r *ScreenRepository) ListScreens(ctx context.Context, limit, offset int) ([]models.Screen, error) {
+	ctx, cancel := r.db.withTimeout(ctx, OperationRead)
+	defer cancel()
+
+	rows, err := r.db.reader(ctx).QueryContext(ctx, `
+		SELECT name, expression, description, created_at, updated_at
+		FROM screens`+r.db.staleClause(ctx)+`
+		ORDER BY name
+		LIMIT $1 OFFSET $2
+	`, limit, offset)
+	if err != nil {
+		return nil, wrapTimeout(ctx, err)
+	}
+	defer rows.Close()
+
+	screens := []models.Screen{}
+	for rows.Next() {
+		screen, err := scanScreen(rows)
+		if err != nil {
+			return nil, err
+		}
+		screens = append(screens, *screen)
+	}
+	return screens, wrapTimeout(ctx, rows.Err())
+}
+
+func (r *ScreenRepository) DeleteScreen(ctx context.Context, name string) error {
+	ctx, cancel := r.db.withTimeout(ctx, OperationWrite)
+	defer cancel()
+
+	result, err := r.db.DB.ExecContext(ctx, `DELETE FROM screens WHERE name = $1`, name)
+	if err != nil {
+		return wrapTimeout(ctx, err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrScreenNotFound
+	}
+	return nil
+}
+
+// ListConsensusWhere is ListConsensusAfter over the rows meeting condition,
+// a boolean SQL expression over ticker_consensus c and its latest event s
+// whose placeholders $1 to $len(args) take args. The condition must come
+// from code, never from user input: only its args may.
+func (r *ScreenRepository) ListConsensusWhere(ctx context.Context, condition string, args []interface{}, afterTicker string, limit int) ([]models.TickerConsensus, error) {
+	ctx, cancel := r.db.withTimeout(ctx, OperationSearch)
+	defer cancel()
+
+	args = append(append([]interface{}{}, args...), afterTicker, limit)
+	query := fmt.Sprintf(`
+		SELECT `+consensusColumns+`
+		FROM ticker_consensus c
+		JOIN stocks s ON s.id = c.latest_stock_id%s
+		WHERE c.ticker > $%d AND (%s)
+		ORDER BY c.ticker
+		LIMIT $%d
+	`, r.db.staleClause(ctx), len(args)-1, condition, len(args))
+
+	rows, err := r.db.reader(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, wrapTimeout(ctx, err)
+	}
+	defer rows.Close()
+
+	var consensus []models.TickerConsensus
+	for rows.Next() {
+		c, err := scanConsensus(rows)
+		if err != nil {
+			return nil, wrapTimeout(ctx, err)
+		}
+		consensus = append(consensus, *c)
+	}
+	return consensus, wrapTimeout(ctx, rows.Err())
+}
+
+func scanScreen(row rowScanner) (*models.Screen, error) {
+	var screen models.Screen
+	err := row.Scan(&screen.Name, &screen.Expression, &screen.Description, &screen.CreatedAt, &screen.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &screen, nil
+}
@@ -0,0 +1,69 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository/storetest"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/screen"
+)
+
+// Reading only the rows a screen's prefilter keeps must find every match a
+// full scan finds.
+func TestListConsensusWhereKeepsEveryMatch(t *testing.T) {
+	ctx := context.Background()
+	db := newMigratedDatabase(t)
+	stocks := repository.NewStockRepository(db)
+	storetest.Seed(t, stocks, 3000, 300)
+
+	all, err := stocks.ListConsensusAfter(ctx, "", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	screens := repository.NewScreenRepository(db)
+
+	for _, expression := range []string{
+		"rating_to in (Buy, STRONG BUY) and brokerage_count >= 3",
+		"age_days < 30 or mean_target > 150",
+		"not (brokerage contains 'brokerage 1' or rating_to = hold)",
+		"company contains t001 and not action_class = upgrade",
+		"target_currency = usd and age_days >= 10 and age_days <= 90",
+		"not rating_from != sell and brokerage_count != 2",
+	} {
+		parsed, err := screen.Parse(expression)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", expression, err)
+		}
+		condition, ok := parsed.Prefilter(now)
+		if !ok {
+			t.Fatalf("Prefilter(%q) compiled nothing", expression)
+		}
+		kept, err := screens.ListConsensusWhere(ctx, condition.SQL, condition.Args, "", 1000)
+		if err != nil {
+			t.Fatalf("ListConsensusWhere(%s): %v", condition.SQL, err)
+		}
+
+		want := matching(parsed, all, now)
+		got := matching(parsed, kept, now)
+		if len(want) == 0 || len(got) != len(want) {
+			t.Errorf("%q: %d matches after the prefilter, %d over every row", expression, len(got), len(want))
+		}
+		if len(kept) == len(all) {
+			t.Errorf("%q: the prefilter kept every row", expression)
+		}
+	}
+}
+
+func matching(parsed *screen.Screen, consensus []models.TickerConsensus, now time.Time) []string {
+	var tickers []string
+	for i := range consensus {
+		if parsed.Match(&screen.Row{Consensus: &consensus[i], Now: now}) {
+			tickers = append(tickers, consensus[i].Ticker)
+		}
+	}
+	return tickers
+}
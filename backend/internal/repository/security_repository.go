@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+)
+
+// SecurityRepository stores the securities reference table.
+type SecurityRepository struct {
+	db *Database
+}
+
+func NewSecurityRepository(db *Database) *SecurityRepository {
+	return &SecurityRepository{db: db}
+}
+
+// UpsertSecurities inserts or replaces securities by ticker in one
+// transaction.
+func (r *SecurityRepository) UpsertSecurities(ctx context.Context, securities []models.Security) error {
+	ctx, cancel := r.db.withTimeout(ctx, OperationWrite)
+	defer cancel()
+
+	now := time.Now().UTC()
+	err := r.db.inTx(ctx, func(tx *sql.Tx) error {
+		for _, security := range securities {
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO securities (ticker, company, sector, industry, updated_at)
+				VALUES ($1, $2, $3, $4, $5)
+				ON CONFLICT (ticker) DO UPDATE SET
+					company = EXCLUDED.company,
+					sector = EXCLUDED.sector,
+					industry = EXCLUDED.industry,
+					updated_at = EXCLUDED.updated_at
+			`, security.Ticker, security.Company, security.Sector, security.Industry, now)
+			if err != nil {
+				return fmt.Errorf("error storing security %s: %w", security.Ticker, err)
+			}
+		}
+		return nil
+	})
+	return wrapTimeout(ctx, err)
+}
+
+// AllSecurities returns the whole reference table ordered by ticker.
+func (r *SecurityRepository) AllSecurities(ctx context.Context) ([]models.Security, error) {
+	ctx, cancel := r.db.withTimeout(ctx, OperationRead)
+	defer cancel()
+
+	rows, err := r.db.reader(ctx).QueryContext(ctx, `
+		SELECT ticker, company, sector, industry, updated_at
+		FROM securities`+r.db.staleClause(ctx)+`
+		ORDER BY ticker
+	`)
+	if err != nil {
+		return nil, wrapTimeout(ctx, err)
+	}
+	defer rows.Close()
+
+	var securities []models.Security
+	for rows.Next() {
+		var security models.Security
+		if err := rows.Scan(&security.Ticker, &security.Company, &security.Sector, &security.Industry, &security.UpdatedAt); err != nil {
+			return nil, err
+		}
+		securities = append(securities, security)
+	}
+	return securities, wrapTimeout(ctx, rows.Err())
+}
@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+)
+
+// ErrSnapshotNotFound is returned by GetSnapshot when no snapshot has the
+// given id.
+var ErrSnapshotNotFound = errors.New("recommendation snapshot not found")
+
+// SnapshotFilter narrows ListSnapshots; empty fields match every snapshot.
+type SnapshotFilter struct {
+	Strategy string
+	Side     string
+	Source   string
+}
+
+// SnapshotRepository stores recommendation snapshots. Snapshots only exist
+// in SQL databases, like retention.
+type SnapshotRepository struct {
+	db *Database
+}
+
+func NewSnapshotRepository(db *Database) *SnapshotRepository {
+	return &SnapshotRepository{db: db}
+}
+
+// CreateSnapshot stores a snapshot with its entries in one transaction,
+// assigning its id when empty.
+func (r *SnapshotRepository) CreateSnapshot(ctx context.Context, snapshot *models.RecommendationSnapshot) error {
+	ctx, cancel := r.db.withTimeout(ctx, OperationWrite)
+	defer cancel()
+
+	if snapshot.ID == "" {
+		snapshot.ID = newID()
+	}
+	snapshot.Size = len(snapshot.Entries)
+
+	factors := make([]string, len(snapshot.Entries))
+	for i, entry := range snapshot.Entries {
+		encoded, err := json.Marshal(entry.Factors)
+		if err != nil {
+			return err
+		}
+		factors[i] = string(encoded)
+	}
+
+	err := r.db.inTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO recommendation_snapshots (id, source, side, strategy, strategy_version, profile_version, size, taken_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`, snapshot.ID, snapshot.Source, snapshot.Side, snapshot.Strategy, snapshot.StrategyVersion,
+			snapshot.ProfileVersion, snapshot.Size, snapshot.TakenAt.UTC())
+		if err != nil {
+			return err
+		}
+
+		for i, entry := range snapshot.Entries {
+			_, err := tx.ExecContext(ctx, `
+				INSERT INTO recommendation_snapshot_entries (snapshot_id, rank, ticker, stock_id, score, reason, factors)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
+			`, snapshot.ID, entry.Rank, entry.Ticker, entry.StockID, entry.Score, entry.Reason, factors[i])
+			if err != nil {
+				return fmt.Errorf("error storing snapshot entry %d (%s): %w", entry.Rank, entry.Ticker, err)
+			}
+		}
+		return nil
+	})
+	return wrapTimeout(ctx, err)
+}
+
+// ListSnapshots returns snapshots without their entries, newest first.
+func (r *SnapshotRepository) ListSnapshots(ctx context.Context, filter SnapshotFilter, limit, offset int) ([]models.RecommendationSnapshot, error) {
+	ctx, cancel := r.db.withTimeout(ctx, OperationRead)
+	defer cancel()
+
+	var conditions []string
+	var args []interface{}
+	for _, condition := range []struct {
+		column, value string
+	}{{"strategy", filter.Strategy}, {"side", filter.Side}, {"source", filter.Source}} {
+		if condition.value == "" {
+			continue
+		}
+		args = append(args, condition.value)
+		conditions = append(conditions, fmt.Sprintf("%s = $%d", condition.column, len(args)))
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, limit, offset)
+
+	query := fmt.Sprintf(`
+		SELECT id, source, side, strategy, strategy_version, profile_version, size, taken_at
+		FROM recommendation_snapshots%s
+		%s
+		ORDER BY taken_at DESC, id DESC
+		LIMIT $%d OFFSET $%d
+	`, r.db.staleClause(ctx), where, len(args)-1, len(args))
+
+	rows, err := r.db.reader(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, wrapTimeout(ctx, err)
+	}
+	defer rows.Close()
+
+	snapshots := []models.RecommendationSnapshot{}
+	for rows.Next() {
+		snapshot, err := scanSnapshot(rows)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, *snapshot)
+	}
+	return snapshots, wrapTimeout(ctx, rows.Err())
+}
+
+// GetSnapshot returns a snapshot with its entries in rank order.
+func (r *SnapshotRepository) GetSnapshot(ctx context.Context, id string) (*models.RecommendationSnapshot, error) {
+	ctx, cancel := r.db.withTimeout(ctx, OperationRead)
+	defer cancel()
+
+	db := r.db.reader(ctx)
+	snapshot, err := scanSnapshot(db.QueryRowContext(ctx, `
+		SELECT id, source, side, strategy, strategy_version, profile_version, size, taken_at
+		FROM recommendation_snapshots`+r.db.staleClause(ctx)+`
+		WHERE id = $1
+	`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrSnapshotNotFound
+	}
+	if err != nil {
+		return nil, wrapTimeout(ctx, err)
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT rank, ticker, stock_id, score, reason, factors
+		FROM recommendation_snapshot_entries`+r.db.staleClause(ctx)+`
+		WHERE snapshot_id = $1
+		ORDER BY rank
+	`, id)
+	if err != nil {
+		return nil, wrapTimeout(ctx, err)
+	}
+	defer rows.Close()
+
+	snapshot.Entries = []models.SnapshotEntry{}
+	for rows.Next() {
+		var entry models.SnapshotEntry
+		var factors string
+		if err := rows.Scan(&entry.Rank, &entry.Ticker, &entry.StockID, &entry.Score, &entry.Reason, &factors); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(factors), &entry.Factors); err != nil {
+			return nil, fmt.Errorf("error decoding factors of snapshot %s rank %d: %w", id, entry.Rank, err)
+		}
+		snapshot.Entries = append(snapshot.Entries, entry)
+	}
+	return snapshot, wrapTimeout(ctx, rows.Err())
+}
+
+func scanSnapshot(row rowScanner) (*models.RecommendationSnapshot, error) {
+	var snapshot models.RecommendationSnapshot
+	err := row.Scan(&snapshot.ID, &snapshot.Source, &snapshot.Side, &snapshot.Strategy, &snapshot.StrategyVersion,
+		&snapshot.ProfileVersion, &snapshot.Size, &snapshot.TakenAt)
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
@@ -1,12 +1,18 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
 )
 
+// ErrStockNotFound is returned by GetByID when no stock has the given id.
+var ErrStockNotFound = errors.New("stock not found")
+
 type StockRepository struct {
 	db *Database
 }
@@ -15,7 +21,33 @@ func NewStockRepository(db *Database) *StockRepository {
 	return &StockRepository{db: db}
 }
 
-func (r *StockRepository) Create(stock *models.Stock) error {
+// Create upserts the stock on (ticker, time). When an existing event's analyst
+// fields change, the previous and new values are written to stock_revisions
+// in the same transaction, tagged with syncRunID when it is not empty. The
+// ticker's consensus row is updated in that transaction too.
+func (r *StockRepository) Create(ctx context.Context, stock *models.Stock, syncRunID string) error {
+	ctx, cancel := r.db.withTimeout(ctx, OperationWrite)
+	defer cancel()
+
+	return wrapTimeout(ctx, r.db.inTx(ctx, func(tx *sql.Tx) error {
+		return r.create(ctx, tx, stock, syncRunID)
+	}))
+}
+
+func (r *StockRepository) create(ctx context.Context, tx *sql.Tx, stock *models.Stock, syncRunID string) error {
+	var existingID, existingCompany string
+	var old models.StockValues
+	err := tx.QueryRowContext(ctx, `
+		SELECT id, company, target_from, target_to, action, brokerage, rating_from, rating_to
+		FROM stocks
+		WHERE ticker = $1 AND time = $2`+r.db.Dialect.forUpdate(),
+		stock.Ticker, stock.Time.UTC(),
+	).Scan(&existingID, &existingCompany, &old.TargetFrom, &old.TargetTo, &old.Action, &old.Brokerage, &old.RatingFrom, &old.RatingTo)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	exists := err == nil
+
 	query := `
 		INSERT INTO stocks (id, ticker, company, target_from, target_to, action, brokerage, rating_from, rating_to, time, last_updated)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
@@ -29,73 +61,259 @@ func (r *StockRepository) Create(stock *models.Stock) error {
 			last_updated = EXCLUDED.last_updated
 	`
 
-	_, err := r.db.DB.Exec(query,
+	_, err = tx.ExecContext(ctx, query,
 		stock.ID, stock.Ticker, stock.Company, stock.TargetFrom, stock.TargetTo,
 		stock.Action, stock.Brokerage, stock.RatingFrom, stock.RatingTo,
-		stock.Time, stock.LastUpdated)
+		stock.Time.UTC(), stock.LastUpdated.UTC())
+	if err != nil {
+		return err
+	}
 
-	return err
+	if exists && old != stock.Values() {
+		revision := models.StockRevision{
+			ID:        newID(),
+			StockID:   existingID,
+			SyncRunID: syncRunID,
+			Old:       old,
+			New:       stock.Values(),
+			ChangedAt: stock.LastUpdated.UTC(),
+		}
+		if err := r.insertRevision(ctx, tx, &revision); err != nil {
+			return err
+		}
+	}
+
+	// The stored row keeps its original id and company on conflict
+	stored := *stock
+	if exists {
+		stored.ID = existingID
+		stored.Company = existingCompany
+	}
+	return r.updateConsensus(ctx, tx, &stored)
 }
 
-func (r *StockRepository) GetAll(limit, offset int) ([]models.Stock, error) {
+func (r *StockRepository) insertRevision(ctx context.Context, tx *sql.Tx, revision *models.StockRevision) error {
+	query := `
+		INSERT INTO stock_revisions (
+			id, stock_id, sync_run_id,
+			old_target_from, old_target_to, old_action, old_brokerage, old_rating_from, old_rating_to,
+			new_target_from, new_target_to, new_action, new_brokerage, new_rating_from, new_rating_to,
+			changed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+	`
+
+	_, err := tx.ExecContext(ctx, query,
+		revision.ID, revision.StockID, nullString(revision.SyncRunID),
+		revision.Old.TargetFrom, revision.Old.TargetTo, revision.Old.Action,
+		revision.Old.Brokerage, revision.Old.RatingFrom, revision.Old.RatingTo,
+		revision.New.TargetFrom, revision.New.TargetTo, revision.New.Action,
+		revision.New.Brokerage, revision.New.RatingFrom, revision.New.RatingTo,
+		revision.ChangedAt)
+	if err != nil {
+		return fmt.Errorf("error recording revision of stock %s: %w", revision.StockID, err)
+	}
+	return nil
+}
+
+// GetRevisions returns the recorded changes of a stock, newest first.
+func (r *StockRepository) GetRevisions(ctx context.Context, stockID string) ([]models.StockRevision, error) {
+	ctx, cancel := r.db.withTimeout(ctx, OperationRead)
+	defer cancel()
+
+	revisions, err := r.getRevisions(ctx, stockID)
+	return revisions, wrapTimeout(ctx, err)
+}
+
+func (r *StockRepository) getRevisions(ctx context.Context, stockID string) ([]models.StockRevision, error) {
+	query := `
+		SELECT ` + revisionColumns + `
+		FROM stock_revisions` + r.db.staleClause(ctx) + `
+		WHERE stock_id = $1
+		ORDER BY changed_at DESC, id
+	`
+
+	rows, err := r.db.reader(ctx).QueryContext(ctx, query, stockID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRevisions(rows)
+}
+
+// ListRevisionsAfter pages through every revision by (changed_at, id).
+func (r *StockRepository) ListRevisionsAfter(ctx context.Context, afterTime time.Time, afterID string, limit int) ([]models.StockRevision, error) {
+	ctx, cancel := r.db.withTimeout(ctx, OperationRead)
+	defer cancel()
+
+	// Seeks straight to the cursor through idx_stock_revisions_changed_at_id
+	query := `
+		SELECT ` + revisionColumns + `
+		FROM stock_revisions` + r.db.staleClause(ctx) + `
+		WHERE (changed_at, id) > ($1, $2)
+		ORDER BY changed_at, id
+		LIMIT $3
+	`
+
+	rows, err := r.db.reader(ctx).QueryContext(ctx, query, afterTime.UTC(), afterID, limit)
+	if err != nil {
+		return nil, wrapTimeout(ctx, err)
+	}
+	defer rows.Close()
+
+	revisions, err := scanRevisions(rows)
+	return revisions, wrapTimeout(ctx, err)
+}
+
+const revisionColumns = `id, stock_id, sync_run_id,
+			old_target_from, old_target_to, old_action, old_brokerage, old_rating_from, old_rating_to,
+			new_target_from, new_target_to, new_action, new_brokerage, new_rating_from, new_rating_to,
+			changed_at`
+
+func scanRevisions(rows *sql.Rows) ([]models.StockRevision, error) {
+	var revisions []models.StockRevision
+	for rows.Next() {
+		var revision models.StockRevision
+		var syncRunID sql.NullString
+		err := rows.Scan(
+			&revision.ID, &revision.StockID, &syncRunID,
+			&revision.Old.TargetFrom, &revision.Old.TargetTo, &revision.Old.Action,
+			&revision.Old.Brokerage, &revision.Old.RatingFrom, &revision.Old.RatingTo,
+			&revision.New.TargetFrom, &revision.New.TargetTo, &revision.New.Action,
+			&revision.New.Brokerage, &revision.New.RatingFrom, &revision.New.RatingTo,
+			&revision.ChangedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		revision.SyncRunID = syncRunID.String
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, rows.Err()
+}
+
+func (r *StockRepository) GetAll(ctx context.Context, limit, offset int) ([]models.Stock, error) {
+	ctx, cancel := r.db.withTimeout(ctx, OperationRead)
+	defer cancel()
+
 	query := `
 		SELECT id, ticker, company, target_from, target_to, action, brokerage, rating_from, rating_to, time, last_updated, created_at
-		FROM stocks
-		ORDER BY time DESC
+		FROM stocks` + r.db.staleClause(ctx) + `
+		ORDER BY time DESC, id
 		LIMIT $1 OFFSET $2
 	`
 
-	rows, err := r.db.DB.Query(query, limit, offset)
+	rows, err := r.db.reader(ctx).QueryContext(ctx, query, limit, offset)
 	if err != nil {
-		return nil, err
+		return nil, wrapTimeout(ctx, err)
 	}
 	defer rows.Close()
 
-	return r.scanStocks(rows)
+	stocks, err := r.scanStocks(rows)
+	return stocks, wrapTimeout(ctx, err)
 }
 
-func (r *StockRepository) GetByID(id string) (*models.Stock, error) {
+func (r *StockRepository) ListStocksAfter(ctx context.Context, afterTime time.Time, afterID string, limit int) ([]models.Stock, error) {
+	ctx, cancel := r.db.withTimeout(ctx, OperationRead)
+	defer cancel()
+
+	// Seeks straight to the cursor through idx_stocks_time_id
 	query := `
 		SELECT id, ticker, company, target_from, target_to, action, brokerage, rating_from, rating_to, time, last_updated, created_at
-		FROM stocks
+		FROM stocks` + r.db.staleClause(ctx) + `
+		WHERE (time, id) > ($1, $2)
+		ORDER BY time, id
+		LIMIT $3
+	`
+
+	rows, err := r.db.reader(ctx).QueryContext(ctx, query, afterTime.UTC(), afterID, limit)
+	if err != nil {
+		return nil, wrapTimeout(ctx, err)
+	}
+	defer rows.Close()
+
+	stocks, err := r.scanStocks(rows)
+	return stocks, wrapTimeout(ctx, err)
+}
+
+func (r *StockRepository) ListTickerEvents(ctx context.Context, ticker string) ([]models.Stock, error) {
+	ctx, cancel := r.db.withTimeout(ctx, OperationRead)
+	defer cancel()
+
+	query := `
+		SELECT id, ticker, company, target_from, target_to, action, brokerage, rating_from, rating_to, time, last_updated, created_at
+		FROM stocks` + r.db.staleClause(ctx) + `
+		WHERE ticker = $1
+		ORDER BY time, id
+	`
+
+	rows, err := r.db.reader(ctx).QueryContext(ctx, query, ticker)
+	if err != nil {
+		return nil, wrapTimeout(ctx, err)
+	}
+	defer rows.Close()
+
+	stocks, err := r.scanStocks(rows)
+	return stocks, wrapTimeout(ctx, err)
+}
+
+func (r *StockRepository) GetByID(ctx context.Context, id string) (*models.Stock, error) {
+	ctx, cancel := r.db.withTimeout(ctx, OperationRead)
+	defer cancel()
+
+	query := `
+		SELECT id, ticker, company, target_from, target_to, action, brokerage, rating_from, rating_to, time, last_updated, created_at
+		FROM stocks` + r.db.staleClause(ctx) + `
 		WHERE id = $1
 	`
 
 	var stock models.Stock
-	err := r.db.DB.QueryRow(query, id).Scan(
+	err := r.db.reader(ctx).QueryRowContext(ctx, query, id).Scan(
 		&stock.ID, &stock.Ticker, &stock.Company, &stock.TargetFrom, &stock.TargetTo,
 		&stock.Action, &stock.Brokerage, &stock.RatingFrom, &stock.RatingTo,
 		&stock.Time, &stock.LastUpdated, &stock.CreatedAt,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("stock not found")
+		return nil, ErrStockNotFound
+	}
+	if err != nil {
+		return nil, wrapTimeout(ctx, err)
 	}
 
-	return &stock, err
+	return &stock, nil
 }
 
-func (r *StockRepository) Search(query string) ([]models.Stock, error) {
+func (r *StockRepository) Search(ctx context.Context, query string) ([]models.Stock, error) {
+	ctx, cancel := r.db.withTimeout(ctx, OperationSearch)
+	defer cancel()
+
+	like := r.db.Dialect.caseInsensitiveLike()
 	searchQuery := `
 		SELECT id, ticker, company, target_from, target_to, action, brokerage, rating_from, rating_to, time, last_updated, created_at
-		FROM stocks
-		WHERE ticker ILIKE $1 OR company ILIKE $1
+		FROM stocks` + r.db.staleClause(ctx) + `
+		WHERE ticker ` + like + ` $1 OR company ` + like + ` $1
 		ORDER BY time DESC
 	`
 
-	rows, err := r.db.DB.Query(searchQuery, "%"+query+"%")
+	rows, err := r.db.reader(ctx).QueryContext(ctx, searchQuery, "%"+query+"%")
 	if err != nil {
-		return nil, err
+		return nil, wrapTimeout(ctx, err)
 	}
 	defer rows.Close()
 
-	return r.scanStocks(rows)
+	stocks, err := r.scanStocks(rows)
+	return stocks, wrapTimeout(ctx, err)
 }
 
-func (r *StockRepository) Count() (int, error) {
+func (r *StockRepository) Count(ctx context.Context) (int, error) {
+	ctx, cancel := r.db.withTimeout(ctx, OperationRead)
+	defer cancel()
+
 	var count int
-	err := r.db.DB.QueryRow("SELECT COUNT(*) FROM stocks").Scan(&count)
-	return count, err
+	err := r.db.reader(ctx).QueryRowContext(ctx, "SELECT COUNT(*) FROM stocks"+r.db.staleClause(ctx)).Scan(&count)
+	return count, wrapTimeout(ctx, err)
 }
 
 func (r *StockRepository) scanStocks(rows *sql.Rows) ([]models.Stock, error) {
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+)
+
+// StockStore is the persistence contract for analyst events. StockRepository
+// implements it on top of SQL and MemoryStockStore keeps everything in process.
+// Every method honors ctx cancellation and deadlines.
+type StockStore interface {
+	// Create inserts the stock or, when an event with the same ticker and time
+	// already exists, updates its analyst fields in place. A real change to
+	// those fields is recorded as a revision attributed to syncRunID, and the
+	// stored event is folded into the ticker's consensus.
+	Create(ctx context.Context, stock *models.Stock, syncRunID string) error
+	// GetAll returns stocks ordered by event time, newest first, then by id.
+	GetAll(ctx context.Context, limit, offset int) ([]models.Stock, error)
+	// ListStocksAfter returns up to limit stocks ordered by event time, then
+	// id, oldest first, starting after the event at afterTime with afterID.
+	// The zero time and an empty id start from the first event. Unlike
+	// GetAll paging it costs the same on every page, so whole-table scans
+	// use it.
+	ListStocksAfter(ctx context.Context, afterTime time.Time, afterID string, limit int) ([]models.Stock, error)
+	// ListTickerEvents returns every event of one ticker ordered by event
+	// time, then id, oldest first.
+	ListTickerEvents(ctx context.Context, ticker string) ([]models.Stock, error)
+	GetByID(ctx context.Context, id string) (*models.Stock, error)
+	// Search matches ticker or company case-insensitively, newest first.
+	Search(ctx context.Context, query string) ([]models.Stock, error)
+	Count(ctx context.Context) (int, error)
+
+	// ListConsensus returns per-ticker consensus with the latest event loaded,
+	// most recently active tickers first.
+	ListConsensus(ctx context.Context, limit, offset int) ([]models.TickerConsensus, error)
+	// ListConsensusAfter returns up to limit tickers in ticker order after
+	// afterTicker; an empty afterTicker starts from the first one.
+	ListConsensusAfter(ctx context.Context, afterTicker string, limit int) ([]models.TickerConsensus, error)
+	GetConsensus(ctx context.Context, ticker string) (*models.TickerConsensus, error)
+	CountConsensus(ctx context.Context) (int, error)
+
+	// GetRevisions returns the changes recorded for a stock, newest first.
+	GetRevisions(ctx context.Context, stockID string) ([]models.StockRevision, error)
+	// ListRevisionsAfter returns up to limit revisions of every stock ordered
+	// by change time, then id, oldest first, starting after the revision at
+	// afterTime with afterID, like ListStocksAfter.
+	ListRevisionsAfter(ctx context.Context, afterTime time.Time, afterID string, limit int) ([]models.StockRevision, error)
+
+	CreateSyncRun(ctx context.Context, run *models.SyncRun) error
+	UpdateSyncRun(ctx context.Context, run *models.SyncRun) error
+	// SavePage stores one page of a sync run together with the run's counters
+	// and checkpoint (nextPage), honoring run.WriteMode: an atomic page is
+	// stored all-or-nothing and the failing row is returned as the error, a
+	// best-effort page records failing rows in run.FailedRows. run is updated
+	// only when the page was saved.
+	SavePage(ctx context.Context, run *models.SyncRun, stocks []models.Stock, nextPage string) error
+	GetSyncRun(ctx context.Context, id string) (*models.SyncRun, error)
+}
+
+var (
+	_ StockStore = (*StockRepository)(nil)
+	_ StockStore = (*MemoryStockStore)(nil)
+)
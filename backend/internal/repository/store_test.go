@@ -0,0 +1,70 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository/storetest"
+)
+
+// newMigratedDatabase is newTestDatabase with every migration applied.
+func newMigratedDatabase(t testing.TB) *repository.Database {
+	t.Helper()
+	db := newTestDatabase(t)
+	migrator, err := repository.NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrating: %v", err)
+	}
+	return db
+}
+
+func TestMemoryStockStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) repository.StockStore {
+		return repository.NewMemoryStockStore()
+	})
+}
+
+func TestSQLiteStockRepository(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) repository.StockStore {
+		return repository.NewStockRepository(newMigratedDatabase(t))
+	})
+}
+
+// BenchmarkListStocksAfter scans every event by key, a page at a time, the
+// way credibility and backtests read them.
+func BenchmarkListStocksAfter(b *testing.B) {
+	const events, pageSize = 50000, 1000
+	stores := []struct {
+		name  string
+		store repository.StockStore
+	}{
+		{"Memory", repository.NewMemoryStockStore()},
+		{"SQLite", repository.NewStockRepository(newMigratedDatabase(b))},
+	}
+	for _, s := range stores {
+		storetest.Seed(b, s.store, events, events/15)
+		b.Run(s.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				var afterTime time.Time
+				var afterID string
+				for {
+					page, err := s.store.ListStocksAfter(context.Background(), afterTime, afterID, pageSize)
+					if err != nil {
+						b.Fatal(err)
+					}
+					if len(page) < pageSize {
+						break
+					}
+					last := page[len(page)-1]
+					afterTime, afterID = last.Time, last.ID
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,680 @@
+// Package storetest is a conformance suite for repository.StockStore
+// implementations. Call Run from a test with a constructor that returns an
+// empty store:
+//
+//	func TestMemoryStore(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) repository.StockStore {
+//			return repository.NewMemoryStockStore()
+//		})
+//	}
+//
+// SQL-backed stores can use a fresh sqlite://:memory: database, migrated up,
+// per subtest, or truncate their tables in the constructor.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository"
+)
+
+// Factory returns a new, empty store for a single subtest.
+type Factory func(t *testing.T) repository.StockStore
+
+var (
+	baseTime = time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	ctx      = context.Background()
+)
+
+// Run executes every conformance check against stores built by newStore.
+func Run(t *testing.T, newStore Factory) {
+	t.Run("CreateAndGetByID", func(t *testing.T) { testCreateAndGetByID(t, newStore(t)) })
+	t.Run("GetByIDNotFound", func(t *testing.T) { testGetByIDNotFound(t, newStore(t)) })
+	t.Run("UpsertOnTickerAndTime", func(t *testing.T) { testUpsert(t, newStore(t)) })
+	t.Run("GetAllOrderAndPaging", func(t *testing.T) { testGetAllOrderAndPaging(t, newStore(t)) })
+	t.Run("ListStocksAfterKeyset", func(t *testing.T) { testListStocksAfter(t, newStore(t)) })
+	t.Run("ListTickerEventsOldestFirst", func(t *testing.T) { testListTickerEvents(t, newStore(t)) })
+	t.Run("ListConsensusAfterKeyset", func(t *testing.T) { testListConsensusAfter(t, newStore(t)) })
+	t.Run("SearchTickerAndCompany", func(t *testing.T) { testSearch(t, newStore(t)) })
+	t.Run("Count", func(t *testing.T) { testCount(t, newStore(t)) })
+	t.Run("RevisionsOnChange", func(t *testing.T) { testRevisions(t, newStore(t)) })
+	t.Run("ListRevisionsAfterKeyset", func(t *testing.T) { testListRevisionsAfter(t, newStore(t)) })
+	t.Run("SyncRunRoundTrip", func(t *testing.T) { testSyncRuns(t, newStore(t)) })
+	t.Run("ConsensusMaintainedOnCreate", func(t *testing.T) { testConsensus(t, newStore(t)) })
+	t.Run("SavePageAtomic", func(t *testing.T) { testSavePageAtomic(t, newStore(t)) })
+	t.Run("SavePageBestEffort", func(t *testing.T) { testSavePageBestEffort(t, newStore(t)) })
+}
+
+// NewStock builds a stock event with a deterministic id for ticker and offset.
+func NewStock(ticker, company string, offset time.Duration) models.Stock {
+	eventTime := baseTime.Add(offset)
+	return models.Stock{
+		ID:          ticker + "-" + eventTime.Format("20060102150405"),
+		Ticker:      ticker,
+		Company:     company,
+		TargetFrom:  "$10.00",
+		TargetTo:    "$12.00",
+		Action:      "target raised by",
+		Brokerage:   "Example Securities",
+		RatingFrom:  "Hold",
+		RatingTo:    "Buy",
+		Time:        eventTime,
+		LastUpdated: baseTime,
+	}
+}
+
+// seedPageSize is how many synthetic events Seed stores per page.
+const seedPageSize = 1000
+
+var (
+	seedRatings = []string{"Strong Sell", "Sell", "Hold", "Buy", "Strong Buy"}
+	seedActions = []string{"upgraded by", "downgraded by", "target raised by", "target lowered by", "reiterated by", "initiated by"}
+)
+
+// Seed stores events synthetic analyst events, spread over tickers tickers
+// and the two years before baseTime, in pages the way a sync stores them. Every call
+// stores the same events, so benchmarks compare like with like.
+func Seed(tb testing.TB, store repository.StockStore, events, tickers int) {
+	tb.Helper()
+	random := rand.New(rand.NewSource(1))
+	start := baseTime.AddDate(-2, 0, 0)
+	span := int64(2 * 365 * 24 * time.Hour / time.Second)
+
+	run := &models.SyncRun{
+		Status:    models.SyncRunRunning,
+		WriteMode: models.SyncWriteAtomic,
+		MaxPages:  events/seedPageSize + 1,
+		StartedAt: baseTime,
+	}
+	if err := store.CreateSyncRun(ctx, run); err != nil {
+		tb.Fatalf("CreateSyncRun: %v", err)
+	}
+
+	for stored := 0; stored < events; {
+		page := make([]models.Stock, 0, seedPageSize)
+		for ; len(page) < seedPageSize && stored < events; stored++ {
+			ticker := fmt.Sprintf("T%05d", random.Intn(tickers))
+			eventTime := start.Add(time.Duration(random.Int63n(span)) * time.Second)
+			from := 20 + random.Float64()*200
+			to := from * (0.7 + random.Float64()*0.6)
+			page = append(page, models.Stock{
+				ID:          fmt.Sprintf("%s-%d", ticker, eventTime.Unix()),
+				Ticker:      ticker,
+				Company:     "Company " + ticker,
+				TargetFrom:  fmt.Sprintf("$%.2f", from),
+				TargetTo:    fmt.Sprintf("$%.2f", to),
+				Action:      seedActions[random.Intn(len(seedActions))],
+				Brokerage:   fmt.Sprintf("Brokerage %02d", random.Intn(40)),
+				RatingFrom:  seedRatings[random.Intn(len(seedRatings))],
+				RatingTo:    seedRatings[random.Intn(len(seedRatings))],
+				Time:        eventTime,
+				LastUpdated: baseTime,
+			})
+		}
+		if err := store.SavePage(ctx, run, page, ""); err != nil {
+			tb.Fatalf("SavePage: %v", err)
+		}
+	}
+}
+
+func mustCreate(t *testing.T, store repository.StockStore, stocks ...models.Stock) {
+	t.Helper()
+	for i := range stocks {
+		if err := store.Create(ctx, &stocks[i], ""); err != nil {
+			t.Fatalf("Create(%s): %v", stocks[i].ID, err)
+		}
+	}
+}
+
+func testCreateAndGetByID(t *testing.T, store repository.StockStore) {
+	stock := NewStock("AAPL", "Apple Inc.", 0)
+	mustCreate(t, store, stock)
+
+	got, err := store.GetByID(ctx, stock.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+
+	if got.Ticker != stock.Ticker || got.Company != stock.Company || got.TargetTo != stock.TargetTo ||
+		got.RatingTo != stock.RatingTo || got.Brokerage != stock.Brokerage || !got.Time.Equal(stock.Time) {
+		t.Errorf("GetByID returned %+v, want %+v", got, stock)
+	}
+	if got.CreatedAt.IsZero() {
+		t.Errorf("CreatedAt was not set")
+	}
+}
+
+func testGetByIDNotFound(t *testing.T, store repository.StockStore) {
+	_, err := store.GetByID(ctx, "missing")
+	if !errors.Is(err, repository.ErrStockNotFound) {
+		t.Fatalf("GetByID(missing) error = %v, want ErrStockNotFound", err)
+	}
+}
+
+func testUpsert(t *testing.T, store repository.StockStore) {
+	original := NewStock("MSFT", "Microsoft", 0)
+	mustCreate(t, store, original)
+
+	revised := original
+	revised.ID = "different-id"
+	revised.Company = "Ignored On Update"
+	revised.TargetTo = "$15.00"
+	revised.RatingTo = "Strong Buy"
+	revised.Brokerage = "Other Brokerage"
+	revised.LastUpdated = baseTime.Add(time.Hour)
+	mustCreate(t, store, revised)
+
+	if count, _ := store.Count(ctx); count != 1 {
+		t.Fatalf("Count after upsert = %d, want 1", count)
+	}
+
+	got, err := store.GetByID(ctx, original.ID)
+	if err != nil {
+		t.Fatalf("GetByID after upsert: %v", err)
+	}
+	if got.TargetTo != "$15.00" || got.RatingTo != "Strong Buy" || got.Brokerage != "Other Brokerage" {
+		t.Errorf("upsert did not update analyst fields: %+v", got)
+	}
+	if got.Company != original.Company {
+		t.Errorf("upsert changed company to %q, want %q", got.Company, original.Company)
+	}
+	if !got.LastUpdated.Equal(revised.LastUpdated) {
+		t.Errorf("LastUpdated = %v, want %v", got.LastUpdated, revised.LastUpdated)
+	}
+}
+
+func testGetAllOrderAndPaging(t *testing.T, store repository.StockStore) {
+	mustCreate(t, store,
+		NewStock("AAA", "Alpha", 1*time.Hour),
+		NewStock("CCC", "Gamma", 3*time.Hour),
+		NewStock("BBB", "Beta", 2*time.Hour),
+		NewStock("DDD", "Delta", 4*time.Hour),
+	)
+
+	all, err := store.GetAll(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	want := []string{"DDD", "CCC", "BBB", "AAA"}
+	if tickers := tickersOf(all); !equal(tickers, want) {
+		t.Fatalf("GetAll order = %v, want %v", tickers, want)
+	}
+
+	page, err := store.GetAll(ctx, 2, 1)
+	if err != nil {
+		t.Fatalf("GetAll(2, 1): %v", err)
+	}
+	if tickers := tickersOf(page); !equal(tickers, []string{"CCC", "BBB"}) {
+		t.Errorf("GetAll(2, 1) = %v, want [CCC BBB]", tickers)
+	}
+
+	past, err := store.GetAll(ctx, 10, 10)
+	if err != nil {
+		t.Fatalf("GetAll past end: %v", err)
+	}
+	if len(past) != 0 {
+		t.Errorf("GetAll past end returned %d stocks", len(past))
+	}
+}
+
+func testListStocksAfter(t *testing.T, store repository.StockStore) {
+	// BBB and AAB share a time, so the id breaks the tie
+	mustCreate(t, store,
+		NewStock("CCC", "Gamma", 3*time.Hour),
+		NewStock("AAA", "Alpha", 1*time.Hour),
+		NewStock("BBB", "Beta", 2*time.Hour),
+		NewStock("AAB", "Alpha B", 2*time.Hour),
+	)
+
+	var seen []string
+	var afterTime time.Time
+	afterID := ""
+	for pages := 0; pages < 10; pages++ {
+		// Pages of 2 end on AAB, which BBB shares a time with
+		page, err := store.ListStocksAfter(ctx, afterTime, afterID, 2)
+		if err != nil {
+			t.Fatalf("ListStocksAfter: %v", err)
+		}
+		seen = append(seen, tickersOf(page)...)
+		if len(page) < 2 {
+			break
+		}
+		last := page[len(page)-1]
+		afterTime, afterID = last.Time, last.ID
+	}
+
+	want := []string{"AAA", "AAB", "BBB", "CCC"}
+	if !equal(seen, want) {
+		t.Errorf("ListStocksAfter pages = %v, want %v", seen, want)
+	}
+}
+
+func testListTickerEvents(t *testing.T, store repository.StockStore) {
+	mustCreate(t, store,
+		NewStock("AAA", "Alpha", 3*time.Hour),
+		NewStock("BBB", "Beta", 2*time.Hour),
+		NewStock("AAA", "Alpha", 1*time.Hour),
+	)
+
+	events, err := store.ListTickerEvents(ctx, "AAA")
+	if err != nil {
+		t.Fatalf("ListTickerEvents: %v", err)
+	}
+	if len(events) != 2 || !events[0].Time.Before(events[1].Time) {
+		t.Errorf("ListTickerEvents(AAA) = %v, want its 2 events oldest first", events)
+	}
+	for _, event := range events {
+		if event.Ticker != "AAA" {
+			t.Errorf("ListTickerEvents(AAA) returned %s", event.Ticker)
+		}
+	}
+}
+
+func testListConsensusAfter(t *testing.T, store repository.StockStore) {
+	mustCreate(t, store,
+		NewStock("CCC", "Gamma", 3*time.Hour),
+		NewStock("AAA", "Alpha", 1*time.Hour),
+		NewStock("BBB", "Beta", 2*time.Hour),
+	)
+
+	first, err := store.ListConsensusAfter(ctx, "", 2)
+	if err != nil {
+		t.Fatalf("ListConsensusAfter: %v", err)
+	}
+	rest, err := store.ListConsensusAfter(ctx, "BBB", 2)
+	if err != nil {
+		t.Fatalf("ListConsensusAfter(BBB): %v", err)
+	}
+
+	var got []string
+	for _, consensus := range append(first, rest...) {
+		if consensus.Latest == nil {
+			t.Errorf("ListConsensusAfter(%s) has no latest event", consensus.Ticker)
+		}
+		got = append(got, consensus.Ticker)
+	}
+	if want := []string{"AAA", "BBB", "CCC"}; !equal(got, want) {
+		t.Errorf("ListConsensusAfter pages = %v, want %v", got, want)
+	}
+}
+
+func testSearch(t *testing.T, store repository.StockStore) {
+	mustCreate(t, store,
+		NewStock("NVDA", "NVIDIA Corporation", 1*time.Hour),
+		NewStock("AMD", "Advanced Micro Devices", 2*time.Hour),
+		NewStock("NVDA", "NVIDIA Corporation", 3*time.Hour),
+	)
+
+	byTicker, err := store.Search(ctx, "nvd")
+	if err != nil {
+		t.Fatalf("Search(nvd): %v", err)
+	}
+	if len(byTicker) != 2 || !byTicker[0].Time.After(byTicker[1].Time) {
+		t.Errorf("Search(nvd) = %v, want both NVDA events newest first", tickersOf(byTicker))
+	}
+
+	byCompany, err := store.Search(ctx, "MICRO")
+	if err != nil {
+		t.Fatalf("Search(MICRO): %v", err)
+	}
+	if tickers := tickersOf(byCompany); !equal(tickers, []string{"AMD"}) {
+		t.Errorf("Search(MICRO) = %v, want [AMD]", tickers)
+	}
+
+	none, err := store.Search(ctx, "zzz")
+	if err != nil {
+		t.Fatalf("Search(zzz): %v", err)
+	}
+	if len(none) != 0 {
+		t.Errorf("Search(zzz) returned %d stocks", len(none))
+	}
+}
+
+func testCount(t *testing.T, store repository.StockStore) {
+	if count, err := store.Count(ctx); err != nil || count != 0 {
+		t.Fatalf("Count on empty store = %d, %v", count, err)
+	}
+
+	mustCreate(t, store,
+		NewStock("AAA", "Alpha", 0),
+		NewStock("AAA", "Alpha", time.Hour),
+		NewStock("BBB", "Beta", 0),
+	)
+
+	if count, err := store.Count(ctx); err != nil || count != 3 {
+		t.Errorf("Count = %d, %v; want 3", count, err)
+	}
+}
+
+func testRevisions(t *testing.T, store repository.StockStore) {
+	original := NewStock("TSLA", "Tesla", 0)
+	mustCreate(t, store, original)
+
+	// Re-syncing identical values is not a revision
+	unchanged := original
+	unchanged.LastUpdated = baseTime.Add(time.Minute)
+	if err := store.Create(ctx, &unchanged, "run-1"); err != nil {
+		t.Fatalf("Create unchanged: %v", err)
+	}
+
+	revised := original
+	revised.TargetTo = "$20.00"
+	revised.RatingTo = "Strong Buy"
+	revised.LastUpdated = baseTime.Add(time.Hour)
+	if err := store.Create(ctx, &revised, "run-2"); err != nil {
+		t.Fatalf("Create revised: %v", err)
+	}
+
+	revisions, err := store.GetRevisions(ctx, original.ID)
+	if err != nil {
+		t.Fatalf("GetRevisions: %v", err)
+	}
+	if len(revisions) != 1 {
+		t.Fatalf("GetRevisions returned %d revisions, want 1", len(revisions))
+	}
+
+	revision := revisions[0]
+	if revision.StockID != original.ID || revision.SyncRunID != "run-2" {
+		t.Errorf("revision ids = (%q, %q), want (%q, run-2)", revision.StockID, revision.SyncRunID, original.ID)
+	}
+	if revision.Old != original.Values() || revision.New != revised.Values() {
+		t.Errorf("revision values = %+v -> %+v", revision.Old, revision.New)
+	}
+	if !revision.ChangedAt.Equal(revised.LastUpdated) {
+		t.Errorf("ChangedAt = %v, want %v", revision.ChangedAt, revised.LastUpdated)
+	}
+
+	if none, err := store.GetRevisions(ctx, "missing"); err != nil || len(none) != 0 {
+		t.Errorf("GetRevisions(missing) = %v, %v", none, err)
+	}
+}
+
+func testListRevisionsAfter(t *testing.T, store repository.StockStore) {
+	mustCreate(t, store, NewStock("AAA", "Alpha", 0), NewStock("BBB", "Beta", 0))
+
+	// Revisions are recorded out of change order, and two share a time
+	revise := func(ticker, target string, changedAt time.Duration) {
+		t.Helper()
+		revised := NewStock(ticker, "", 0)
+		revised.TargetTo = target
+		revised.LastUpdated = baseTime.Add(changedAt)
+		if err := store.Create(ctx, &revised, ""); err != nil {
+			t.Fatalf("Create revised %s: %v", ticker, err)
+		}
+	}
+	revise("AAA", "$20.00", 3*time.Hour)
+	revise("BBB", "$30.00", time.Hour)
+	revise("AAA", "$21.00", 5*time.Hour)
+	revise("BBB", "$31.00", 3*time.Hour)
+
+	// Pages of two split the revisions sharing a time
+	var seen []string
+	var afterTime time.Time
+	afterID := ""
+	for pages := 0; pages < 10; pages++ {
+		page, err := store.ListRevisionsAfter(ctx, afterTime, afterID, 2)
+		if err != nil {
+			t.Fatalf("ListRevisionsAfter: %v", err)
+		}
+		for i, revision := range page {
+			seen = append(seen, revision.New.TargetTo)
+			if i > 0 && revision.ChangedAt.Equal(page[i-1].ChangedAt) && revision.ID < page[i-1].ID {
+				t.Errorf("revisions at %v are not in id order", revision.ChangedAt)
+			}
+		}
+		if len(page) < 2 {
+			break
+		}
+		last := page[len(page)-1]
+		afterTime, afterID = last.ChangedAt, last.ID
+	}
+
+	// The two revisions at 3h may come in either order, by their ids
+	if len(seen) != 4 || seen[0] != "$30.00" || seen[3] != "$21.00" {
+		t.Errorf("ListRevisionsAfter pages = %v, want $30.00, the two at 3h, then $21.00", seen)
+	}
+}
+
+func testSyncRuns(t *testing.T, store repository.StockStore) {
+	run := models.SyncRun{Status: models.SyncRunRunning, WriteMode: models.SyncWriteBestEffort, MaxPages: 5, StartedAt: baseTime}
+	if err := store.CreateSyncRun(ctx, &run); err != nil {
+		t.Fatalf("CreateSyncRun: %v", err)
+	}
+	if run.ID == "" {
+		t.Fatalf("CreateSyncRun did not assign an id")
+	}
+
+	finishedAt := baseTime.Add(time.Minute)
+	run.Status = models.SyncRunFailed
+	run.Pages, run.Fetched, run.Stored, run.Failed = 2, 20, 19, 1
+	run.Error = "provider unavailable"
+	run.NextPage = "cursor-3"
+	run.FailedRows = []models.FailedRow{{StockID: "x", Ticker: "X", Time: baseTime, Page: 2, Error: "bad row"}}
+	run.FinishedAt = &finishedAt
+	if err := store.UpdateSyncRun(ctx, &run); err != nil {
+		t.Fatalf("UpdateSyncRun: %v", err)
+	}
+
+	got, err := store.GetSyncRun(ctx, run.ID)
+	if err != nil {
+		t.Fatalf("GetSyncRun: %v", err)
+	}
+	if got.Status != run.Status || got.MaxPages != 5 || got.Pages != 2 || got.Fetched != 20 ||
+		got.Stored != 19 || got.Failed != 1 || got.Error != run.Error ||
+		got.FinishedAt == nil || !got.FinishedAt.Equal(finishedAt) || !got.StartedAt.Equal(baseTime) ||
+		got.WriteMode != models.SyncWriteBestEffort || got.NextPage != "cursor-3" ||
+		len(got.FailedRows) != 1 || got.FailedRows[0].Error != "bad row" || !got.FailedRows[0].Time.Equal(baseTime) {
+		t.Errorf("GetSyncRun = %+v, want %+v", got, run)
+	}
+
+	if _, err := store.GetSyncRun(ctx, "missing"); !errors.Is(err, repository.ErrSyncRunNotFound) {
+		t.Errorf("GetSyncRun(missing) error = %v, want ErrSyncRunNotFound", err)
+	}
+	missing := models.SyncRun{ID: "missing"}
+	if err := store.UpdateSyncRun(ctx, &missing); !errors.Is(err, repository.ErrSyncRunNotFound) {
+		t.Errorf("UpdateSyncRun(missing) error = %v, want ErrSyncRunNotFound", err)
+	}
+}
+
+func analystView(brokerage, rating, target string, offset time.Duration) models.Stock {
+	stock := NewStock("AAPL", "Apple Inc.", offset)
+	stock.Brokerage = brokerage
+	stock.RatingTo = rating
+	stock.TargetTo = target
+	return stock
+}
+
+func testConsensus(t *testing.T, store repository.StockStore) {
+	mustCreate(t, store,
+		analystView("Alpha", "Buy", "$100.00", 0),
+		analystView("Beta", "Hold", "$120.00", time.Hour),
+		analystView("Alpha", "Strong Buy", "$110.00", 2*time.Hour),
+		analystView("Gamma", "Buy", "N/A", -time.Hour),
+		// Older than Alpha's latest view, so it must not replace it
+		analystView("Alpha", "Sell", "$50.00", -2*time.Hour),
+	)
+
+	got, err := store.GetConsensus(ctx, "AAPL")
+	if err != nil {
+		t.Fatalf("GetConsensus: %v", err)
+	}
+	latest := analystView("Alpha", "Strong Buy", "$110.00", 2*time.Hour)
+	if got.CoveringBrokerages != 3 || got.LatestStockID != latest.ID || !got.LastActionTime.Equal(latest.Time) ||
+		got.Latest == nil || got.Latest.ID != latest.ID || got.Company != "Apple Inc." {
+		t.Errorf("GetConsensus = %+v", got)
+	}
+	wantDistribution := map[string]int{"Strong Buy": 1, "Hold": 1, "Buy": 1}
+	if len(got.RatingDistribution) != len(wantDistribution) {
+		t.Errorf("RatingDistribution = %v, want %v", got.RatingDistribution, wantDistribution)
+	}
+	for rating, count := range wantDistribution {
+		if got.RatingDistribution[rating] != count {
+			t.Errorf("RatingDistribution = %v, want %v", got.RatingDistribution, wantDistribution)
+		}
+	}
+	// Gamma's target does not parse and is left out of the statistics
+	if !targetIs(got.MeanTarget, 115) || !targetIs(got.MedianTarget, 115) ||
+		!targetIs(got.HighTarget, 120) || !targetIs(got.LowTarget, 110) {
+		t.Errorf("targets mean=%v median=%v high=%v low=%v, want 115/115/120/110",
+			got.MeanTarget, got.MedianTarget, got.HighTarget, got.LowTarget)
+	}
+	if got.TargetCurrency != "USD" {
+		t.Errorf("TargetCurrency = %q, want USD", got.TargetCurrency)
+	}
+
+	// Moving Beta's event to another brokerage drops Beta's view
+	moved := analystView("Delta", "Hold", "$130.00", time.Hour)
+	mustCreate(t, store, moved)
+	got, err = store.GetConsensus(ctx, "AAPL")
+	if err != nil {
+		t.Fatalf("GetConsensus after brokerage change: %v", err)
+	}
+	var brokerages []string
+	for _, view := range got.Brokerages {
+		brokerages = append(brokerages, view.Brokerage)
+	}
+	if !equal(brokerages, []string{"Alpha", "Delta", "Gamma"}) || !targetIs(got.HighTarget, 130) {
+		t.Errorf("after brokerage change brokerages = %v, high = %v; want [Alpha Delta Gamma], 130", brokerages, got.HighTarget)
+	}
+
+	mustCreate(t, store, NewStock("GOOG", "Alphabet Inc.", 3*time.Hour))
+	list, err := store.ListConsensus(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("ListConsensus: %v", err)
+	}
+	var tickers []string
+	for _, consensus := range list {
+		tickers = append(tickers, consensus.Ticker)
+	}
+	if !equal(tickers, []string{"GOOG", "AAPL"}) {
+		t.Errorf("ListConsensus tickers = %v, want [GOOG AAPL]", tickers)
+	}
+	if page, err := store.ListConsensus(ctx, 1, 1); err != nil || len(page) != 1 || page[0].Ticker != "AAPL" {
+		t.Errorf("ListConsensus(1, 1) = %v, %v", page, err)
+	}
+	if count, err := store.CountConsensus(ctx); err != nil || count != 2 {
+		t.Errorf("CountConsensus = %d, %v; want 2", count, err)
+	}
+
+	if _, err := store.GetConsensus(ctx, "MISSING"); !errors.Is(err, repository.ErrTickerNotFound) {
+		t.Errorf("GetConsensus(missing) error = %v, want ErrTickerNotFound", err)
+	}
+}
+
+func targetIs(target *float64, want float64) bool {
+	return target != nil && *target == want
+}
+
+func newRun(t *testing.T, store repository.StockStore, writeMode string) *models.SyncRun {
+	t.Helper()
+	run := &models.SyncRun{Status: models.SyncRunRunning, WriteMode: writeMode, MaxPages: 5, StartedAt: baseTime}
+	if err := store.CreateSyncRun(ctx, run); err != nil {
+		t.Fatalf("CreateSyncRun: %v", err)
+	}
+	return run
+}
+
+// conflictingPage returns a page whose second row reuses the id of an
+// already stored event under a different (ticker, time), which every store
+// rejects.
+func conflictingPage(t *testing.T, store repository.StockStore) []models.Stock {
+	t.Helper()
+	existing := NewStock("AAPL", "Apple Inc.", 0)
+	mustCreate(t, store, existing)
+
+	conflict := NewStock("MSFT", "Microsoft Corp.", time.Hour)
+	conflict.ID = existing.ID
+	return []models.Stock{
+		NewStock("GOOG", "Alphabet Inc.", 2*time.Hour),
+		conflict,
+		NewStock("NVDA", "NVIDIA Corp.", 3*time.Hour),
+	}
+}
+
+func testSavePageAtomic(t *testing.T, store repository.StockStore) {
+	run := newRun(t, store, models.SyncWriteAtomic)
+
+	if err := store.SavePage(ctx, run, []models.Stock{NewStock("TSLA", "Tesla Inc.", -time.Hour)}, "cursor-2"); err != nil {
+		t.Fatalf("SavePage: %v", err)
+	}
+	if run.Pages != 1 || run.Fetched != 1 || run.Stored != 1 || run.NextPage != "cursor-2" {
+		t.Errorf("run after first page = %+v", run)
+	}
+
+	before := *run
+	if err := store.SavePage(ctx, run, conflictingPage(t, store), "cursor-3"); err == nil {
+		t.Fatalf("SavePage with a conflicting row succeeded in atomic mode")
+	}
+	if run.Pages != before.Pages || run.Stored != before.Stored || run.NextPage != before.NextPage {
+		t.Errorf("run changed by a failed page: %+v, want %+v", run, before)
+	}
+
+	// TSLA from the first page and the AAPL seed only; nothing from the failed page
+	if count, err := store.Count(ctx); err != nil || count != 2 {
+		t.Errorf("Count after failed atomic page = %d, %v; want 2", count, err)
+	}
+	got, err := store.GetSyncRun(ctx, run.ID)
+	if err != nil {
+		t.Fatalf("GetSyncRun: %v", err)
+	}
+	if got.Pages != 1 || got.Stored != 1 || got.NextPage != "cursor-2" {
+		t.Errorf("stored run after failed page = %+v", got)
+	}
+}
+
+func testSavePageBestEffort(t *testing.T, store repository.StockStore) {
+	run := newRun(t, store, models.SyncWriteBestEffort)
+	page := conflictingPage(t, store)
+
+	if err := store.SavePage(ctx, run, page, ""); err != nil {
+		t.Fatalf("SavePage: %v", err)
+	}
+	if run.Pages != 1 || run.Fetched != 3 || run.Stored != 2 || run.Failed != 1 || run.NextPage != "" {
+		t.Errorf("run after best-effort page = %+v", run)
+	}
+	if len(run.FailedRows) != 1 || run.FailedRows[0].Ticker != "MSFT" || run.FailedRows[0].Page != 1 || run.FailedRows[0].Error == "" {
+		t.Errorf("FailedRows = %+v, want the MSFT row of page 1", run.FailedRows)
+	}
+
+	if count, err := store.Count(ctx); err != nil || count != 3 {
+		t.Errorf("Count after best-effort page = %d, %v; want 3", count, err)
+	}
+	got, err := store.GetSyncRun(ctx, run.ID)
+	if err != nil {
+		t.Fatalf("GetSyncRun: %v", err)
+	}
+	if got.Failed != 1 || len(got.FailedRows) != 1 || got.FailedRows[0].StockID != page[1].ID {
+		t.Errorf("stored run = %+v", got)
+	}
+
+	missing := models.SyncRun{ID: "missing", WriteMode: models.SyncWriteBestEffort}
+	if err := store.SavePage(ctx, &missing, nil, ""); !errors.Is(err, repository.ErrSyncRunNotFound) {
+		t.Errorf("SavePage(missing run) error = %v, want ErrSyncRunNotFound", err)
+	}
+}
+
+func tickersOf(stocks []models.Stock) []string {
+	tickers := make([]string, len(stocks))
+	for i, stock := range stocks {
+		tickers[i] = stock.Ticker
+	}
+	return tickers
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
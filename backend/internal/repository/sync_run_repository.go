@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+)
+
+// ErrSyncRunNotFound is returned by GetSyncRun when no run has the given id.
+var ErrSyncRunNotFound = errors.New("sync run not found")
+
+// CreateSyncRun stores a new run, assigning its id when empty.
+func (r *StockRepository) CreateSyncRun(ctx context.Context, run *models.SyncRun) error {
+	ctx, cancel := r.db.withTimeout(ctx, OperationWrite)
+	defer cancel()
+
+	if run.ID == "" {
+		run.ID = newID()
+	}
+
+	query := `
+		INSERT INTO sync_runs (id, status, write_mode, max_pages, pages, fetched, stored, failed,
+			next_page, failed_rows, error, started_at, finished_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	`
+
+	failedRows, err := encodeFailedRows(run.FailedRows)
+	if err != nil {
+		return err
+	}
+
+	err = r.db.retrySerializable(ctx, func() error {
+		_, err := r.db.DB.ExecContext(ctx, query,
+			run.ID, run.Status, run.WriteMode, run.MaxPages, run.Pages, run.Fetched, run.Stored, run.Failed,
+			nullString(run.NextPage), failedRows, nullString(run.Error), run.StartedAt.UTC(), nullTime(run.FinishedAt))
+		return err
+	})
+	return wrapTimeout(ctx, err)
+}
+
+// UpdateSyncRun saves the status, counters and checkpoint of an existing run.
+func (r *StockRepository) UpdateSyncRun(ctx context.Context, run *models.SyncRun) error {
+	ctx, cancel := r.db.withTimeout(ctx, OperationWrite)
+	defer cancel()
+
+	err := r.db.retrySerializable(ctx, func() error {
+		return r.updateSyncRun(ctx, r.db.DB, run)
+	})
+	return wrapTimeout(ctx, err)
+}
+
+// SavePage stores one provider page and advances the run's counters and
+// checkpoint to nextPage in a single transaction. In atomic mode the first
+// failing row rolls the whole page back and is returned; in best-effort mode
+// each row runs under a savepoint and failures are appended to
+// run.FailedRows. run is only modified once the transaction commits.
+func (r *StockRepository) SavePage(ctx context.Context, run *models.SyncRun, stocks []models.Stock, nextPage string) error {
+	ctx, cancel := r.db.withTimeout(ctx, OperationWrite)
+	defer cancel()
+
+	var saved models.SyncRun
+	err := r.db.inTx(ctx, func(tx *sql.Tx) error {
+		// Start from the caller's run on every attempt so retries do not double count
+		saved = *run
+		saved.FailedRows = append([]models.FailedRow(nil), run.FailedRows...)
+		saved.Pages++
+
+		for i := range stocks {
+			stock := &stocks[i]
+			if run.WriteMode != models.SyncWriteBestEffort {
+				if err := r.create(ctx, tx, stock, run.ID); err != nil {
+					return fmt.Errorf("error storing stock %s (%s): %w", stock.ID, stock.Ticker, err)
+				}
+				saved.Stored++
+				continue
+			}
+
+			rowErr, err := r.createUnderSavepoint(ctx, tx, stock, run.ID)
+			if err != nil {
+				return err
+			}
+			if rowErr != nil {
+				saved.Failed++
+				saved.FailedRows = append(saved.FailedRows, models.FailedRow{
+					StockID: stock.ID,
+					Ticker:  stock.Ticker,
+					Time:    stock.Time.UTC(),
+					Page:    saved.Pages,
+					Error:   rowErr.Error(),
+				})
+				continue
+			}
+			saved.Stored++
+		}
+
+		saved.Fetched += len(stocks)
+		saved.NextPage = nextPage
+		return r.updateSyncRun(ctx, tx, &saved)
+	})
+	if err != nil {
+		return wrapTimeout(ctx, err)
+	}
+
+	*run = saved
+	return nil
+}
+
+// createUnderSavepoint upserts one row, rolling back only that row when it
+// fails. The row's own error is returned separately from errors that doom the
+// whole transaction (serialization failures, cancellation, savepoint errors).
+func (r *StockRepository) createUnderSavepoint(ctx context.Context, tx *sql.Tx, stock *models.Stock, syncRunID string) (rowErr error, err error) {
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT sync_row"); err != nil {
+		return nil, err
+	}
+
+	if rowErr = r.create(ctx, tx, stock, syncRunID); rowErr != nil {
+		if isSerializationFailure(rowErr) || ctx.Err() != nil {
+			return nil, rowErr
+		}
+		if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT sync_row"); err != nil {
+			return nil, err
+		}
+		return rowErr, nil
+	}
+
+	_, err = tx.ExecContext(ctx, "RELEASE SAVEPOINT sync_row")
+	return nil, err
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func (r *StockRepository) updateSyncRun(ctx context.Context, db execer, run *models.SyncRun) error {
+	query := `
+		UPDATE sync_runs
+		SET status = $2, pages = $3, fetched = $4, stored = $5, failed = $6,
+			next_page = $7, failed_rows = $8, error = $9, finished_at = $10
+		WHERE id = $1
+	`
+
+	failedRows, err := encodeFailedRows(run.FailedRows)
+	if err != nil {
+		return err
+	}
+
+	result, err := db.ExecContext(ctx, query,
+		run.ID, run.Status, run.Pages, run.Fetched, run.Stored, run.Failed,
+		nullString(run.NextPage), failedRows, nullString(run.Error), nullTime(run.FinishedAt))
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrSyncRunNotFound
+	}
+	return nil
+}
+
+func (r *StockRepository) GetSyncRun(ctx context.Context, id string) (*models.SyncRun, error) {
+	ctx, cancel := r.db.withTimeout(ctx, OperationRead)
+	defer cancel()
+
+	query := `
+		SELECT id, status, write_mode, max_pages, pages, fetched, stored, failed,
+			next_page, failed_rows, error, started_at, finished_at
+		FROM sync_runs
+		WHERE id = $1
+	`
+
+	var run models.SyncRun
+	var nextPage, failedRows, runError sql.NullString
+	var finishedAt sql.NullTime
+	err := r.db.DB.QueryRowContext(ctx, query, id).Scan(
+		&run.ID, &run.Status, &run.WriteMode, &run.MaxPages, &run.Pages, &run.Fetched, &run.Stored, &run.Failed,
+		&nextPage, &failedRows, &runError, &run.StartedAt, &finishedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrSyncRunNotFound
+	}
+	if err != nil {
+		return nil, wrapTimeout(ctx, err)
+	}
+
+	run.NextPage = nextPage.String
+	run.Error = runError.String
+	if finishedAt.Valid {
+		run.FinishedAt = &finishedAt.Time
+	}
+	if failedRows.Valid {
+		if err := json.Unmarshal([]byte(failedRows.String), &run.FailedRows); err != nil {
+			return nil, fmt.Errorf("error decoding failed rows of sync run %s: %w", run.ID, err)
+		}
+	}
+	return &run, nil
+}
+
+// encodeFailedRows stores the list as JSON text, or NULL when it is empty.
+func encodeFailedRows(rows []models.FailedRow) (sql.NullString, error) {
+	if len(rows) == 0 {
+		return sql.NullString{}, nil
+	}
+
+	encoded, err := json.Marshal(rows)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(encoded), Valid: true}, nil
+}
@@ -0,0 +1,114 @@
+package screen
+
+import "strings"
+
+// truth is a three-valued boolean, as in SQL: a comparison with an unknown
+// value is itself unknown, and so is not of it. Evaluating the way SQL does
+// keeps what a pushed-down condition filters out a subset of what Match
+// rejects.
+type truth int8
+
+const (
+	truthFalse truth = iota
+	truthTrue
+	truthUnknown
+)
+
+// Match reports whether row passes the screen; rows the expression is
+// unknown for do not.
+func (s *Screen) Match(row *Row) bool {
+	return eval(s.root, row) == truthTrue
+}
+
+// Values returns the value of every field the expression reads, nil for
+// unknown ones, so results can show why a ticker matched.
+func (s *Screen) Values(row *Row) map[string]interface{} {
+	values := make(map[string]interface{}, len(s.fields))
+	for _, f := range s.fields {
+		values[f.name] = f.get(row).any()
+	}
+	return values
+}
+
+func eval(e expr, row *Row) truth {
+	switch e := e.(type) {
+	case *logical:
+		left, right := eval(e.left, row), eval(e.right, row)
+		if e.op == "and" {
+			if left == truthFalse || right == truthFalse {
+				return truthFalse
+			}
+			if left == truthUnknown || right == truthUnknown {
+				return truthUnknown
+			}
+			return truthTrue
+		}
+		if left == truthTrue || right == truthTrue {
+			return truthTrue
+		}
+		if left == truthUnknown || right == truthUnknown {
+			return truthUnknown
+		}
+		return truthFalse
+	case *not:
+		switch eval(e.x, row) {
+		case truthTrue:
+			return truthFalse
+		case truthFalse:
+			return truthTrue
+		}
+		return truthUnknown
+	case *comparison:
+		return compare(e, e.field.get(row))
+	}
+	return truthUnknown
+}
+
+func compare(c *comparison, v value) truth {
+	if v.typ == 0 {
+		return truthUnknown
+	}
+
+	var result bool
+	switch c.op {
+	case OpIn, OpNotIn:
+		for _, candidate := range c.values {
+			if equal(v, candidate) {
+				result = true
+				break
+			}
+		}
+		if c.op == OpNotIn {
+			result = !result
+		}
+	case OpContains, OpNotContains:
+		result = strings.Contains(strings.ToLower(v.str), strings.ToLower(c.values[0].str))
+		if c.op == OpNotContains {
+			result = !result
+		}
+	case OpEq:
+		result = equal(v, c.values[0])
+	case OpNe:
+		result = !equal(v, c.values[0])
+	case OpLt:
+		result = v.num < c.values[0].num
+	case OpLe:
+		result = v.num <= c.values[0].num
+	case OpGt:
+		result = v.num > c.values[0].num
+	case OpGe:
+		result = v.num >= c.values[0].num
+	}
+	if result {
+		return truthTrue
+	}
+	return truthFalse
+}
+
+// equal compares numbers exactly and strings regardless of case.
+func equal(a, b value) bool {
+	if a.typ == TypeNumber {
+		return a.num == b.num
+	}
+	return strings.EqualFold(a.str, b.str)
+}
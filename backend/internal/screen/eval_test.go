@@ -0,0 +1,132 @@
+package screen
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+)
+
+var evalNow = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// evalRow is Apple ten days after an upgrade whose targets are in
+// different currencies, covered by three brokerages, without a security.
+func evalRow() *Row {
+	mean := 210.0
+	return &Row{
+		Consensus: &models.TickerConsensus{
+			Ticker:             "AAPL",
+			Company:            "Apple Inc.",
+			CoveringBrokerages: 3,
+			MeanTarget:         &mean,
+			TargetCurrency:     "USD",
+			LastActionTime:     evalNow.AddDate(0, 0, -10),
+			Latest: &models.Stock{
+				Ticker:     "AAPL",
+				Brokerage:  "Example Securities",
+				Action:     "upgraded by",
+				RatingFrom: "Hold",
+				RatingTo:   "Strong Buy",
+				TargetFrom: "$200.00",
+				TargetTo:   "€230",
+			},
+		},
+		Now: evalNow,
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       bool
+	}{
+		{"ticker = aapl", true},
+		{"ticker != AAPL", false},
+		{"rating_to in (Buy, strong buy)", true},
+		{"rating_to not in (Buy, Strong Buy)", false},
+		{"company contains APPLE", true},
+		{"company not contains apple", false},
+		{"action_class = upgrade", true},
+		{"brokerage_count >= 3 and brokerage_count < 4", true},
+		{"mean_target > 210", false},
+		{"mean_target <= 210", true},
+		{"target_from = 200 and target_to = 230", true},
+		{"age_days > 9.5 and age_days <= 10", true},
+		{"target_currency = usd", true},
+		// The targets are in different currencies, so the change is unknown:
+		// comparisons with it and their not fail
+		{"target_change_pct >= 0", false},
+		{"target_change_pct < 0", false},
+		{"not target_change_pct >= 0", false},
+		{"target_change_pct >= 0 or ticker = AAPL", true},
+		{"target_change_pct >= 0 and ticker = AAPL", false},
+		{"not (target_change_pct >= 0 and ticker = AAPL)", false},
+		{"not (target_change_pct >= 0 and ticker = MSFT)", true},
+		{"not (target_change_pct >= 0 or ticker = MSFT)", false},
+		// Without a security, sector is unknown too
+		{"sector = Technology", false},
+		{"sector != Technology", false},
+		{"not sector in (Technology)", false},
+	}
+	for _, tt := range tests {
+		screen, err := Parse(tt.expression)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.expression, err)
+		}
+		if got := screen.Match(evalRow()); got != tt.want {
+			t.Errorf("Match(%q) = %v, want %v", tt.expression, got, tt.want)
+		}
+	}
+}
+
+func TestMatchSecurity(t *testing.T) {
+	screen, err := Parse("sector = technology and industry contains hardware")
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := evalRow()
+	row.Security = &models.Security{Ticker: "AAPL", Sector: "Technology", Industry: "Consumer Hardware"}
+	if !screen.Match(row) {
+		t.Error("a known security in the sector and industry should match")
+	}
+	row.Security.Industry = ""
+	if screen.Match(row) {
+		t.Error("a blank industry is unknown and should not match")
+	}
+}
+
+func TestMatchWithoutLatestEvent(t *testing.T) {
+	row := evalRow()
+	row.Consensus.Latest = nil
+	for _, expression := range []string{"rating_to = Buy", "not rating_to = Buy", "target_to > 0"} {
+		screen, err := Parse(expression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if screen.Match(row) {
+			t.Errorf("Match(%q) without a latest event = true, want false", expression)
+		}
+	}
+}
+
+func TestValues(t *testing.T) {
+	screen, err := Parse("rating_to = Buy or target_change_pct > 10 or brokerage_count > 2 or sector = Energy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := screen.Values(evalRow())
+	want := map[string]interface{}{
+		"rating_to":         "Strong Buy",
+		"target_change_pct": nil,
+		"brokerage_count":   3.0,
+		"sector":            nil,
+	}
+	if len(values) != len(want) {
+		t.Fatalf("Values = %v, want %v", values, want)
+	}
+	for name, value := range want {
+		if got, ok := values[name]; !ok || got != value {
+			t.Errorf("Values[%s] = %v, want %v", name, got, value)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package screen
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+)
+
+// Row is what an expression is evaluated against: a ticker's consensus with
+// its latest event, and the ticker's security when known.
+type Row struct {
+	Consensus *models.TickerConsensus
+	Security  *models.Security
+	Now       time.Time
+}
+
+// field is a name an expression can compare.
+type field struct {
+	name string
+	typ  Type
+	// column is the field's SQL over ticker_consensus c and its latest event
+	// s; empty for fields only known in memory
+	column string
+	// age fields are days since column, a timestamp
+	age bool
+	// security fields come from the securities reference
+	security bool
+	get      func(row *Row) value
+}
+
+// allows reports whether op applies to the field's type.
+func (f *field) allows(op string) bool {
+	switch op {
+	case OpEq, OpNe, OpIn, OpNotIn:
+		return true
+	case OpLt, OpLe, OpGt, OpGe:
+		return f.typ == TypeNumber
+	case OpContains, OpNotContains:
+		return f.typ == TypeString
+	}
+	return false
+}
+
+// latestField reads a string of the ticker's latest event.
+func latestField(name, column string, get func(*models.Stock) string) *field {
+	return &field{name: name, typ: TypeString, column: column, get: func(row *Row) value {
+		if row.Consensus.Latest == nil {
+			return value{}
+		}
+		return text(get(row.Consensus.Latest))
+	}}
+}
+
+// targetField reads a target amount of the ticker's latest event, in
+// whatever currency it is written in.
+func targetField(name string, get func(*models.Stock) string) *field {
+	return &field{name: name, typ: TypeNumber, get: func(row *Row) value {
+		if row.Consensus.Latest == nil {
+			return value{}
+		}
+		amount, _, err := models.ParseTarget(get(row.Consensus.Latest))
+		if err != nil {
+			return value{}
+		}
+		return number(amount)
+	}}
+}
+
+// consensusTarget reads one of the consensus target statistics.
+func consensusTarget(name, column string, get func(*models.TickerConsensus) *float64) *field {
+	return &field{name: name, typ: TypeNumber, column: column, get: func(row *Row) value {
+		if target := get(row.Consensus); target != nil {
+			return number(*target)
+		}
+		return value{}
+	}}
+}
+
+// securityField reads the ticker's sector or industry; unknown tickers and
+// blank entries are unknown.
+func securityField(name string, get func(*models.Security) string) *field {
+	return &field{name: name, typ: TypeString, security: true, get: func(row *Row) value {
+		if row.Security == nil || get(row.Security) == "" {
+			return value{}
+		}
+		return text(get(row.Security))
+	}}
+}
+
+// fields are the names expressions can use. Event fields are those of the
+// ticker's latest event; a target that cannot be read, or a change between
+// targets in different currencies, is unknown.
+var fields = []*field{
+	{name: "ticker", typ: TypeString, column: "c.ticker", get: func(row *Row) value { return text(row.Consensus.Ticker) }},
+	{name: "company", typ: TypeString, column: "c.company", get: func(row *Row) value { return text(row.Consensus.Company) }},
+	securityField("sector", func(s *models.Security) string { return s.Sector }),
+	securityField("industry", func(s *models.Security) string { return s.Industry }),
+	latestField("brokerage", "s.brokerage", func(s *models.Stock) string { return s.Brokerage }),
+	latestField("action", "s.action", func(s *models.Stock) string { return s.Action }),
+	latestField("action_class", "", func(s *models.Stock) string { return models.ActionClass(s.Action) }),
+	latestField("rating_from", "s.rating_from", func(s *models.Stock) string { return s.RatingFrom }),
+	latestField("rating_to", "s.rating_to", func(s *models.Stock) string { return s.RatingTo }),
+	targetField("target_from", func(s *models.Stock) string { return s.TargetFrom }),
+	targetField("target_to", func(s *models.Stock) string { return s.TargetTo }),
+	{name: "target_change_pct", typ: TypeNumber, get: func(row *Row) value {
+		latest := row.Consensus.Latest
+		if latest == nil {
+			return value{}
+		}
+		from, fromCurrency, err := models.ParseTarget(latest.TargetFrom)
+		if err != nil {
+			return value{}
+		}
+		to, toCurrency, err := models.ParseTarget(latest.TargetTo)
+		if err != nil || toCurrency != fromCurrency {
+			return value{}
+		}
+		return number((to - from) / from * 100)
+	}},
+	{name: "age_days", typ: TypeNumber, column: "c.last_action_time", age: true, get: func(row *Row) value {
+		return number(row.Now.Sub(row.Consensus.LastActionTime).Hours() / 24)
+	}},
+	{name: "brokerage_count", typ: TypeNumber, column: "CAST(c.covering_brokerages AS DOUBLE PRECISION)", get: func(row *Row) value {
+		return number(float64(row.Consensus.CoveringBrokerages))
+	}},
+	consensusTarget("mean_target", "c.mean_target", func(c *models.TickerConsensus) *float64 { return c.MeanTarget }),
+	consensusTarget("median_target", "c.median_target", func(c *models.TickerConsensus) *float64 { return c.MedianTarget }),
+	consensusTarget("high_target", "c.high_target", func(c *models.TickerConsensus) *float64 { return c.HighTarget }),
+	consensusTarget("low_target", "c.low_target", func(c *models.TickerConsensus) *float64 { return c.LowTarget }),
+	{name: "target_currency", typ: TypeString, column: "c.target_currency", get: func(row *Row) value {
+		if row.Consensus.TargetCurrency == "" {
+			return value{}
+		}
+		return text(row.Consensus.TargetCurrency)
+	}},
+}
+
+func lookupField(name string) (*field, bool) {
+	name = strings.ToLower(name)
+	for _, f := range fields {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// FieldNames lists the fields expressions can use, alphabetically.
+func FieldNames() []string {
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = f.name
+	}
+	sort.Strings(names)
+	return names
+}
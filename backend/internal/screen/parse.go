@@ -0,0 +1,324 @@
+package screen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return fmt.Sprintf("string %q", t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// keyword returns the lowercased keyword t spells, or "".
+func (t token) keyword() string {
+	if t.kind != tokWord {
+		return ""
+	}
+	switch word := strings.ToLower(t.text); word {
+	case "and", "or", "not", "in", "contains":
+		return word
+	}
+	return ""
+}
+
+// maxExpressionLength bounds what a screen may hold, and with it how deep
+// the parser may recurse.
+const maxExpressionLength = 4096
+
+type lexer struct {
+	input string
+	pos   int
+}
+
+// isWordRune reports whether r may appear in a bare word: field names,
+// numbers and unquoted values such as "Strong", "BRK.B" or "J&J".
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_.-&+/", r)
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.input) {
+		r, size := utf8.DecodeRuneInString(l.input[l.pos:])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		l.pos += size
+	}
+	start := l.pos
+	if l.pos >= len(l.input) {
+		return token{kind: tokEOF, pos: start}, nil
+	}
+
+	r, size := utf8.DecodeRuneInString(l.input[l.pos:])
+	switch {
+	case r == '(':
+		l.pos++
+		return token{kind: tokLParen, text: "(", pos: start}, nil
+	case r == ')':
+		l.pos++
+		return token{kind: tokRParen, text: ")", pos: start}, nil
+	case r == ',':
+		l.pos++
+		return token{kind: tokComma, text: ",", pos: start}, nil
+	case r == '\'' || r == '"':
+		// Quotes are escaped by doubling them, as in SQL
+		var b strings.Builder
+		l.pos++
+		for {
+			end := strings.IndexRune(l.input[l.pos:], r)
+			if end < 0 {
+				return token{}, errorf(start, "unterminated string")
+			}
+			b.WriteString(l.input[l.pos : l.pos+end])
+			l.pos += end + 1
+			if l.pos < len(l.input) && rune(l.input[l.pos]) == r {
+				b.WriteRune(r)
+				l.pos++
+				continue
+			}
+			return token{kind: tokString, text: b.String(), pos: start}, nil
+		}
+	case strings.ContainsRune("=!<>", r):
+		for _, op := range []string{"<=", ">=", "!=", "<>", "=", "<", ">"} {
+			if strings.HasPrefix(l.input[l.pos:], op) {
+				l.pos += len(op)
+				if op == "<>" {
+					op = OpNe
+				}
+				return token{kind: tokOp, text: op, pos: start}, nil
+			}
+		}
+		return token{}, errorf(start, "unexpected %q", r)
+	case isWordRune(r):
+		for l.pos < len(l.input) {
+			r, size := utf8.DecodeRuneInString(l.input[l.pos:])
+			if !isWordRune(r) {
+				break
+			}
+			l.pos += size
+		}
+		return token{kind: tokWord, text: l.input[start:l.pos], pos: start}, nil
+	}
+	l.pos += size
+	return token{}, errorf(start, "unexpected %q", r)
+}
+
+// parser is a recursive descent parser over the grammar
+//
+//	or         = and { "or" and }
+//	and        = unary { "and" unary }
+//	unary      = "not" unary | "(" or ")" | comparison
+//	comparison = field op literal
+//	           | field [ "not" ] "in" "(" literal { "," literal } ")"
+//	           | field [ "not" ] "contains" literal
+//	literal    = number | quoted string | words
+//
+// where words are bare words read up to the next keyword, operator or
+// parenthesis and joined by single spaces, so Strong Buy needs no quotes.
+// Keywords are case-insensitive.
+type parser struct {
+	lexer lexer
+	tok   token
+	err   error
+}
+
+func (p *parser) next() {
+	if p.err != nil {
+		return
+	}
+	p.tok, p.err = p.lexer.next()
+	if p.err != nil {
+		p.tok = token{kind: tokEOF, pos: p.lexer.pos}
+	}
+}
+
+// fail returns the lexer's error if it stopped early, else err.
+func (p *parser) fail(err *Error) error {
+	if p.err != nil {
+		return p.err
+	}
+	return err
+}
+
+func (p *parser) parseOr() (expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.keyword() == "or" {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logical{op: "or", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.keyword() == "and" {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &logical{op: "and", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (expr, error) {
+	switch {
+	case p.tok.keyword() == "not":
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &not{x: x}, nil
+	case p.tok.kind == tokLParen:
+		p.next()
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokRParen {
+			return nil, p.fail(errorf(p.tok.pos, "expected \")\", got %s", p.tok))
+		}
+		p.next()
+		return x, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (expr, error) {
+	if p.tok.kind != tokWord || p.tok.keyword() != "" {
+		return nil, p.fail(errorf(p.tok.pos, "expected a field, got %s", p.tok))
+	}
+	f, ok := lookupField(p.tok.text)
+	if !ok {
+		return nil, errorf(p.tok.pos, "unknown field %q; fields are %s", p.tok.text, strings.Join(FieldNames(), ", "))
+	}
+	p.next()
+
+	c := &comparison{field: f}
+	opPos := p.tok.pos
+	switch {
+	case p.tok.kind == tokOp:
+		c.op = p.tok.text
+		p.next()
+	case p.tok.keyword() == "in" || p.tok.keyword() == "contains":
+		c.op = p.tok.keyword()
+		p.next()
+	case p.tok.keyword() == "not":
+		p.next()
+		switch p.tok.keyword() {
+		case "in":
+			c.op = OpNotIn
+		case "contains":
+			c.op = OpNotContains
+		default:
+			return nil, p.fail(errorf(p.tok.pos, "expected \"in\" or \"contains\" after \"not\", got %s", p.tok))
+		}
+		p.next()
+	default:
+		return nil, p.fail(errorf(p.tok.pos, "expected an operator after %s, got %s", f.name, p.tok))
+	}
+	if !f.allows(c.op) {
+		return nil, errorf(opPos, "%s is a %s field and cannot be compared with %s", f.name, f.typ, c.op)
+	}
+
+	if c.op == OpIn || c.op == OpNotIn {
+		if p.tok.kind != tokLParen {
+			return nil, p.fail(errorf(p.tok.pos, "expected \"(\" after %s, got %s", c.op, p.tok))
+		}
+		p.next()
+		for {
+			v, err := p.parseLiteral(f)
+			if err != nil {
+				return nil, err
+			}
+			c.values = append(c.values, v)
+			if p.tok.kind == tokComma {
+				p.next()
+				continue
+			}
+			if p.tok.kind != tokRParen {
+				return nil, p.fail(errorf(p.tok.pos, "expected \",\" or \")\", got %s", p.tok))
+			}
+			p.next()
+			break
+		}
+	} else {
+		v, err := p.parseLiteral(f)
+		if err != nil {
+			return nil, err
+		}
+		c.values = []value{v}
+	}
+	return c, nil
+}
+
+// parseLiteral reads a literal and checks it against the field it is
+// compared with: numbers for number fields, anything for string fields.
+func (p *parser) parseLiteral(f *field) (value, error) {
+	start := p.tok
+	var raw string
+	quoted := false
+	switch {
+	case p.tok.kind == tokString:
+		raw, quoted = p.tok.text, true
+		p.next()
+	case p.tok.kind == tokWord && p.tok.keyword() == "":
+		words := []string{p.tok.text}
+		p.next()
+		for p.tok.kind == tokWord && p.tok.keyword() == "" {
+			words = append(words, p.tok.text)
+			p.next()
+		}
+		raw = strings.Join(words, " ")
+	default:
+		return value{}, p.fail(errorf(p.tok.pos, "expected a value for %s, got %s", f.name, p.tok))
+	}
+
+	if f.typ == TypeString {
+		return text(raw), nil
+	}
+	n, err := strconv.ParseFloat(raw, 64)
+	if quoted || err != nil || n != n || n > 1e300 || n < -1e300 {
+		return value{}, errorf(start.pos, "%s is a number field, got %q", f.name, raw)
+	}
+	return number(n), nil
+}
@@ -0,0 +1,129 @@
+package screen
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+		fields     string
+	}{
+		{
+			expression: "rating_to in (Buy, Strong Buy) and target_change_pct > 20 and brokerage_count >= 3 and age_days < 14",
+			want:       "(((rating_to in ('Buy', 'Strong Buy') and target_change_pct > 20) and brokerage_count >= 3) and age_days < 14)",
+			fields:     "age_days brokerage_count rating_to target_change_pct",
+		},
+		// and binds tighter than or; keywords and field names ignore case
+		{
+			expression: "ticker = AAPL OR Ticker = MSFT AND NOT age_days > 7",
+			want:       "(ticker = 'AAPL' or (ticker = 'MSFT' and not age_days > 7))",
+			fields:     "age_days ticker",
+		},
+		{
+			expression: "(ticker = AAPL or ticker = MSFT) and sector = Technology",
+			want:       "((ticker = 'AAPL' or ticker = 'MSFT') and sector = 'Technology')",
+			fields:     "sector ticker",
+		},
+		// Quotes escape by doubling; bare words keep symbols tickers use
+		{
+			expression: `company contains 'O''Reilly' and ticker not in ("BRK.B", J&J) and brokerage not contains "x"`,
+			want:       "((company contains 'O''Reilly' and ticker not in ('BRK.B', 'J&J')) and brokerage not contains 'x')",
+			fields:     "brokerage company ticker",
+		},
+		{
+			expression: "mean_target <> -1.5e2 and action_class != upgrade",
+			want:       "(mean_target != -150 and action_class != 'upgrade')",
+			fields:     "action_class mean_target",
+		},
+	}
+	for _, tt := range tests {
+		screen, err := Parse(tt.expression)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.expression, err)
+			continue
+		}
+		if got := screen.String(); got != tt.want {
+			t.Errorf("Parse(%q) = %s, want %s", tt.expression, got, tt.want)
+		}
+		if got := strings.Join(screen.Fields(), " "); got != tt.fields {
+			t.Errorf("Parse(%q) reads %s, want %s", tt.expression, got, tt.fields)
+		}
+		// The canonical form parses back to itself
+		again, err := Parse(screen.String())
+		if err != nil || again.String() != screen.String() {
+			t.Errorf("Parse(%q) = %v, %v; want it unchanged", screen.String(), again, err)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expression string
+		pos        int
+		msg        string
+	}{
+		{"", 0, "empty expression"},
+		{"price > 10", 0, `unknown field "price"`},
+		{"ticker < AAPL", 7, "ticker is a string field and cannot be compared with <"},
+		{"age_days contains 3", 9, "age_days is a number field and cannot be compared with contains"},
+		{"age_days > ten", 11, `age_days is a number field, got "ten"`},
+		{"age_days > '10'", 11, `age_days is a number field, got "10"`},
+		{"age_days > 1e400", 11, "age_days is a number field"},
+		{"ticker = 'AAPL", 9, "unterminated string"},
+		{"ticker = AAPL and", 17, "expected a field, got end of expression"},
+		{"(ticker = AAPL", 14, `expected ")", got end of expression`},
+		{"ticker = AAPL)", 13, `unexpected ")"`},
+		{"ticker in AAPL", 10, `expected "(" after in`},
+		{"ticker in (AAPL MSFT", 20, `expected "," or ")"`},
+		{"ticker not = AAPL", 11, `expected "in" or "contains" after "not"`},
+		{"ticker AAPL", 7, "expected an operator after ticker"},
+		{"ticker = ", 9, "expected a value for ticker"},
+		{"ticker = AAPL; drop", 13, `unexpected ';'`},
+		{"ticker ! AAPL", 7, `unexpected '!'`},
+		{strings.Repeat("x", maxExpressionLength+1), maxExpressionLength, "longer than"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.expression)
+		var parseErr *Error
+		if !errors.As(err, &parseErr) {
+			t.Errorf("Parse(%q) = %v, want an *Error", tt.expression, err)
+			continue
+		}
+		if parseErr.Pos != tt.pos || !strings.Contains(parseErr.Msg, tt.msg) {
+			t.Errorf("Parse(%q) = %d: %s; want %d: %s", tt.expression, parseErr.Pos, parseErr.Msg, tt.pos, tt.msg)
+		}
+	}
+}
+
+func TestParseDeepNestingStaysWithinLength(t *testing.T) {
+	depth := maxExpressionLength / 2
+	_, err := Parse(strings.Repeat("(", depth) + "age_days > 1" + strings.Repeat(")", depth))
+	if err == nil {
+		t.Fatal("an expression over the length limit parsed")
+	}
+	nested := strings.Repeat("not ", 500) + "age_days > 1"
+	if _, err := Parse(nested); err != nil {
+		t.Errorf("Parse of %d nested nots: %v", 500, err)
+	}
+}
+
+func TestUsesSecurities(t *testing.T) {
+	for expression, want := range map[string]bool{
+		"ticker = AAPL":                          false,
+		"ticker = AAPL or sector = Energy":       true,
+		"not industry contains software":         true,
+		"target_currency = EUR and age_days < 7": false,
+	} {
+		screen, err := Parse(expression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := screen.UsesSecurities(); got != want {
+			t.Errorf("%q uses securities = %v, want %v", expression, got, want)
+		}
+	}
+}
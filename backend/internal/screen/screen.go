@@ -0,0 +1,215 @@
+// Package screen implements the expression language of saved screens, such
+// as
+//
+//	rating_to in (Buy, Strong Buy) and target_change_pct > 20 and brokerage_count >= 3 and age_days < 14
+//
+// Expressions are comparisons of a field with literals, combined with and,
+// or, not and parentheses. They are type-checked against the fields of
+// fields.go when parsed, evaluated in memory against a ticker's consensus and
+// latest event, and the parts that map to columns compile to a parameterized
+// SQL condition that narrows the rows read.
+package screen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Type is the type of a field or literal.
+type Type int
+
+const (
+	TypeNumber Type = iota + 1
+	TypeString
+)
+
+func (t Type) String() string {
+	switch t {
+	case TypeNumber:
+		return "number"
+	case TypeString:
+		return "string"
+	}
+	return "unknown"
+}
+
+// Comparison operators
+const (
+	OpEq          = "="
+	OpNe          = "!="
+	OpLt          = "<"
+	OpLe          = "<="
+	OpGt          = ">"
+	OpGe          = ">="
+	OpIn          = "in"
+	OpNotIn       = "not in"
+	OpContains    = "contains"
+	OpNotContains = "not contains"
+)
+
+// Error is a syntax or type error at a byte offset of the expression.
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("at position %d: %s", e.Pos+1, e.Msg)
+}
+
+func errorf(pos int, format string, args ...interface{}) *Error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// expr is a node of a parsed expression.
+type expr interface {
+	String() string
+}
+
+// logical is an and or or of two expressions.
+type logical struct {
+	op          string
+	left, right expr
+}
+
+func (e *logical) String() string {
+	return "(" + e.left.String() + " " + e.op + " " + e.right.String() + ")"
+}
+
+type not struct {
+	x expr
+}
+
+func (e *not) String() string {
+	return "not " + e.x.String()
+}
+
+// comparison tests a field against one literal, or several for in.
+type comparison struct {
+	field  *field
+	op     string
+	values []value
+}
+
+func (e *comparison) String() string {
+	texts := make([]string, len(e.values))
+	for i, v := range e.values {
+		texts[i] = v.String()
+	}
+	if e.op == OpIn || e.op == OpNotIn {
+		return e.field.name + " " + e.op + " (" + strings.Join(texts, ", ") + ")"
+	}
+	return e.field.name + " " + e.op + " " + texts[0]
+}
+
+// value is a typed literal, or the value of a field; a value without a type
+// is unknown, like SQL's NULL.
+type value struct {
+	typ Type
+	num float64
+	str string
+}
+
+func number(n float64) value { return value{typ: TypeNumber, num: n} }
+func text(s string) value    { return value{typ: TypeString, str: s} }
+
+func (v value) String() string {
+	switch v.typ {
+	case TypeNumber:
+		return fmt.Sprint(v.num)
+	case TypeString:
+		return "'" + strings.ReplaceAll(v.str, "'", "''") + "'"
+	}
+	return "null"
+}
+
+// any returns v as JSON shows it, nil when unknown.
+func (v value) any() interface{} {
+	switch v.typ {
+	case TypeNumber:
+		return v.num
+	case TypeString:
+		return v.str
+	}
+	return nil
+}
+
+// Screen is a parsed and type-checked expression.
+type Screen struct {
+	root   expr
+	fields []*field
+}
+
+// Parse reads and type-checks an expression. The error is an *Error
+// pointing at the offending part.
+func Parse(expression string) (*Screen, error) {
+	if strings.TrimSpace(expression) == "" {
+		return nil, errorf(0, "empty expression")
+	}
+	if len(expression) > maxExpressionLength {
+		return nil, errorf(maxExpressionLength, "expression is longer than %d characters", maxExpressionLength)
+	}
+
+	p := &parser{lexer: lexer{input: expression}}
+	p.next()
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, errorf(p.tok.pos, "unexpected %s", p.tok)
+	}
+
+	seen := map[string]bool{}
+	var fields []*field
+	walk(root, func(c *comparison) {
+		if !seen[c.field.name] {
+			seen[c.field.name] = true
+			fields = append(fields, c.field)
+		}
+	})
+	sort.Slice(fields, func(i, j int) bool { return fields[i].name < fields[j].name })
+	return &Screen{root: root, fields: fields}, nil
+}
+
+// String is the expression with every operator explicit and every
+// combination parenthesized, e.g. "(rating_to in ('Buy') and age_days < 14)".
+func (s *Screen) String() string {
+	return s.root.String()
+}
+
+// Fields lists the names of the fields the expression reads.
+func (s *Screen) Fields() []string {
+	names := make([]string, len(s.fields))
+	for i, f := range s.fields {
+		names[i] = f.name
+	}
+	return names
+}
+
+// UsesSecurities reports whether the expression reads sector or industry
+// data, which comes from the securities reference rather than the events.
+func (s *Screen) UsesSecurities() bool {
+	for _, f := range s.fields {
+		if f.security {
+			return true
+		}
+	}
+	return false
+}
+
+func walk(e expr, fn func(*comparison)) {
+	switch e := e.(type) {
+	case *logical:
+		walk(e.left, fn)
+		walk(e.right, fn)
+	case *not:
+		walk(e.x, fn)
+	case *comparison:
+		fn(e)
+	}
+}
@@ -0,0 +1,172 @@
+package screen
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Condition is a parameterized SQL condition over ticker_consensus c and
+// the latest event s joined to it, with placeholders $1 to $len(Args).
+type Condition struct {
+	SQL  string
+	Args []interface{}
+}
+
+// ageSlack widens the time bounds age_days compiles to, so rounding of
+// stored timestamps never drops a row Match would keep.
+const ageSlack = time.Second
+
+// maxAgeDays is the largest age compiled to a bound; larger ones would
+// overflow a time.Duration.
+const maxAgeDays = 100 * 365
+
+// Prefilter compiles the parts of the expression that map to columns into
+// a condition every matching row meets, evaluated as of now. Reading only
+// the rows it keeps and calling Match on them gives the screen's results;
+// ok is false when no part maps to columns and every row has to be read.
+func (s *Screen) Prefilter(now time.Time) (Condition, bool) {
+	c := &compiler{now: now}
+	where, ok := c.loose(s.root)
+	if !ok {
+		return Condition{}, false
+	}
+	return Condition{SQL: where, Args: c.args}, true
+}
+
+type compiler struct {
+	now  time.Time
+	args []interface{}
+}
+
+func (c *compiler) arg(v interface{}) string {
+	c.args = append(c.args, v)
+	return fmt.Sprintf("$%d", len(c.args))
+}
+
+// loose compiles e to a condition that holds wherever e does, leaving out
+// the parts of an and that do not compile.
+func (c *compiler) loose(e expr) (string, bool) {
+	switch e := e.(type) {
+	case *logical:
+		mark := len(c.args)
+		left, leftOK := c.loose(e.left)
+		right, rightOK := c.loose(e.right)
+		switch {
+		case leftOK && rightOK:
+			return "(" + left + " " + strings.ToUpper(e.op) + " " + right + ")", true
+		case e.op == "and" && leftOK:
+			return left, true
+		case e.op == "and" && rightOK:
+			return right, true
+		}
+		c.args = c.args[:mark]
+		return "", false
+	case *comparison:
+		if e.field.age {
+			return c.age(e)
+		}
+	}
+	return c.exact(e)
+}
+
+// exact compiles e to a condition that is true, false or NULL exactly where
+// e evaluates true, false or unknown, so it can be negated.
+func (c *compiler) exact(e expr) (string, bool) {
+	mark := len(c.args)
+	switch e := e.(type) {
+	case *logical:
+		left, leftOK := c.exact(e.left)
+		if leftOK {
+			if right, ok := c.exact(e.right); ok {
+				return "(" + left + " " + strings.ToUpper(e.op) + " " + right + ")", true
+			}
+		}
+	case *not:
+		if x, ok := c.exact(e.x); ok {
+			return "NOT (" + x + ")", true
+		}
+	case *comparison:
+		if where, ok := c.comparison(e); ok {
+			return where, true
+		}
+	}
+	c.args = c.args[:mark]
+	return "", false
+}
+
+func (c *compiler) comparison(e *comparison) (string, bool) {
+	f := e.field
+	if f.column == "" || f.age {
+		return "", false
+	}
+
+	column := f.column
+	if f.typ == TypeString {
+		// Case is folded by LOWER, which only folds ASCII on SQLite; other
+		// literals are left to Match
+		for _, v := range e.values {
+			if !isASCII(v.str) {
+				return "", false
+			}
+		}
+		column = "LOWER(COALESCE(" + column + ", ''))"
+	}
+	literal := func(v value) string {
+		if v.typ == TypeString {
+			return c.arg(strings.ToLower(v.str))
+		}
+		return c.arg(v.num)
+	}
+
+	switch e.op {
+	case OpIn, OpNotIn:
+		placeholders := make([]string, len(e.values))
+		for i, v := range e.values {
+			placeholders[i] = literal(v)
+		}
+		op := "IN"
+		if e.op == OpNotIn {
+			op = "NOT IN"
+		}
+		return fmt.Sprintf("%s %s (%s)", column, op, strings.Join(placeholders, ", ")), true
+	case OpContains, OpNotContains:
+		pattern := "%" + likeEscaper.Replace(strings.ToLower(e.values[0].str)) + "%"
+		op := "LIKE"
+		if e.op == OpNotContains {
+			op = "NOT LIKE"
+		}
+		return fmt.Sprintf(`%s %s %s ESCAPE '\'`, column, op, c.arg(pattern)), true
+	case OpNe:
+		return fmt.Sprintf("%s <> %s", column, literal(e.values[0])), true
+	}
+	return fmt.Sprintf("%s %s %s", column, e.op, literal(e.values[0])), true
+}
+
+// age compiles an ordering of an age field to a bound on its timestamp:
+// age_days < 14 holds for events after now minus 14 days.
+func (c *compiler) age(e *comparison) (string, bool) {
+	if math.Abs(e.values[0].num) > maxAgeDays {
+		return "", false
+	}
+	bound := c.now.Add(-time.Duration(e.values[0].num * float64(24*time.Hour)))
+	switch e.op {
+	case OpLt, OpLe:
+		return fmt.Sprintf("%s >= %s", e.field.column, c.arg(bound.Add(-ageSlack).UTC())), true
+	case OpGt, OpGe:
+		return fmt.Sprintf("%s <= %s", e.field.column, c.arg(bound.Add(ageSlack).UTC())), true
+	}
+	return "", false
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,99 @@
+package screen
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestPrefilter(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		expression string
+		sql        string
+		args       string
+	}{
+		{
+			expression: "ticker = AAPL",
+			sql:        "LOWER(COALESCE(c.ticker, '')) = $1",
+			args:       "[aapl]",
+		},
+		{
+			expression: "rating_to not in (Buy, Strong Buy) or brokerage_count > 2",
+			sql:        "(LOWER(COALESCE(s.rating_to, '')) NOT IN ($1, $2) OR CAST(c.covering_brokerages AS DOUBLE PRECISION) > $3)",
+			args:       "[buy strong buy 2]",
+		},
+		{
+			expression: "company contains '50%_off\\' and mean_target != 10",
+			sql:        `(LOWER(COALESCE(c.company, '')) LIKE $1 ESCAPE '\' AND c.mean_target <> $2)`,
+			args:       `[%50\%\_off\\% 10]`,
+		},
+		{
+			expression: "not (ticker = AAPL or company not contains apple)",
+			sql:        "NOT ((LOWER(COALESCE(c.ticker, '')) = $1 OR LOWER(COALESCE(c.company, '')) NOT LIKE $2 ESCAPE '\\'))",
+			args:       "[aapl %apple%]",
+		},
+		// Fields only known in memory drop out of an and
+		{
+			expression: "sector = Energy and target_change_pct > 10 and brokerage_count >= 3",
+			sql:        "CAST(c.covering_brokerages AS DOUBLE PRECISION) >= $1",
+			args:       "[3]",
+		},
+		// and the arguments of a part that did not compile are dropped
+		{
+			expression: "(ticker = AAPL or sector = Energy) and ticker = MSFT",
+			sql:        "LOWER(COALESCE(c.ticker, '')) = $1",
+			args:       "[msft]",
+		},
+		// Ages bound the timestamp, a second wider than the exact bound
+		{
+			expression: "age_days < 14 and age_days >= 1.5",
+			sql:        "(c.last_action_time >= $1 AND c.last_action_time <= $2)",
+			args:       "[2025-05-18 11:59:59 +0000 UTC 2025-05-31 00:00:01 +0000 UTC]",
+		},
+		{
+			expression: "target_currency in (EUR, GBP) and age_days < 14",
+			sql:        "(LOWER(COALESCE(c.target_currency, '')) IN ($1, $2) AND c.last_action_time >= $3)",
+			args:       "[eur gbp 2025-05-18 11:59:59 +0000 UTC]",
+		},
+	}
+	for _, tt := range tests {
+		screen, err := Parse(tt.expression)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.expression, err)
+		}
+		condition, ok := screen.Prefilter(now)
+		if !ok {
+			t.Errorf("Prefilter(%q) compiled nothing", tt.expression)
+			continue
+		}
+		if condition.SQL != tt.sql {
+			t.Errorf("Prefilter(%q) SQL\n got %s\nwant %s", tt.expression, condition.SQL, tt.sql)
+		}
+		if got := fmt.Sprint(condition.Args); got != tt.args {
+			t.Errorf("Prefilter(%q) args %s, want %s", tt.expression, got, tt.args)
+		}
+	}
+}
+
+func TestPrefilterLeavesToMatch(t *testing.T) {
+	for _, expression := range []string{
+		"sector = Energy",
+		"target_change_pct > 10 or ticker = AAPL",
+		// A negated age would need its NULLs kept
+		"not age_days < 14",
+		"age_days = 3",
+		"age_days < 1e9",
+		// LOWER only folds ASCII on SQLite
+		"company = 'Société Générale'",
+		"not (sector = Energy and ticker = AAPL)",
+	} {
+		screen, err := Parse(expression)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", expression, err)
+		}
+		if condition, ok := screen.Prefilter(time.Now()); ok {
+			t.Errorf("Prefilter(%q) = %s, want nothing compiled", expression, condition.SQL)
+		}
+	}
+}
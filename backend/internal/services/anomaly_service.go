@@ -0,0 +1,276 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository"
+)
+
+// AnomalySettings tune the detector. The latest Window of events is
+// compared with the BaselineWindows windows of the same length before it;
+// a count at least Threshold standard deviations away from their mean is
+// an anomaly when the window holds at least MinEvents events behind it.
+type AnomalySettings struct {
+	Window          time.Duration
+	BaselineWindows int
+	Threshold       float64
+	MinEvents       int
+}
+
+// activity counts the events of one ticker or sector per window; index 0 is
+// the latest window.
+type activity struct {
+	events  []int
+	bullish []int
+	bearish []int
+}
+
+// AnomalyService looks for bursts of analyst events and swings in their
+// direction, per ticker and per sector, and keeps the latest ticker swings
+// for scoring.
+type AnomalyService struct {
+	repo       *repository.AnomalyRepository
+	stocks     StockLister
+	securities *SecurityService
+	settings   AnomalySettings
+	now        func() time.Time
+
+	mu      sync.RWMutex
+	signals map[string]float64
+}
+
+// NewAnomalyService detects anomalies among the events of stocks; without
+// securities only tickers are checked. The window must be whole days.
+func NewAnomalyService(repo *repository.AnomalyRepository, stocks StockLister, securities *SecurityService, settings AnomalySettings) (*AnomalyService, error) {
+	if settings.Window <= 0 || settings.BaselineWindows < 2 {
+		return nil, fmt.Errorf("anomaly detection needs a positive window and at least 2 baseline windows")
+	}
+	// Windows end at a UTC midnight; a shorter one would end in the future
+	if settings.Window%(24*time.Hour) != 0 {
+		return nil, fmt.Errorf("anomaly window %s is not a whole number of days", settings.Window)
+	}
+	if settings.Threshold <= 0 || settings.MinEvents < 1 {
+		return nil, fmt.Errorf("anomaly threshold and minimum events must be positive")
+	}
+
+	return &AnomalyService{
+		repo:       repo,
+		stocks:     stocks,
+		securities: securities,
+		settings:   settings,
+		now:        time.Now,
+		signals:    make(map[string]float64),
+	}, nil
+}
+
+// Detect checks the latest window and stores what it finds in place of an
+// earlier run's findings for the same window. Windows end at the next UTC
+// midnight, so every run of a day looks at the same window.
+func (s *AnomalyService) Detect(ctx context.Context) ([]models.AnalystAnomaly, error) {
+	now := s.now().UTC()
+	end := now.Truncate(24 * time.Hour).Add(24 * time.Hour)
+	windows := s.settings.BaselineWindows + 1
+	start := end.Add(-time.Duration(windows) * s.settings.Window)
+
+	tickers := map[string]*activity{}
+	sectors := map[string]*activity{}
+	err := eachStockPageSince(ctx, s.stocks, start, func(page []models.Stock) error {
+		for i := range page {
+			stock := &page[i]
+			index := int(end.Sub(stock.Time) / s.settings.Window)
+			if !stock.Time.Before(end) || index >= windows {
+				continue
+			}
+			direction := eventDirection(stock.Action)
+			record(tickers, stock.Ticker, index, direction, windows)
+			if s.securities != nil {
+				if security, ok := s.securities.Lookup(stock.Ticker); ok && security.Sector != "" {
+					record(sectors, security.Sector, index, direction, windows)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	anomalies := []models.AnalystAnomaly{}
+	for _, scope := range []struct {
+		name     string
+		subjects map[string]*activity
+	}{{models.AnomalyScopeTicker, tickers}, {models.AnomalyScopeSector, sectors}} {
+		for subject, counts := range scope.subjects {
+			for _, anomaly := range s.check(counts) {
+				anomaly.Scope, anomaly.Subject = scope.name, subject
+				anomaly.WindowStart, anomaly.WindowEnd = end.Add(-s.settings.Window), end
+				anomaly.DetectedAt = now
+				anomalies = append(anomalies, anomaly)
+			}
+		}
+	}
+	sort.Slice(anomalies, func(i, j int) bool {
+		if math.Abs(anomalies[i].ZScore) == math.Abs(anomalies[j].ZScore) {
+			return anomalies[i].Scope+anomalies[i].Subject+anomalies[i].Kind < anomalies[j].Scope+anomalies[j].Subject+anomalies[j].Kind
+		}
+		return math.Abs(anomalies[i].ZScore) > math.Abs(anomalies[j].ZScore)
+	})
+
+	if err := s.repo.ReplaceWindow(ctx, end, anomalies); err != nil {
+		return nil, err
+	}
+
+	signals := make(map[string]float64)
+	for _, anomaly := range anomalies {
+		if anomaly.Scope == models.AnomalyScopeTicker && anomaly.Kind == models.AnomalyKindDirection {
+			signals[anomaly.Subject] = anomaly.ZScore
+		}
+	}
+	s.mu.Lock()
+	s.signals = signals
+	s.mu.Unlock()
+	return anomalies, nil
+}
+
+func record(subjects map[string]*activity, subject string, index, direction, windows int) {
+	counts, ok := subjects[subject]
+	if !ok {
+		counts = &activity{events: make([]int, windows), bullish: make([]int, windows), bearish: make([]int, windows)}
+		subjects[subject] = counts
+	}
+	counts.events[index]++
+	switch direction {
+	case 1:
+		counts.bullish[index]++
+	case -1:
+		counts.bearish[index]++
+	}
+}
+
+// check compares the latest window of counts with the baseline windows.
+func (s *AnomalyService) check(counts *activity) []models.AnalystAnomaly {
+	var anomalies []models.AnalystAnomaly
+	latest := models.AnalystAnomaly{
+		Events:  counts.events[0],
+		Bullish: counts.bullish[0],
+		Bearish: counts.bearish[0],
+	}
+
+	// A burst of events, whichever way they point
+	volume := make([]float64, len(counts.events))
+	for i, n := range counts.events {
+		volume[i] = float64(n)
+	}
+	if anomaly := s.score(latest, volume); anomaly.ZScore >= s.settings.Threshold && latest.Events >= s.settings.MinEvents {
+		anomaly.Kind = models.AnomalyKindVolume
+		switch {
+		case latest.Bullish > latest.Bearish:
+			anomaly.Direction = models.DirectionBullish
+		case latest.Bearish > latest.Bullish:
+			anomaly.Direction = models.DirectionBearish
+		default:
+			anomaly.Direction = models.DirectionMixed
+		}
+		anomalies = append(anomalies, anomaly)
+	}
+
+	// A swing in bullish minus bearish events
+	net := make([]float64, len(counts.events))
+	for i := range net {
+		net[i] = float64(counts.bullish[i] - counts.bearish[i])
+	}
+	anomaly := s.score(latest, net)
+	switch {
+	case anomaly.ZScore >= s.settings.Threshold && latest.Bullish >= s.settings.MinEvents:
+		anomaly.Direction = models.DirectionBullish
+	case anomaly.ZScore <= -s.settings.Threshold && latest.Bearish >= s.settings.MinEvents:
+		anomaly.Direction = models.DirectionBearish
+	default:
+		return anomalies
+	}
+	anomaly.Kind = models.AnomalyKindDirection
+	return append(anomalies, anomaly)
+}
+
+// score sets the z-score of values[0] against the rest of values. The
+// standard deviation is floored at one event: a quiet baseline has none, and
+// a couple of events on a name that never moves should not score infinity.
+func (s *AnomalyService) score(anomaly models.AnalystAnomaly, values []float64) models.AnalystAnomaly {
+	baseline := values[1:]
+	var sum float64
+	for _, value := range baseline {
+		sum += value
+	}
+	mean := sum / float64(len(baseline))
+	var squares float64
+	for _, value := range baseline {
+		squares += (value - mean) * (value - mean)
+	}
+	stddev := math.Sqrt(squares / float64(len(baseline)))
+
+	anomaly.Observed = values[0]
+	anomaly.BaselineMean = mean
+	anomaly.BaselineStdDev = stddev
+	anomaly.ZScore = (values[0] - mean) / math.Max(stddev, 1)
+	return anomaly
+}
+
+// eventDirection is 1 for upgrades and target raises, -1 for downgrades and
+// target cuts and 0 for anything else.
+func eventDirection(action string) int {
+	switch models.ActionClass(action) {
+	case models.ActionUpgrade, models.ActionTargetRaised:
+		return 1
+	case models.ActionDowngrade, models.ActionTargetLowered:
+		return -1
+	}
+	return 0
+}
+
+// Signals returns the z-score of every ticker whose latest direction swing
+// was flagged, positive for bullish swings.
+func (s *AnomalyService) Signals() map[string]float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	signals := make(map[string]float64, len(s.signals))
+	for ticker, z := range s.signals {
+		signals[ticker] = z
+	}
+	return signals
+}
+
+// List returns stored anomalies, latest windows and strongest first.
+func (s *AnomalyService) List(ctx context.Context, filter repository.AnomalyFilter, limit, offset int) ([]models.AnalystAnomaly, error) {
+	return s.repo.ListAnomalies(ctx, filter, limit, offset)
+}
+
+// StartSchedule detects anomalies every interval until ctx is cancelled.
+func (s *AnomalyService) StartSchedule(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := s.Detect(ctx); err != nil {
+					log.Printf("Anomaly detection error: %v", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
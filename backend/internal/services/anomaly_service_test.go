@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository"
+)
+
+var anomalySettings = AnomalySettings{Window: 24 * time.Hour, BaselineWindows: 4, Threshold: 2, MinEvents: 3}
+
+func TestNewAnomalyServiceRejectsSettings(t *testing.T) {
+	tests := map[string]AnomalySettings{
+		"no window":           {BaselineWindows: 4, Threshold: 2, MinEvents: 3},
+		"window under a day":  {Window: 12 * time.Hour, BaselineWindows: 4, Threshold: 2, MinEvents: 3},
+		"part of a day":       {Window: 36 * time.Hour, BaselineWindows: 4, Threshold: 2, MinEvents: 3},
+		"one baseline window": {Window: 24 * time.Hour, BaselineWindows: 1, Threshold: 2, MinEvents: 3},
+		"no threshold":        {Window: 24 * time.Hour, BaselineWindows: 4, MinEvents: 3},
+		"no minimum events":   {Window: 24 * time.Hour, BaselineWindows: 4, Threshold: 2},
+	}
+	for name, settings := range tests {
+		if _, err := NewAnomalyService(nil, nil, nil, settings); err == nil {
+			t.Errorf("%s: settings %+v accepted", name, settings)
+		}
+	}
+	if _, err := NewAnomalyService(nil, nil, nil, AnomalySettings{Window: 7 * 24 * time.Hour, BaselineWindows: 12, Threshold: 3, MinEvents: 3}); err != nil {
+		t.Errorf("default settings rejected: %v", err)
+	}
+}
+
+func TestAnomalyScore(t *testing.T) {
+	service := &AnomalyService{settings: anomalySettings}
+	tests := []struct {
+		name         string
+		values       []float64
+		mean, stddev float64
+		z            float64
+	}{
+		{"against a varied baseline", []float64{10, 2, 4, 2, 4}, 3, 1, 7},
+		{"a quiet baseline floors the deviation at one event", []float64{3, 0, 0, 0, 0}, 0, 0, 3},
+		{"below the baseline", []float64{-4, 2, 0, 2, 0}, 1, 1, -5},
+		{"a wide baseline", []float64{6, 0, 4, 0, 4}, 2, 2, 2},
+	}
+	for _, tt := range tests {
+		got := service.score(models.AnalystAnomaly{Events: 7}, tt.values)
+		if got.Observed != tt.values[0] || got.BaselineMean != tt.mean || got.BaselineStdDev != tt.stddev || got.ZScore != tt.z {
+			t.Errorf("%s: observed %v, mean %v, stddev %v, z %v; want %v, %v, %v, %v", tt.name,
+				got.Observed, got.BaselineMean, got.BaselineStdDev, got.ZScore, tt.values[0], tt.mean, tt.stddev, tt.z)
+		}
+		if got.Events != 7 {
+			t.Errorf("%s: score dropped the window's counts", tt.name)
+		}
+	}
+}
+
+func TestAnomalyCheck(t *testing.T) {
+	service := &AnomalyService{settings: anomalySettings}
+	tests := []struct {
+		name    string
+		counts  activity
+		flagged string
+	}{
+		{
+			name:    "bullish burst",
+			counts:  activity{events: []int{6, 1, 1, 1, 1}, bullish: []int{5, 0, 0, 0, 0}, bearish: []int{0, 0, 0, 0, 0}},
+			flagged: "volume bullish z=5.0; direction bullish z=5.0",
+		},
+		{
+			name:    "bearish burst",
+			counts:  activity{events: []int{5, 1, 1, 1, 1}, bullish: []int{0, 1, 0, 1, 0}, bearish: []int{4, 0, 0, 0, 0}},
+			flagged: "volume bearish z=4.0; direction bearish z=-4.5",
+		},
+		{
+			name:    "a mixed burst moves no direction",
+			counts:  activity{events: []int{8, 1, 1, 1, 1}, bullish: []int{4, 0, 0, 0, 0}, bearish: []int{4, 0, 0, 0, 0}},
+			flagged: "volume mixed z=7.0",
+		},
+		{
+			name:   "too few events to flag",
+			counts: activity{events: []int{2, 0, 0, 0, 0}, bullish: []int{2, 0, 0, 0, 0}, bearish: []int{0, 0, 0, 0, 0}},
+		},
+		{
+			name:   "as busy as usual",
+			counts: activity{events: []int{5, 4, 6, 5, 5}, bullish: []int{3, 2, 4, 3, 3}, bearish: []int{1, 1, 1, 1, 1}},
+		},
+	}
+	for _, tt := range tests {
+		var flagged []string
+		for _, anomaly := range service.check(&tt.counts) {
+			flagged = append(flagged, fmt.Sprintf("%s %s z=%.1f", anomaly.Kind, anomaly.Direction, anomaly.ZScore))
+			if anomaly.Events != tt.counts.events[0] || anomaly.Bullish != tt.counts.bullish[0] || anomaly.Bearish != tt.counts.bearish[0] {
+				t.Errorf("%s: %s anomaly counts %d/%d/%d, want the latest window's", tt.name, anomaly.Kind, anomaly.Events, anomaly.Bullish, anomaly.Bearish)
+			}
+		}
+		if got := strings.Join(flagged, "; "); got != tt.flagged {
+			t.Errorf("%s: flagged %q, want %q", tt.name, got, tt.flagged)
+		}
+	}
+}
+
+func TestDetectCountsTheDaySoFar(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 6, 10, 15, 0, 0, 0, time.UTC)
+	store := repository.NewMemoryStockStore()
+	event := func(id, action string, at time.Time) {
+		stock := &models.Stock{ID: id, Ticker: "AAA", Company: "Alpha", Brokerage: "Broker " + id, Action: action, Time: at}
+		if err := store.Create(ctx, stock, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// One quiet event on each of the four days before, five upgrades today
+	for day := 1; day <= 4; day++ {
+		event(fmt.Sprintf("quiet-%d", day), "reiterated by", now.AddDate(0, 0, -day))
+	}
+	for i := 0; i < 5; i++ {
+		event(fmt.Sprintf("up-%d", i), "upgraded by", now.Add(-time.Duration(i+1)*time.Hour))
+	}
+
+	service, err := NewAnomalyService(repository.NewAnomalyRepository(newMigratedDatabase(t)), NewStockService(store, "", "", models.SyncWriteAtomic), nil, anomalySettings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.now = func() time.Time { return now }
+
+	anomalies, err := service.Detect(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anomalies) != 2 {
+		t.Fatalf("detected %+v, want a volume and a direction anomaly", anomalies)
+	}
+	wantStart, wantEnd := time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC), time.Date(2025, 6, 11, 0, 0, 0, 0, time.UTC)
+	for _, anomaly := range anomalies {
+		if !anomaly.WindowStart.Equal(wantStart) || !anomaly.WindowEnd.Equal(wantEnd) {
+			t.Errorf("%s window %s to %s, want %s to %s", anomaly.Kind, anomaly.WindowStart, anomaly.WindowEnd, wantStart, wantEnd)
+		}
+		if anomaly.Events != 5 || anomaly.Direction != models.DirectionBullish {
+			t.Errorf("%s anomaly has %d events, %s; want 5, bullish", anomaly.Kind, anomaly.Events, anomaly.Direction)
+		}
+	}
+	if z := service.Signals()["AAA"]; math.Abs(z-5) > 1e-9 {
+		t.Errorf("AAA signal %v, want 5", z)
+	}
+}
@@ -0,0 +1,395 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+)
+
+var (
+	// ErrInvalidBacktest wraps every validation failure of a backtest request.
+	ErrInvalidBacktest = errors.New("invalid backtest")
+	// ErrNoPriceHistory is returned when no price file was loaded.
+	ErrNoPriceHistory = errors.New("no price history loaded; set PRICE_HISTORY_PATH")
+	// ErrBacktestNotFound is returned for an unknown job id.
+	ErrBacktestNotFound = errors.New("backtest not found")
+)
+
+// maxBacktestJobs bounds how many finished jobs are kept in memory.
+const maxBacktestJobs = 100
+
+// maxBacktestPeriods bounds how many rankings one backtest replays, ten
+// years of weekly rankings.
+const maxBacktestPeriods = 520
+
+// BacktestService replays the stored analyst events, with the values their
+// revisions show they had at the time, to rank tickers as a strategy would
+// have on past dates, and measures the rankings against a daily price file.
+type BacktestService struct {
+	stocks     StockHistory
+	strategies *StrategyRegistry
+	profiles   *ScoringProfileStore
+	prices     *PriceHistory
+
+	mu   sync.Mutex
+	jobs map[string]*models.BacktestJob
+	ids  []string
+}
+
+// NewBacktestService creates the service; prices may be nil, in which case
+// every backtest fails with ErrNoPriceHistory.
+func NewBacktestService(stocks StockHistory, strategies *StrategyRegistry, profiles *ScoringProfileStore, prices *PriceHistory) *BacktestService {
+	return &BacktestService{
+		stocks:     stocks,
+		strategies: strategies,
+		profiles:   profiles,
+		prices:     prices,
+		jobs:       make(map[string]*models.BacktestJob),
+	}
+}
+
+// Start validates req and runs the backtest in the background.
+func (s *BacktestService) Start(req models.BacktestRequest) (*models.BacktestJob, error) {
+	strategy, err := s.prepare(&req)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &models.BacktestJob{
+		ID:        newJobID(),
+		Status:    models.BacktestRunning,
+		Request:   req,
+		StartedAt: time.Now().UTC(),
+	}
+	s.mu.Lock()
+	s.jobs[job.ID] = job
+	s.ids = append(s.ids, job.ID)
+	s.trimJobs()
+	s.mu.Unlock()
+
+	// The job outlives the request that started it
+	go func() {
+		result, err := s.run(context.Background(), req, strategy)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		completedAt := time.Now().UTC()
+		job.CompletedAt = &completedAt
+		if err != nil {
+			log.Printf("Backtest %s failed: %v", job.ID, err)
+			job.Status = models.BacktestFailed
+			job.Error = err.Error()
+			return
+		}
+		job.Status = models.BacktestCompleted
+		job.Result = result
+	}()
+
+	return s.copyJob(job), nil
+}
+
+// GetJob returns a snapshot of a backtest job.
+func (s *BacktestService) GetJob(id string) (*models.BacktestJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrBacktestNotFound
+	}
+	return s.copyJob(job), nil
+}
+
+// Run executes a backtest synchronously.
+func (s *BacktestService) Run(ctx context.Context, req models.BacktestRequest) (*models.BacktestResult, error) {
+	strategy, err := s.prepare(&req)
+	if err != nil {
+		return nil, err
+	}
+	return s.run(ctx, req, strategy)
+}
+
+// prepare fills in defaults and validates req: weekly rankings of the top
+// 10 held for 30 days, over the year whose last picks are sold on the day
+// of the last price.
+func (s *BacktestService) prepare(req *models.BacktestRequest) (ScoringStrategy, error) {
+	if s.prices == nil {
+		return nil, ErrNoPriceHistory
+	}
+
+	if req.Strategy == "" {
+		req.Strategy = DefaultStrategyName
+	}
+	if req.StepDays == 0 {
+		req.StepDays = 7
+	}
+	if req.HorizonDays == 0 {
+		req.HorizonDays = 30
+	}
+	if req.TopN == 0 {
+		req.TopN = 10
+	}
+	if req.To.IsZero() {
+		req.To = s.prices.LastDate().AddDate(0, 0, -req.HorizonDays-1)
+	}
+	if req.From.IsZero() {
+		req.From = req.To.AddDate(-1, 0, 0)
+	}
+	req.From, req.To = truncateDay(req.From), truncateDay(req.To)
+
+	strategy, err := s.strategies.Get(req.Strategy)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case req.StepDays < 0 || req.HorizonDays < 0 || req.TopN < 0:
+		return nil, fmt.Errorf("%w: step_days, horizon_days and top_n must be positive", ErrInvalidBacktest)
+	case req.To.Before(req.From):
+		return nil, fmt.Errorf("%w: from must not be after to", ErrInvalidBacktest)
+	case int(req.To.Sub(req.From).Hours()/24)/req.StepDays+1 > maxBacktestPeriods:
+		return nil, fmt.Errorf("%w: more than %d rankings between from and to; use a larger step_days", ErrInvalidBacktest, maxBacktestPeriods)
+	case req.Benchmark != "" && !s.hasPrices(req.Benchmark):
+		return nil, fmt.Errorf("%w: no prices for benchmark %q", ErrInvalidBacktest, req.Benchmark)
+	}
+	return strategy, nil
+}
+
+func (s *BacktestService) run(ctx context.Context, req models.BacktestRequest, strategy ScoringStrategy) (*models.BacktestResult, error) {
+	versions, err := s.loadVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Credibility is learned from today's prices, so it would leak the
+	// future into the past; every brokerage weighs the same here
+	profile := s.profiles.Active()
+	horizon := time.Duration(req.HorizonDays) * 24 * time.Hour
+	result := &models.BacktestResult{
+		BacktestRequest: req,
+		StrategyVersion: strategy.Version(),
+		ProfileVersion:  profile.Version,
+	}
+
+	tickers := make(map[string]*models.TickerConsensus)
+	// current holds the replayed values of every known event, by ticker and id
+	current := make(map[string]map[string]*models.Stock)
+	next := 0
+	var pickSum, benchedSum, benchSum float64
+	var picks, hits, beats, picksWithBench int
+
+	for date := req.From; !date.After(req.To); date = date.AddDate(0, 0, req.StepDays) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		// Fold in every event and revision known by the end of the day
+		known := date.Add(24 * time.Hour)
+		for ; next < len(versions) && versions[next].at.Before(known); next++ {
+			stock := &versions[next].stock
+			events, ok := current[stock.Ticker]
+			if !ok {
+				events = make(map[string]*models.Stock)
+				current[stock.Ticker] = events
+				tickers[stock.Ticker] = &models.TickerConsensus{}
+			}
+			events[stock.ID] = stock
+
+			consensus := tickers[stock.Ticker]
+			if !consensus.Apply(stock) {
+				// A revision moved the event to another brokerage
+				consensus = models.NewTickerConsensus(replayedEvents(events))
+				tickers[stock.Ticker] = consensus
+			}
+			consensus.Latest = events[consensus.LatestStockID]
+		}
+
+		snapshot := make([]models.TickerConsensus, 0, len(tickers))
+		for _, consensus := range tickers {
+			snapshot = append(snapshot, *consensus)
+		}
+		params := ScoringParams{Profile: profile, Now: known}
+		ranking := rankTickers(snapshot, strategy, params, models.SideBuy, req.TopN)
+
+		// The ranking uses the whole day's events, so its picks can only be
+		// bought at the next close
+		period := models.BacktestPeriod{Date: date, BenchmarkReturn: s.benchmarkReturn(req.Benchmark, known, horizon)}
+		var periodSum float64
+		var periodPicks int
+		for _, recommendation := range ranking {
+			pick := models.BacktestPick{Ticker: recommendation.Stock.Ticker, Score: recommendation.Score}
+			if forward, ok := s.prices.ForwardReturn(pick.Ticker, known, horizon); ok {
+				pick.Return = &forward
+				periodSum += forward
+				periodPicks++
+				if forward > 0 {
+					hits++
+				}
+				if period.BenchmarkReturn != nil {
+					picksWithBench++
+					benchedSum += forward
+					benchSum += *period.BenchmarkReturn
+					if forward > *period.BenchmarkReturn {
+						beats++
+					}
+				}
+			}
+			period.Picks = append(period.Picks, pick)
+		}
+		period.AverageReturn = ratio(periodSum, periodPicks)
+		pickSum += periodSum
+		picks += periodPicks
+		result.Periods = append(result.Periods, period)
+	}
+
+	// The benchmark is averaged over the same holding periods as the picks
+	result.Summary = models.BacktestSummary{
+		Periods:                len(result.Periods),
+		Picks:                  picks,
+		Hits:                   hits,
+		HitRate:                ratio(float64(hits), picks),
+		BeatRate:               ratio(float64(beats), picksWithBench),
+		AverageReturn:          ratio(pickSum, picks),
+		BenchmarkAverageReturn: ratio(benchSum, picksWithBench),
+		ExcessReturn:           ratio(benchedSum-benchSum, picksWithBench),
+	}
+	return result, nil
+}
+
+// eventVersion is the values an event had from a point of the replay on.
+type eventVersion struct {
+	at    time.Time
+	stock models.Stock
+}
+
+// loadVersions reads every stored event and its revisions as the versions
+// the replay folds in, oldest first: each event with the values it was
+// first stored with, at its own time, then the values of each revision,
+// when it was recorded. Stored rows only hold the latest values, so without
+// revisions a replay would score past dates with corrections made later.
+func (s *BacktestService) loadVersions(ctx context.Context) ([]eventVersion, error) {
+	var events []models.Stock
+	err := eachStockPage(ctx, s.stocks, func(page []models.Stock) error {
+		events = append(events, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := make(map[string][]models.StockRevision)
+	err = eachRevisionPage(ctx, s.stocks, func(page []models.StockRevision) error {
+		for _, revision := range page {
+			revisions[revision.StockID] = append(revisions[revision.StockID], revision)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]eventVersion, 0, len(events))
+	for i := range events {
+		event := &events[i]
+		changes := revisions[event.ID]
+
+		first := *event
+		if len(changes) > 0 {
+			first = event.WithValues(changes[0].Old)
+		}
+		versions = append(versions, eventVersion{at: event.Time, stock: first})
+		for _, change := range changes {
+			// A change cannot be known before the event itself
+			at := change.ChangedAt
+			if at.Before(event.Time) {
+				at = event.Time
+			}
+			versions = append(versions, eventVersion{at: at, stock: event.WithValues(change.New)})
+		}
+	}
+
+	// Stable, so an event stays ahead of its own revisions and events keep
+	// their (time, id) order
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].at.Before(versions[j].at)
+	})
+	return versions, nil
+}
+
+// replayedEvents lists the current values of a ticker's replayed events.
+func replayedEvents(events map[string]*models.Stock) []models.Stock {
+	stocks := make([]models.Stock, 0, len(events))
+	for _, stock := range events {
+		stocks = append(stocks, *stock)
+	}
+	return stocks
+}
+
+// benchmarkReturn is the forward return of the benchmark ticker, or of an
+// equal-weight basket of every priced ticker when there is none.
+func (s *BacktestService) benchmarkReturn(benchmark string, date time.Time, horizon time.Duration) *float64 {
+	if benchmark != "" {
+		if forward, ok := s.prices.ForwardReturn(benchmark, date, horizon); ok {
+			return &forward
+		}
+		return nil
+	}
+
+	var sum float64
+	var count int
+	for _, ticker := range s.prices.Tickers() {
+		if forward, ok := s.prices.ForwardReturn(ticker, date, horizon); ok {
+			sum += forward
+			count++
+		}
+	}
+	return ratio(sum, count)
+}
+
+func (s *BacktestService) hasPrices(ticker string) bool {
+	_, _, ok := s.prices.CloseOnOrBefore(ticker, s.prices.LastDate())
+	return ok
+}
+
+// trimJobs forgets the oldest finished jobs beyond maxBacktestJobs. The
+// caller holds s.mu.
+func (s *BacktestService) trimJobs() {
+	for i := 0; len(s.ids) > maxBacktestJobs && i < len(s.ids); {
+		if s.jobs[s.ids[i]].Status == models.BacktestRunning {
+			i++
+			continue
+		}
+		delete(s.jobs, s.ids[i])
+		s.ids = append(s.ids[:i], s.ids[i+1:]...)
+	}
+}
+
+// copyJob snapshots a job so callers never see it change. The caller holds
+// s.mu.
+func (s *BacktestService) copyJob(job *models.BacktestJob) *models.BacktestJob {
+	jobCopy := *job
+	return &jobCopy
+}
+
+func ratio(sum float64, count int) *float64 {
+	if count == 0 {
+		return nil
+	}
+	value := sum / float64(count)
+	return &value
+}
+
+func newJobID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("error generating id: %v", err))
+	}
+	return hex.EncodeToString(b[:])
+}
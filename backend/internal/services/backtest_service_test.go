@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository"
+)
+
+func newTestPriceHistory(t *testing.T, from time.Time, days int, tickers ...string) *PriceHistory {
+	t.Helper()
+	var csv strings.Builder
+	csv.WriteString("date,ticker,close\n")
+	for day := 0; day < days; day++ {
+		for i, ticker := range tickers {
+			fmt.Fprintf(&csv, "%s,%s,%d\n", from.AddDate(0, 0, day).Format("2006-01-02"), ticker, 100+day*(i+1))
+		}
+	}
+	prices, err := ParsePriceHistory(strings.NewReader(csv.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return prices
+}
+
+func newTestBacktestService(t *testing.T, store repository.StockStore, prices *PriceHistory) *BacktestService {
+	t.Helper()
+	strategies, err := NewStrategyRegistry(NewDefaultStrategy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	profiles, err := NewScoringProfileStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewBacktestService(NewStockService(store, "", "", models.SyncWriteAtomic), strategies, profiles, prices)
+}
+
+// TestBacktestReplaysRevisions revises a bullish call into a bearish one a
+// month after it was made: dates before the revision must rank it on its
+// original values, not on the correction stored in the row today.
+func TestBacktestReplaysRevisions(t *testing.T) {
+	ctx := context.Background()
+	day := func(month time.Month, d int) time.Time { return time.Date(2025, month, d, 0, 0, 0, 0, time.UTC) }
+
+	store := repository.NewMemoryStockStore()
+	bullish := models.Stock{
+		ID: "aaa-1", Ticker: "AAA", Company: "Alpha", Brokerage: "Example Securities",
+		Action: "upgraded by", RatingFrom: "Hold", RatingTo: "Buy", TargetFrom: "$10.00", TargetTo: "$15.00",
+		Time: day(time.January, 10).Add(14 * time.Hour), LastUpdated: day(time.January, 10).Add(15 * time.Hour),
+	}
+	steady := models.Stock{
+		ID: "bbb-1", Ticker: "BBB", Company: "Beta", Brokerage: "Example Securities",
+		Action: "reiterated by", RatingFrom: "Buy", RatingTo: "Buy", TargetFrom: "$10.00", TargetTo: "$10.00",
+		Time: day(time.January, 10).Add(14 * time.Hour), LastUpdated: day(time.January, 10).Add(15 * time.Hour),
+	}
+	for _, stock := range []models.Stock{bullish, steady} {
+		if err := store.Create(ctx, &stock, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	revised := bullish
+	revised.Action, revised.RatingFrom, revised.RatingTo, revised.TargetTo = "downgraded by", "Buy", "Sell", "$6.00"
+	revised.LastUpdated = day(time.February, 10).Add(9 * time.Hour)
+	if err := store.Create(ctx, &revised, "run-2"); err != nil {
+		t.Fatal(err)
+	}
+
+	service := newTestBacktestService(t, store, newTestPriceHistory(t, day(time.January, 1), 120, "AAA", "BBB"))
+
+	result, err := service.Run(ctx, models.BacktestRequest{
+		From: day(time.January, 15), To: day(time.February, 19), StepDays: 7, HorizonDays: 10, TopN: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var picks []string
+	for _, period := range result.Periods {
+		for _, pick := range period.Picks {
+			picks = append(picks, period.Date.Format("01-02")+" "+pick.Ticker)
+		}
+	}
+	want := []string{"01-15 AAA", "01-22 AAA", "01-29 AAA", "02-05 AAA", "02-12 BBB", "02-19 BBB"}
+	if strings.Join(picks, ", ") != strings.Join(want, ", ") {
+		t.Errorf("picks %v, want %v", picks, want)
+	}
+}
+
+// An event of the afternoon is ranked that day, but its pick is only bought
+// at the next close: the jump at the day's own close is not earned.
+func TestBacktestBuysAtTheNextClose(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryStockStore()
+	upgrade := &models.Stock{
+		ID: "aaa-1", Ticker: "AAA", Company: "Alpha", Brokerage: "Example Securities",
+		Action: "upgraded by", RatingFrom: "Hold", RatingTo: "Buy",
+		Time: time.Date(2025, 1, 10, 14, 0, 0, 0, time.UTC),
+	}
+	if err := store.Create(ctx, upgrade, ""); err != nil {
+		t.Fatal(err)
+	}
+	prices, err := ParsePriceHistory(strings.NewReader("date,ticker,close\n" +
+		"2025-01-09,AAA,100\n2025-01-10,AAA,150\n2025-01-11,AAA,160\n2025-01-15,AAA,176\n2025-01-16,AAA,200\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	result, err := newTestBacktestService(t, store, prices).Run(ctx, models.BacktestRequest{
+		From: day, To: day, StepDays: 1, HorizonDays: 4, TopN: 1, Benchmark: "AAA",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	picks := result.Periods[0].Picks
+	if len(picks) != 1 || picks[0].Return == nil {
+		t.Fatalf("picks %+v, want AAA with a return", picks)
+	}
+	// Bought at 160 on the 11th, sold at 176 four days later
+	if got := *picks[0].Return; math.Abs(got-0.1) > 1e-9 {
+		t.Errorf("return %v, want 0.1 from the next close", got)
+	}
+	if got := *result.Periods[0].BenchmarkReturn; math.Abs(got-0.1) > 1e-9 {
+		t.Errorf("benchmark return %v, want the same holding period", got)
+	}
+}
+
+func TestBacktestRejectsTooManyPeriods(t *testing.T) {
+	service := newTestBacktestService(t, repository.NewMemoryStockStore(),
+		newTestPriceHistory(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), 10, "AAA"))
+	from := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// Ten years of weekly rankings fit, one more week does not
+	limit := from.AddDate(0, 0, 7*(maxBacktestPeriods-1))
+	if _, err := service.prepare(&models.BacktestRequest{From: from, To: limit, StepDays: 7}); err != nil {
+		t.Errorf("%d weekly rankings rejected: %v", maxBacktestPeriods, err)
+	}
+	for _, req := range []models.BacktestRequest{
+		{From: from, To: limit.AddDate(0, 0, 7), StepDays: 7},
+		{From: from, To: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), StepDays: 1},
+	} {
+		if _, err := service.prepare(&req); !errors.Is(err, ErrInvalidBacktest) {
+			t.Errorf("%s to %s every %d days: %v, want ErrInvalidBacktest", req.From.Format("2006-01-02"), req.To.Format("2006-01-02"), req.StepDays, err)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+)
+
+// ConsensusStrategyName selects the multi-brokerage strategy.
+const ConsensusStrategyName = "consensus"
+
+// consensusStrategy scores a ticker from the latest view of every brokerage
+// covering it, so a single analyst cannot outvote the rest: net rating
+// revisions in the window, how many brokerages are bullish, and the change
+// of the mean target. Each view counts as much as its brokerage's
+// credibility.
+type consensusStrategy struct{}
+
+func NewConsensusStrategy() ScoringStrategy {
+	return &consensusStrategy{}
+}
+
+func (s *consensusStrategy) Name() string    { return ConsensusStrategyName }
+func (s *consensusStrategy) Version() string { return "6" }
+
+func (s *consensusStrategy) Score(consensus *models.TickerConsensus, params ScoringParams) ScoreResult {
+	weights := params.Profile.Consensus
+
+	// Views past the maximum age no longer count as coverage
+	var views []models.BrokerageRating
+	for _, view := range consensus.Brokerages {
+		if !params.Expired(view.Time) {
+			views = append(views, view)
+		}
+	}
+	if len(views) == 0 {
+		return newScoreResult(nil, "no recent analyst views")
+	}
+	if len(views) < weights.MinBrokerages {
+		return newScoreResult(nil, fmt.Sprintf("covered by %d of the %d brokerages required", len(views), weights.MinBrokerages))
+	}
+
+	var factors []models.ScoreFactor
+
+	// Factor 1: net upgrades minus downgrades among views in the window
+	var upgrades, downgrades int
+	var revisions, windowViews float64
+	for _, view := range views {
+		if !s.inWindow(view.Time, params) {
+			continue
+		}
+		weight := params.Weight(view.Brokerage)
+		windowViews += weight
+		direction := ratingRevision(view.RatingFrom, view.Rating, view.Action, &params.Profile.Rating)
+		if direction > 0 {
+			upgrades++
+		} else if direction < 0 {
+			downgrades++
+		}
+		revisions += float64(direction) * weight * params.Decay(view.Time, params.Profile.Decay.RatingHalfLifeDays)
+	}
+	revisionFactor := models.ScoreFactor{
+		Name:   "net_revisions",
+		Label:  "no net revisions",
+		Input:  fmt.Sprintf("%d upgrades, %d downgrades", upgrades, downgrades),
+		Weight: weights.Revisions,
+		Cap:    capOf(weights.Revisions),
+	}
+	if revisions != 0 && windowViews > 0 {
+		revisionFactor.Points = revisions / windowViews * weights.Revisions
+		revisionFactor.Label = fmt.Sprintf("%d upgrades vs %d downgrades", upgrades, downgrades)
+	}
+	factors = append(factors, revisionFactor)
+
+	// Factor 2: share of rated brokerages with a bullish rating, less the
+	// share with a bearish one
+	var rated, bullish, bearish int
+	var ratedWeight, stanceWeight float64
+	for _, view := range views {
+		level, known := params.Profile.Rating.Scale[view.Rating]
+		if !known {
+			continue
+		}
+		weight := params.Weight(view.Brokerage)
+		rated++
+		ratedWeight += weight
+		if level >= params.Profile.Rating.StrongLevel {
+			bullish++
+			stanceWeight += weight
+		} else if level <= params.Profile.Rating.WeakLevel {
+			bearish++
+			stanceWeight -= weight
+		}
+	}
+	agreement := models.ScoreFactor{
+		Name:   "agreement",
+		Label:  fmt.Sprintf("%d of %d brokerages bullish", bullish, rated),
+		Input:  fmt.Sprintf("%d bullish, %d bearish of %d", bullish, bearish, rated),
+		Weight: weights.Agreement,
+		Cap:    capOf(weights.Agreement),
+	}
+	if bearish > bullish {
+		agreement.Label = fmt.Sprintf("%d of %d brokerages bearish", bearish, rated)
+	}
+	if stanceWeight != 0 {
+		agreement.Points = stanceWeight / ratedWeight * weights.Agreement
+	}
+	factors = append(factors, agreement)
+
+	// Factor 3: change of the mean target across views in the window, in
+	// the currency of the consensus targets
+	var fromSum, toSum, decaySum, weightSum float64
+	for _, view := range views {
+		if !s.inWindow(view.Time, params) {
+			continue
+		}
+		from, fromCurrency, err := models.ParseTarget(view.TargetFrom)
+		if err != nil || fromCurrency != consensus.TargetCurrency {
+			continue
+		}
+		to, toCurrency, err := models.ParseTarget(view.Target)
+		if err != nil || toCurrency != consensus.TargetCurrency {
+			continue
+		}
+		weight := params.Weight(view.Brokerage)
+		fromSum += from * weight
+		toSum += to * weight
+		decaySum += params.Decay(view.Time, params.Profile.Decay.TargetHalfLifeDays) * weight
+		weightSum += weight
+	}
+	var changePerc float64
+	if fromSum > 0 {
+		changePerc = (toSum - fromSum) / fromSum * 100
+	}
+	target := models.ScoreFactor{
+		Name:   "mean_target_change",
+		Label:  "mean target unchanged",
+		Input:  fmt.Sprintf("%+.1f%%", changePerc),
+		Weight: weights.TargetMultiplier,
+		Cap:    capOf(weights.TargetCap),
+	}
+	if weightSum > 0 && changePerc != 0 {
+		points := math.Copysign(math.Min(math.Abs(changePerc)*weights.TargetMultiplier, weights.TargetCap), changePerc)
+		decay := decaySum / weightSum
+		target.Points = points * decay
+		target.Label = decayed(fmt.Sprintf("mean target %+.1f%%", changePerc), points, decay)
+	}
+	factors = append(factors, target)
+
+	if anomaly, ok := anomalyFactor(consensus.Ticker, params); ok {
+		factors = append(factors, anomaly)
+	}
+
+	return newScoreResult(factors, "No consensus signal")
+}
+
+func (s *consensusStrategy) inWindow(t time.Time, params ScoringParams) bool {
+	window := params.Profile.Consensus.WindowDays
+	return window <= 0 || params.Age(t) <= time.Duration(window*24*float64(time.Hour))
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+)
+
+// scoreConsensus scores the views of distinct brokerages, made daysAgo[i]
+// days before scoringNow.
+func scoreConsensus(profile *ScoringProfile, views []models.Stock, daysAgo ...int) ScoreResult {
+	for i := range views {
+		views[i].ID = fmt.Sprintf("event-%d", i)
+		views[i].Ticker = "AAPL"
+		views[i].Brokerage = fmt.Sprintf("Brokerage %d", i)
+		views[i].Time = scoringNow
+		if i < len(daysAgo) {
+			views[i].Time = scoringNow.AddDate(0, 0, -daysAgo[i])
+		}
+	}
+	consensus := models.NewTickerConsensus(views)
+	return NewConsensusStrategy().Score(consensus, ScoringParams{Profile: profile, Now: scoringNow})
+}
+
+func consensusFactor(t *testing.T, result ScoreResult, name string) models.ScoreFactor {
+	t.Helper()
+	for _, factor := range result.Factors {
+		if factor.Name == name {
+			return factor
+		}
+	}
+	t.Fatalf("no %s factor in %+v", name, result.Factors)
+	return models.ScoreFactor{}
+}
+
+func TestConsensusNetRevisions(t *testing.T) {
+	tests := []struct {
+		name    string
+		views   []models.Stock
+		daysAgo []int
+		points  float64
+		input   string
+	}{
+		{
+			name: "two upgrades and a downgrade",
+			views: []models.Stock{
+				{RatingFrom: "Hold", RatingTo: "Buy"},
+				{RatingFrom: "Hold", RatingTo: "Strong Buy"},
+				{RatingFrom: "Buy", RatingTo: "Hold"},
+				{RatingFrom: "Hold", RatingTo: "Hold"},
+			},
+			points: 40 * 1.0 / 4,
+			input:  "2 upgrades, 1 downgrades",
+		},
+		// The default window is 90 days
+		{
+			name: "revisions outside the window",
+			views: []models.Stock{
+				{RatingFrom: "Buy", RatingTo: "Hold"},
+				{RatingFrom: "Hold", RatingTo: "Buy"},
+				{RatingFrom: "Hold", RatingTo: "Buy"},
+			},
+			daysAgo: []int{10, 91, 120},
+			points:  -40,
+			input:   "0 upgrades, 1 downgrades",
+		},
+		// Unknown ratings fall back to the action
+		{
+			name: "revisions by action",
+			views: []models.Stock{
+				{Action: "upgraded by", RatingFrom: "Accumulate", RatingTo: "Conviction Buy"},
+				{Action: "reiterated by", RatingTo: "Conviction Buy"},
+			},
+			points: 20,
+			input:  "1 upgrades, 0 downgrades",
+		},
+		{
+			name: "upgrades and downgrades cancel out",
+			views: []models.Stock{
+				{RatingFrom: "Hold", RatingTo: "Buy"},
+				{RatingFrom: "Buy", RatingTo: "Hold"},
+			},
+			input: "1 upgrades, 1 downgrades",
+		},
+	}
+	for _, tt := range tests {
+		factor := consensusFactor(t, scoreConsensus(DefaultScoringProfile(), tt.views, tt.daysAgo...), "net_revisions")
+		if math.Abs(factor.Points-tt.points) > 1e-9 || factor.Input != tt.input {
+			t.Errorf("%s: %v points for %q, want %v for %q", tt.name, factor.Points, factor.Input, tt.points, tt.input)
+		}
+	}
+}
+
+func TestConsensusAgreement(t *testing.T) {
+	tests := []struct {
+		name    string
+		ratings []string
+		points  float64
+		label   string
+	}{
+		{"mostly bullish", []string{"Buy", "Strong Buy", "Outperform", "Hold"}, 30 * 3.0 / 4, "3 of 4 brokerages bullish"},
+		{"mostly bearish", []string{"Sell", "Underweight", "Underperform", "Buy"}, -30 * 2.0 / 4, "3 of 4 brokerages bearish"},
+		{"unknown ratings are not counted", []string{"Buy", "Strong Buy", "Conviction Buy", ""}, 30, "2 of 2 brokerages bullish"},
+		{"evenly split", []string{"Buy", "Sell", "Hold"}, 0, "1 of 3 brokerages bullish"},
+	}
+	for _, tt := range tests {
+		var views []models.Stock
+		for _, rating := range tt.ratings {
+			views = append(views, models.Stock{RatingFrom: rating, RatingTo: rating})
+		}
+		factor := consensusFactor(t, scoreConsensus(DefaultScoringProfile(), views), "agreement")
+		if math.Abs(factor.Points-tt.points) > 1e-9 || factor.Label != tt.label {
+			t.Errorf("%s: %v points, %q; want %v, %q", tt.name, factor.Points, factor.Label, tt.points, tt.label)
+		}
+	}
+}
+
+func TestConsensusMeanTargetChange(t *testing.T) {
+	tests := []struct {
+		name   string
+		views  []models.Stock
+		points float64
+		input  string
+	}{
+		// $30 to $34 is +13.3%, at 2 points per percent
+		{
+			name: "mean target raised",
+			views: []models.Stock{
+				{TargetFrom: "$10.00", TargetTo: "$12.00"},
+				{TargetFrom: "$20.00", TargetTo: "$22.00"},
+			},
+			points: 2 * 4.0 / 30 * 100,
+			input:  "+13.3%",
+		},
+		// The consensus is in dollars: euro targets and a move between
+		// currencies are left out
+		{
+			name: "only the consensus currency",
+			views: []models.Stock{
+				{TargetFrom: "$10.00", TargetTo: "$12.00"},
+				{TargetFrom: "$20.00", TargetTo: "$22.00"},
+				{TargetFrom: "€100", TargetTo: "€300"},
+				{TargetFrom: "€50", TargetTo: "$60.00"},
+			},
+			points: 2 * 4.0 / 30 * 100,
+			input:  "+13.3%",
+		},
+		{
+			name: "mean target cut past the cap",
+			views: []models.Stock{
+				{TargetFrom: "€100", TargetTo: "€50"},
+				{TargetFrom: "€100", TargetTo: "€80"},
+			},
+			points: -30,
+			input:  "-35.0%",
+		},
+	}
+	for _, tt := range tests {
+		factor := consensusFactor(t, scoreConsensus(DefaultScoringProfile(), tt.views), "mean_target_change")
+		if math.Abs(factor.Points-tt.points) > 1e-9 || factor.Input != tt.input {
+			t.Errorf("%s: %v points for %s, want %v for %s", tt.name, factor.Points, factor.Input, tt.points, tt.input)
+		}
+	}
+}
+
+func TestConsensusMinBrokerages(t *testing.T) {
+	profile := DefaultScoringProfile()
+	profile.Consensus.MinBrokerages = 3
+	bullish := func(n int) []models.Stock {
+		views := make([]models.Stock, n)
+		for i := range views {
+			views[i] = models.Stock{Action: "upgraded by", RatingFrom: "Hold", RatingTo: "Buy", TargetFrom: "$10.00", TargetTo: "$12.00"}
+		}
+		return views
+	}
+
+	result := scoreConsensus(profile, bullish(2))
+	if result.Score != 0 || len(result.Factors) != 0 || result.Reason != "covered by 2 of the 3 brokerages required" {
+		t.Errorf("two brokerages = %+v, want score 0 for too little coverage", result)
+	}
+	if result := scoreConsensus(profile, bullish(3)); result.Score <= 0 {
+		t.Errorf("three bullish brokerages scored %v, want a positive score", result.Score)
+	}
+
+	// Expired views do not count as coverage
+	profile.Decay.MaxAgeDays = 30
+	if result := scoreConsensus(profile, bullish(3), 0, 0, 31); result.Score != 0 {
+		t.Errorf("two recent brokerages and an expired one scored %v, want 0", result.Score)
+	}
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrBrokerageNotFound is returned for a brokerage with no events and no tier.
+var ErrBrokerageNotFound = errors.New("brokerage not found")
+
+// credibilityPriorEvents pulls hit rates toward 50% so that a brokerage with
+// a handful of lucky calls is not trusted more than a proven one.
+const credibilityPriorEvents = 10
+
+// CredibilityTiers assigns brokerages to named tiers with fixed weights, e.g.
+//
+//	tiers:
+//	  high: 1.25
+//	  low: 0.75
+//	brokerages:
+//	  Goldman Sachs: high
+type CredibilityTiers struct {
+	Tiers      map[string]float64 `yaml:"tiers"`
+	Brokerages map[string]string  `yaml:"brokerages"`
+}
+
+// LoadCredibilityTiers reads and validates a tiers file.
+func LoadCredibilityTiers(path string) (*CredibilityTiers, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading credibility tiers: %w", err)
+	}
+
+	var tiers CredibilityTiers
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&tiers); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for name, weight := range tiers.Tiers {
+		if weight <= 0 {
+			return nil, fmt.Errorf("%s: tier %q must have a positive weight", path, name)
+		}
+	}
+	for brokerage, tier := range tiers.Brokerages {
+		if _, ok := tiers.Tiers[tier]; !ok {
+			return nil, fmt.Errorf("%s: brokerage %q uses unknown tier %q", path, brokerage, tier)
+		}
+	}
+	return &tiers, nil
+}
+
+// CredibilityService rates brokerages. A manually assigned tier wins;
+// otherwise the rating comes from how often the brokerage's directional
+// calls were followed by a price move the same way within the horizon, as
+// far as the price history tells. Brokerages with neither weigh 1.
+type CredibilityService struct {
+	stocks   StockLister
+	profiles *ScoringProfileStore
+	tiers    *CredibilityTiers
+	prices   *PriceHistory
+	horizon  time.Duration
+
+	mu          sync.RWMutex
+	credibility map[string]models.BrokerageCredibility
+}
+
+// NewCredibilityService rates brokerages from tiers and prices; either may
+// be nil.
+func NewCredibilityService(stocks StockLister, profiles *ScoringProfileStore, tiers *CredibilityTiers, prices *PriceHistory, horizon time.Duration) *CredibilityService {
+	return &CredibilityService{
+		stocks:      stocks,
+		profiles:    profiles,
+		tiers:       tiers,
+		prices:      prices,
+		horizon:     horizon,
+		credibility: make(map[string]models.BrokerageCredibility),
+	}
+}
+
+// Refresh recomputes the credibility of every brokerage from the stored
+// events.
+func (s *CredibilityService) Refresh(ctx context.Context) error {
+	type tally struct {
+		name         string
+		events, hits int
+	}
+	tallies := make(map[string]*tally)
+	rating := &s.profiles.Active().Rating
+
+	err := eachStockPage(ctx, s.stocks, func(page []models.Stock) error {
+		for _, stock := range page {
+			id := models.BrokerageID(stock.Brokerage)
+			if id == "" {
+				continue
+			}
+			t, ok := tallies[id]
+			if !ok {
+				t = &tally{name: stock.Brokerage}
+				tallies[id] = t
+			}
+			if hit, known := s.checkCall(&stock, rating); known {
+				t.events++
+				if hit {
+					t.hits++
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	credibility := make(map[string]models.BrokerageCredibility, len(tallies))
+	for id, t := range tallies {
+		entry := models.BrokerageCredibility{
+			ID: id, Brokerage: t.name, Credibility: 1, Source: models.CredibilitySourceDefault,
+			Events: t.events, Hits: t.hits, UpdatedAt: now,
+		}
+		if t.events > 0 {
+			hitRate := float64(t.hits) / float64(t.events)
+			entry.HitRate = &hitRate
+			// 0.5 to 1.5, exactly 1 at a 50% hit rate
+			entry.Credibility = 0.5 + (float64(t.hits)+credibilityPriorEvents/2.0)/float64(t.events+credibilityPriorEvents)
+			entry.Source = models.CredibilitySourceHistory
+		}
+		credibility[id] = entry
+	}
+
+	if s.tiers != nil {
+		for name, tier := range s.tiers.Brokerages {
+			id := models.BrokerageID(name)
+			entry, ok := credibility[id]
+			if !ok {
+				entry = models.BrokerageCredibility{ID: id, Brokerage: name, UpdatedAt: now}
+			}
+			entry.Credibility = s.tiers.Tiers[tier]
+			entry.Source = models.CredibilitySourceTier
+			entry.Tier = tier
+			credibility[id] = entry
+		}
+	}
+
+	s.mu.Lock()
+	s.credibility = credibility
+	s.mu.Unlock()
+	return nil
+}
+
+// checkCall reports whether a directional call was right, and whether it
+// could be judged at all: neutral events, tickers without prices and calls
+// whose horizon has not passed yet are not.
+func (s *CredibilityService) checkCall(stock *models.Stock, rating *RatingWeights) (bool, bool) {
+	if s.prices == nil {
+		return false, false
+	}
+
+	direction := ratingRevision(stock.RatingFrom, stock.RatingTo, stock.Action, rating)
+	if direction == 0 {
+		if change, ok := models.TargetChangePct(stock.TargetFrom, stock.TargetTo); ok && change != 0 {
+			direction = 1
+			if change < 0 {
+				direction = -1
+			}
+		}
+	}
+	if direction == 0 {
+		return false, false
+	}
+
+	forward, ok := s.prices.ForwardReturn(stock.Ticker, stock.Time, s.horizon)
+	if !ok || forward == 0 {
+		return false, false
+	}
+	return math.Signbit(forward) == (direction < 0), true
+}
+
+// StartRefreshing refreshes credibility every interval until ctx is
+// cancelled.
+func (s *CredibilityService) StartRefreshing(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.Refresh(ctx); err != nil {
+					log.Printf("Credibility refresh error: %v", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Get returns the credibility of a brokerage by id.
+func (s *CredibilityService) Get(id string) (*models.BrokerageCredibility, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, ok := s.credibility[models.BrokerageID(id)]
+	if !ok {
+		return nil, ErrBrokerageNotFound
+	}
+	return &entry, nil
+}
+
+// All returns every rated brokerage ordered by name.
+func (s *CredibilityService) All() []models.BrokerageCredibility {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := make([]models.BrokerageCredibility, 0, len(s.credibility))
+	for _, entry := range s.credibility {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Brokerage < entries[j].Brokerage })
+	return entries
+}
+
+// Weights returns the credibility of every brokerage by id, for one ranking.
+func (s *CredibilityService) Weights() map[string]float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	weights := make(map[string]float64, len(s.credibility))
+	for id, entry := range s.credibility {
+		weights[id] = entry.Credibility
+	}
+	return weights
+}
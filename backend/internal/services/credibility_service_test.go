@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository"
+)
+
+// newCredibilityFixture stores upgrades and downgrades of one ticker whose
+// price rises every day, so every upgrade is a hit and every downgrade a
+// miss.
+func newCredibilityFixture(t *testing.T, tiers *CredibilityTiers, calls map[string][2]int) *CredibilityService {
+	t.Helper()
+	ctx := context.Background()
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := repository.NewMemoryStockStore()
+	n := 0
+	for brokerage, counts := range calls {
+		for i := 0; i < counts[0]+counts[1]; i++ {
+			stock := &models.Stock{
+				ID: fmt.Sprintf("event-%d", n), Ticker: "AAA", Company: "Alpha", Brokerage: brokerage,
+				Action: "upgraded by", RatingFrom: "Hold", RatingTo: "Buy", Time: from.Add(time.Duration(n) * time.Minute),
+			}
+			if i >= counts[0] {
+				stock.Action, stock.RatingFrom, stock.RatingTo = "downgraded by", "Buy", "Hold"
+			}
+			if err := store.Create(ctx, stock, ""); err != nil {
+				t.Fatal(err)
+			}
+			n++
+		}
+	}
+
+	profiles, err := NewScoringProfileStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := NewCredibilityService(NewStockService(store, "", "", models.SyncWriteAtomic), profiles, tiers,
+		newTestPriceHistory(t, from, 60, "AAA"), 30*24*time.Hour)
+	if err := service.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return service
+}
+
+func TestCredibilityFromHistory(t *testing.T) {
+	// Hits and misses of each brokerage
+	service := newCredibilityFixture(t, nil, map[string][2]int{
+		"Always Right": {200, 0},
+		"Always Wrong": {0, 200},
+		"Coin Flip":    {7, 7},
+		"Lucky":        {2, 0},
+	})
+	tests := []struct {
+		brokerage    string
+		events, hits int
+		credibility  float64
+	}{
+		{"Always Right", 200, 200, 0.5 + 205.0/210},
+		{"Always Wrong", 200, 0, 0.5 + 5.0/210},
+		// A 50% hit rate is exactly neutral
+		{"Coin Flip", 14, 7, 1},
+		// Two lucky calls are pulled toward 50%
+		{"Lucky", 2, 2, 0.5 + 7.0/12},
+	}
+	for _, tt := range tests {
+		entry, err := service.Get(tt.brokerage)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.brokerage, err)
+		}
+		if entry.Events != tt.events || entry.Hits != tt.hits || entry.Source != models.CredibilitySourceHistory {
+			t.Errorf("%s: %d hits of %d from %s, want %d of %d from history", tt.brokerage, entry.Hits, entry.Events, entry.Source, tt.hits, tt.events)
+		}
+		if math.Abs(entry.Credibility-tt.credibility) > 1e-12 {
+			t.Errorf("%s: credibility %v, want %v", tt.brokerage, entry.Credibility, tt.credibility)
+		}
+		if entry.Credibility <= 0.5 || entry.Credibility >= 1.5 {
+			t.Errorf("%s: credibility %v outside 0.5 to 1.5", tt.brokerage, entry.Credibility)
+		}
+	}
+	if coin, _ := service.Get("Coin Flip"); coin.Credibility != 1 {
+		t.Errorf("a 50%% hit rate weighs %v, want exactly 1", coin.Credibility)
+	}
+}
+
+func TestCredibilityTierOverridesHistory(t *testing.T) {
+	tiers := &CredibilityTiers{
+		Tiers:      map[string]float64{"low": 0.75},
+		Brokerages: map[string]string{"Always Right": "low", "Unseen Research": "low"},
+	}
+	service := newCredibilityFixture(t, tiers, map[string][2]int{"Always Right": {20, 0}, "Coin Flip": {1, 1}})
+
+	for _, brokerage := range []string{"Always Right", "Unseen Research"} {
+		entry, err := service.Get(brokerage)
+		if err != nil {
+			t.Fatalf("%s: %v", brokerage, err)
+		}
+		if entry.Credibility != 0.75 || entry.Source != models.CredibilitySourceTier || entry.Tier != "low" {
+			t.Errorf("%s: %v from %s %q, want 0.75 from the low tier", brokerage, entry.Credibility, entry.Source, entry.Tier)
+		}
+	}
+	// The track record is still reported next to the tier
+	if entry, _ := service.Get("Always Right"); entry.Events != 20 || entry.Hits != 20 {
+		t.Errorf("tiered brokerage lost its history: %d hits of %d", entry.Hits, entry.Events)
+	}
+	if weight := service.Weights()[models.BrokerageID("Always Right")]; weight != 0.75 {
+		t.Errorf("ranking weight %v, want the tier's 0.75", weight)
+	}
+}
+
+func TestLoadCredibilityTiers(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		err  string
+	}{
+		{"valid", "tiers:\n  high: 1.25\n  low: 0.75\nbrokerages:\n  Goldman Sachs: high\n", ""},
+		{"unknown tier", "tiers:\n  high: 1.25\nbrokerages:\n  Goldman Sachs: top\n", `unknown tier "top"`},
+		{"zero weight", "tiers:\n  muted: 0\n", `tier "muted" must have a positive weight`},
+		{"negative weight", "tiers:\n  contrarian: -1\n", `tier "contrarian" must have a positive weight`},
+		{"unknown field", "tiers:\n  high: 1.25\nweights:\n  high: 2\n", "field weights not found"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "tiers.yaml")
+		if err := os.WriteFile(path, []byte(tt.yaml), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		tiers, err := LoadCredibilityTiers(path)
+		if tt.err == "" {
+			if err != nil || tiers.Brokerages["Goldman Sachs"] != "high" {
+				t.Errorf("%s: %+v, %v", tt.name, tiers, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: error %v, want one containing %q", tt.name, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+)
+
+// DefaultStrategyName is the strategy used when a request does not name one.
+const DefaultStrategyName = "default"
+
+// defaultStrategy is the original formula: target change, rating change,
+// action type and a momentum bonus, weighted by the scoring profile. Bearish
+// signals score the same points negated.
+type defaultStrategy struct{}
+
+func NewDefaultStrategy() ScoringStrategy {
+	return &defaultStrategy{}
+}
+
+func (s *defaultStrategy) Name() string    { return DefaultStrategyName }
+func (s *defaultStrategy) Version() string { return "8" }
+
+// Score rates the ticker's most recent analyst event, weighted by the
+// credibility of its brokerage, plus any flagged swing in its analysts'
+// direction. Events past the profile's maximum age score nothing.
+func (s *defaultStrategy) Score(consensus *models.TickerConsensus, params ScoringParams) ScoreResult {
+	if params.Expired(consensus.Latest.Time) {
+		return newScoreResult(nil, "latest analyst event is too old")
+	}
+	factors := s.factors(consensus.Latest, params)
+	if anomaly, ok := anomalyFactor(consensus.Ticker, params); ok {
+		factors = append(factors, anomaly)
+	}
+	return newScoreResult(factors, "Good fundamentals")
+}
+
+func (s *defaultStrategy) factors(stock *models.Stock, params ScoringParams) []models.ScoreFactor {
+	profile := params.Profile
+	var factors []models.ScoreFactor
+
+	// Each factor fades with the age of the event
+	targetDecay := params.Decay(stock.Time, profile.Decay.TargetHalfLifeDays)
+	ratingDecay := params.Decay(stock.Time, profile.Decay.RatingHalfLifeDays)
+	actionDecay := params.Decay(stock.Time, profile.Decay.ActionHalfLifeDays)
+
+	// A move between targets in different currencies, or one that cannot
+	// be read, counts as unchanged
+	changePerc, _ := models.TargetChangePct(stock.TargetFrom, stock.TargetTo)
+
+	// Factor 1: Target price change, a cut counting as much against
+	target := models.ScoreFactor{
+		Name:   "target_change",
+		Label:  "target price unchanged",
+		Input:  fmt.Sprintf("%s → %s (%+.1f%%)", stock.TargetFrom, stock.TargetTo, changePerc),
+		Weight: profile.Target.Multiplier,
+		Cap:    capOf(profile.Target.Cap),
+	}
+	if changePerc != 0 {
+		points := math.Copysign(math.Min(math.Abs(changePerc)*profile.Target.Multiplier, profile.Target.Cap), changePerc)
+		target.Points = points * targetDecay
+		target.Label = decayed("target price increased", points, targetDecay)
+		if changePerc < 0 {
+			target.Label = decayed("target price cut", points, targetDecay)
+		}
+	}
+	factors = append(factors, target)
+
+	// Factor 2: Rating change
+	ratingScore, ratingLabel := s.getRatingScore(stock.RatingFrom, stock.RatingTo, &profile.Rating)
+	upgraded := ratingScore > 0 && ratingScore >= profile.Rating.Upgrade
+	downgraded := ratingScore < 0 && ratingScore <= -profile.Rating.Upgrade
+	rating := models.ScoreFactor{
+		Name:   "rating_change",
+		Label:  "no rating points",
+		Input:  fmt.Sprintf("%s → %s", stock.RatingFrom, stock.RatingTo),
+		Weight: ratingScore,
+		Points: ratingScore * ratingDecay,
+		Cap:    capOf(profile.Rating.Upgrade),
+	}
+	if ratingLabel != "" {
+		rating.Label = decayed(ratingLabel, ratingScore, ratingDecay)
+	}
+	factors = append(factors, rating)
+
+	// Factor 3: Action type
+	actionScore := s.getActionScore(stock.Action, stock.RatingTo, profile)
+	action := models.ScoreFactor{
+		Name:   "action",
+		Label:  decayed("analyst action", actionScore, actionDecay),
+		Input:  stock.Action,
+		Weight: actionScore,
+		Points: actionScore * actionDecay,
+		Cap:    capOf(profile.Action.RaisedOrUpgraded),
+	}
+	if actionScore > profile.Action.PositiveAbove {
+		action.Label = decayed("positive analyst action", actionScore, actionDecay)
+	} else if actionScore < -profile.Action.PositiveAbove {
+		action.Label = decayed("negative analyst action", actionScore, actionDecay)
+	}
+	factors = append(factors, action)
+
+	// Bonus: Momentum synergy, fading like the target change it builds on
+	// Reward strong alignment when there is a big target hike and an upgrade
+	// action/rating, and penalize the mirror image of cuts and downgrades
+	actionLower := strings.ToLower(stock.Action)
+	upgradeAction := strings.Contains(actionLower, "upgraded") || strings.Contains(actionLower, "raised")
+	downgradeAction := strings.Contains(actionLower, "downgraded") || strings.Contains(actionLower, "lowered")
+	momentum := profile.Momentum
+	bonus := models.ScoreFactor{
+		Name:  "momentum",
+		Label: "no momentum bonus",
+		Input: fmt.Sprintf("%+.1f%% target, rating %q, action %q", changePerc, ratingLabel, stock.Action),
+		Cap:   capOf(momentum.StrongBonus),
+	}
+	switch {
+	case changePerc >= momentum.StrongChangePct && upgraded && upgradeAction:
+		bonus.Weight = momentum.StrongBonus
+		bonus.Label = "strong multi-signal momentum"
+	case changePerc >= momentum.ChangePct && (upgraded || upgradeAction):
+		bonus.Weight = momentum.Bonus
+		bonus.Label = "strong momentum"
+	case changePerc <= -momentum.StrongChangePct && downgraded && downgradeAction:
+		bonus.Weight = -momentum.StrongBonus
+		bonus.Label = "strong multi-signal bearish momentum"
+	case changePerc <= -momentum.ChangePct && (downgraded || downgradeAction):
+		bonus.Weight = -momentum.Bonus
+		bonus.Label = "bearish momentum"
+	}
+	if bonus.Weight != 0 {
+		bonus.Points = bonus.Weight * targetDecay
+		bonus.Label = decayed(bonus.Label, bonus.Weight, targetDecay)
+	}
+	factors = append(factors, bonus)
+
+	// The brokerage's credibility scales everything above
+	if weight := params.Weight(stock.Brokerage); weight != 1 {
+		var subtotal float64
+		for _, factor := range factors {
+			subtotal += factor.Points
+		}
+		factors = append(factors, models.ScoreFactor{
+			Name:   "credibility",
+			Label:  fmt.Sprintf("weighted x%.2f by %s credibility", weight, stock.Brokerage),
+			Input:  stock.Brokerage,
+			Weight: weight,
+			Points: subtotal * (weight - 1),
+		})
+	}
+
+	return factors
+}
+
+// decayed names a factor and, when its points faded, how much was kept.
+func decayed(reason string, points, decay float64) string {
+	if decay >= 1 {
+		return reason
+	}
+	return fmt.Sprintf("%s (%.1f of %.1f pts after decay)", reason, points*decay, points)
+}
+
+// getRatingScore scores the rating change and names it. Bullish moves score
+// as in the original formula, where new coverage counts as an upgrade from
+// no rating. Bearish moves mirror them: a downgrade, or new coverage with a
+// weak rating, costs what an upgrade earns, and a weak rating kept what a
+// strong one kept earns.
+func (s *defaultStrategy) getRatingScore(ratingFrom, ratingTo string, weights *RatingWeights) (float64, string) {
+	scoreFrom := weights.Scale[ratingFrom]
+	scoreTo := weights.Scale[ratingTo]
+	weak := scoreTo > 0 && scoreTo <= weights.WeakLevel
+
+	switch {
+	case scoreFrom == 0 && weak:
+		return -weights.Upgrade, "weak rating initiated"
+	case scoreTo > scoreFrom:
+		return weights.Upgrade, "rating upgraded"
+	case scoreTo >= weights.StrongLevel && scoreFrom == 0:
+		// New coverage with strong rating (no previous rating)
+		return weights.NewStrong, "strong rating initiated"
+	case scoreTo == 0:
+		return 0, ""
+	case scoreTo < scoreFrom:
+		return -weights.Upgrade, "rating downgraded"
+	case scoreTo >= weights.StrongLevel:
+		// Maintained strong rating (reiterated)
+		return weights.Reiterated, "positive rating maintained"
+	case weak:
+		return -weights.Reiterated, "negative rating maintained"
+	}
+	return 0, ""
+}
+
+// getActionScore scores the action text. Raises and upgrades earn what cuts
+// and downgrades cost; other actions lean the way of the rating they come
+// with.
+func (s *defaultStrategy) getActionScore(action, ratingTo string, profile *ScoringProfile) float64 {
+	weights := &profile.Action
+	action = strings.ToLower(action)
+	if strings.Contains(action, "raised") || strings.Contains(action, "upgraded") {
+		return weights.RaisedOrUpgraded
+	} else if strings.Contains(action, "lowered") || strings.Contains(action, "downgraded") {
+		return -weights.RaisedOrUpgraded
+	}
+
+	sign := 1.0
+	if level := profile.Rating.Scale[ratingTo]; level > 0 && level <= profile.Rating.WeakLevel {
+		sign = -1
+	}
+	if strings.Contains(action, "initiated") {
+		return sign * weights.Initiated
+	} else if strings.Contains(action, "reiterated") {
+		return sign * weights.Reiterated
+	}
+	return sign * weights.Other
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+)
+
+var scoringNow = time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+func scoreEvent(strategy ScoringStrategy, stock models.Stock) ScoreResult {
+	stock.Ticker = "AAPL"
+	stock.Time = scoringNow
+	consensus := models.NewTickerConsensus([]models.Stock{stock})
+	consensus.Latest = &stock
+	return strategy.Score(consensus, ScoringParams{Profile: DefaultScoringProfile(), Now: scoringNow})
+}
+
+func TestDefaultRatingScore(t *testing.T) {
+	weights := &DefaultScoringProfile().Rating
+	tests := []struct {
+		from, to string
+		want     float64
+		label    string
+	}{
+		// Bullish moves score as in the original formula
+		{"", "Strong Buy", 30, "rating upgraded"},
+		{"", "Buy", 30, "rating upgraded"},
+		{"", "Hold", 30, "rating upgraded"},
+		{"Hold", "Buy", 30, "rating upgraded"},
+		{"Sell", "Hold", 30, "rating upgraded"},
+		{"Buy", "Buy", 10, "positive rating maintained"},
+		{"Outperform", "Buy", 10, "positive rating maintained"},
+		{"Hold", "Hold", 0, ""},
+		{"Buy", "", 0, ""},
+		{"", "", 0, ""},
+		// Bearish moves mirror them
+		{"", "Sell", -30, "weak rating initiated"},
+		{"", "Underweight", -30, "weak rating initiated"},
+		{"Buy", "Hold", -30, "rating downgraded"},
+		{"Hold", "Sell", -30, "rating downgraded"},
+		{"Sell", "Sell", -10, "negative rating maintained"},
+	}
+	strategy := &defaultStrategy{}
+	for _, tt := range tests {
+		got, label := strategy.getRatingScore(tt.from, tt.to, weights)
+		if got != tt.want || label != tt.label {
+			t.Errorf("getRatingScore(%q, %q) = %v %q, want %v %q", tt.from, tt.to, got, label, tt.want, tt.label)
+		}
+	}
+}
+
+// TestDefaultScoreBearishEventsOnTheBuySide pins the scores that changed
+// from the original formula when bearish events began to score below zero.
+// The original clamped scores at 0 and gave these events points, so a Sell
+// initiation or a target cut could rank on the buy list; now they cost
+// what their bullish mirror images earn.
+func TestDefaultScoreBearishEventsOnTheBuySide(t *testing.T) {
+	tests := []struct {
+		name     string
+		stock    models.Stock
+		original float64
+		want     float64
+	}{
+		// Other action 5 before; now the lowered action costs 20
+		{"target lowered action", models.Stock{Action: "target lowered by", RatingFrom: "Hold", RatingTo: "Hold"}, 5, -20},
+		// A 30% cut earned nothing before; now it costs 40 and momentum 5
+		{"target cut", models.Stock{Action: "target lowered by", RatingFrom: "Hold", RatingTo: "Hold", TargetFrom: "$13.00", TargetTo: "$9.10"}, 5, -65},
+		// A weak rating from none counted as an upgrade (30) plus the action (15)
+		{"initiated at Sell", models.Stock{Action: "initiated by", RatingTo: "Sell"}, 45, -45},
+		{"initiated at Underweight", models.Stock{Action: "initiated by", RatingTo: "Underweight"}, 45, -45},
+		// A weak rating kept earned the reiteration (10)
+		{"Sell reiterated", models.Stock{Action: "reiterated by", RatingFrom: "Sell", RatingTo: "Sell"}, 10, -20},
+		// A downgrade earned the other action (5)
+		{"downgrade", models.Stock{Action: "downgraded by", RatingFrom: "Buy", RatingTo: "Hold"}, 5, -50},
+	}
+	strategy := NewDefaultStrategy()
+	for _, tt := range tests {
+		if got := scoreEvent(strategy, tt.stock).Score; got != tt.want {
+			t.Errorf("%s: score %v, want %v (the original formula gave %v)", tt.name, got, tt.want, tt.original)
+		}
+	}
+}
+
+func TestDefaultScoreBullishAndMirror(t *testing.T) {
+	tests := []struct {
+		name  string
+		stock models.Stock
+		want  float64
+	}{
+		// Upgrade from no rating (30) plus the action (15)
+		{"coverage initiated at Buy", models.Stock{Action: "initiated by", RatingTo: "Buy"}, 45},
+		{"coverage initiated at Sell", models.Stock{Action: "initiated by", RatingTo: "Sell"}, -45},
+		// Target +50% capped at 40, upgrade 30, action 20, strong momentum 10
+		{"strong upgrade", models.Stock{Action: "upgraded by", RatingFrom: "Hold", RatingTo: "Buy", TargetFrom: "$10.00", TargetTo: "$15.00"}, 100},
+		{"strong downgrade", models.Stock{Action: "downgraded by", RatingFrom: "Buy", RatingTo: "Hold", TargetFrom: "$15.00", TargetTo: "$7.50"}, -100},
+		// Target +30% (40 capped), action 20, momentum 5 from the raise
+		{"target raised", models.Stock{Action: "target raised by", RatingFrom: "Hold", RatingTo: "Hold", TargetFrom: "$10.00", TargetTo: "$13.00"}, 65},
+		{"target lowered", models.Stock{Action: "target lowered by", RatingFrom: "Hold", RatingTo: "Hold", TargetFrom: "$13.00", TargetTo: "$9.10"}, -65},
+		// Targets are read with their currency and thousands separators
+		{"target raised past a thousand", models.Stock{Action: "target raised by", RatingFrom: "Hold", RatingTo: "Hold", TargetFrom: "$1,000.00", TargetTo: "$1,300.00"}, 65},
+		{"target raised in euros", models.Stock{Action: "target raised by", RatingFrom: "Hold", RatingTo: "Hold", TargetFrom: "€10", TargetTo: "€13"}, 65},
+		// A move between currencies counts as unchanged, leaving the action
+		{"target moved across currencies", models.Stock{Action: "target raised by", RatingFrom: "Hold", RatingTo: "Hold", TargetFrom: "$10.00", TargetTo: "€13"}, 20},
+	}
+	strategy := NewDefaultStrategy()
+	for _, tt := range tests {
+		if got := scoreEvent(strategy, tt.stock).Score; got != tt.want {
+			t.Errorf("%s: score %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateLayout is how days are written in price files and backtest requests.
+const dateLayout = "2006-01-02"
+
+// ParseDate reads a day as YYYY-MM-DD or a full RFC 3339 timestamp.
+func ParseDate(value string) (time.Time, error) {
+	if t, err := time.Parse(dateLayout, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// pricePoint is one daily close.
+type pricePoint struct {
+	Date  time.Time
+	Close float64
+}
+
+// PriceHistory holds daily closes per ticker, loaded from a CSV file with a
+// "date,ticker,close" header and dates as YYYY-MM-DD. Extra columns are
+// ignored.
+type PriceHistory struct {
+	prices map[string][]pricePoint
+	last   time.Time
+}
+
+// LoadPriceHistory reads a daily price CSV file.
+func LoadPriceHistory(path string) (*PriceHistory, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading price history: %w", err)
+	}
+	defer file.Close()
+
+	history, err := ParsePriceHistory(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return history, nil
+}
+
+// ParsePriceHistory reads daily closes in the LoadPriceHistory format.
+func ParsePriceHistory(r io.Reader) (*PriceHistory, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading price history header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"date", "ticker", "close"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("price history is missing the %q column", name)
+		}
+	}
+
+	history := &PriceHistory{prices: make(map[string][]pricePoint)}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < len(header) {
+			return nil, fmt.Errorf("line %d: expected %d columns", line, len(header))
+		}
+
+		date, err := time.Parse(dateLayout, strings.TrimSpace(record[columns["date"]]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid date: %w", line, err)
+		}
+		closePrice, err := strconv.ParseFloat(strings.TrimSpace(record[columns["close"]]), 64)
+		if err != nil || closePrice <= 0 {
+			return nil, fmt.Errorf("line %d: invalid close %q", line, record[columns["close"]])
+		}
+		ticker := strings.ToUpper(strings.TrimSpace(record[columns["ticker"]]))
+
+		history.prices[ticker] = append(history.prices[ticker], pricePoint{Date: date, Close: closePrice})
+		if date.After(history.last) {
+			history.last = date
+		}
+	}
+
+	for _, points := range history.prices {
+		sort.Slice(points, func(i, j int) bool { return points[i].Date.Before(points[j].Date) })
+	}
+	return history, nil
+}
+
+// LastDate is the most recent day with a close for any ticker.
+func (h *PriceHistory) LastDate() time.Time {
+	return h.last
+}
+
+// Tickers lists the tickers with prices, sorted.
+func (h *PriceHistory) Tickers() []string {
+	tickers := make([]string, 0, len(h.prices))
+	for ticker := range h.prices {
+		tickers = append(tickers, ticker)
+	}
+	sort.Strings(tickers)
+	return tickers
+}
+
+// CloseOnOrAfter returns the first close of ticker on the day of t or later,
+// with the day it was recorded.
+func (h *PriceHistory) CloseOnOrAfter(ticker string, t time.Time) (float64, time.Time, bool) {
+	points := h.prices[strings.ToUpper(ticker)]
+	day := truncateDay(t)
+	i := sort.Search(len(points), func(i int) bool { return !points[i].Date.Before(day) })
+	if i == len(points) {
+		return 0, time.Time{}, false
+	}
+	return points[i].Close, points[i].Date, true
+}
+
+// CloseOnOrBefore returns the last close of ticker on the day of t or
+// earlier, with the day it was recorded.
+func (h *PriceHistory) CloseOnOrBefore(ticker string, t time.Time) (float64, time.Time, bool) {
+	points := h.prices[strings.ToUpper(ticker)]
+	day := truncateDay(t)
+	i := sort.Search(len(points), func(i int) bool { return points[i].Date.After(day) })
+	if i == 0 {
+		return 0, time.Time{}, false
+	}
+	return points[i-1].Close, points[i-1].Date, true
+}
+
+// ForwardReturn is the return of ticker from the first close on or after
+// from to the first close on or after from+horizon. It is unknown when the
+// history does not reach that far.
+func (h *PriceHistory) ForwardReturn(ticker string, from time.Time, horizon time.Duration) (float64, bool) {
+	entry, _, ok := h.CloseOnOrAfter(ticker, from)
+	if !ok {
+		return 0, false
+	}
+	exit, _, ok := h.CloseOnOrAfter(ticker, from.Add(horizon))
+	if !ok {
+		return 0, false
+	}
+	return exit/entry - 1, true
+}
+
+func truncateDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
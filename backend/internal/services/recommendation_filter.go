@@ -0,0 +1,174 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+)
+
+var (
+	// ErrInvalidFilter is returned for recommendation filters that cannot
+	// match anything meaningful, such as an unknown action class.
+	ErrInvalidFilter = errors.New("invalid recommendation filter")
+	// ErrInvalidCursor is returned for a cursor this service did not issue,
+	// or paging it cannot honour.
+	ErrInvalidCursor = errors.New("invalid recommendation cursor")
+)
+
+// RecommendationFilter narrows the universe before it is ranked, so a limit
+// always takes the best of the tickers that match. Zero values match
+// everything.
+type RecommendationFilter struct {
+	// MinScore is the least strength on the ranked side: on the sell side
+	// a ticker scoring -30 has a strength of 30
+	MinScore float64
+	// Sectors keeps tickers in any of these sectors
+	Sectors []string
+	// Brokerages keeps tickers with a current view from any of these
+	// brokerages, by name or id
+	Brokerages []string
+	// ActionClasses keeps tickers whose latest event is of any of these
+	// classes (models.ActionClasses)
+	ActionClasses []string
+	// MaxAge keeps tickers whose latest event is at most this old
+	MaxAge time.Duration
+	// ExcludeTickers leaves these tickers out, e.g. ones already held
+	ExcludeTickers []string
+	// MinAnalysts keeps tickers covered by at least this many brokerages
+	MinAnalysts int
+}
+
+func (f *RecommendationFilter) validate() error {
+	if f.MinScore < 0 || f.MaxAge < 0 || f.MinAnalysts < 0 {
+		return fmt.Errorf("%w: min score, max age and min analysts must not be negative", ErrInvalidFilter)
+	}
+	for _, class := range f.ActionClasses {
+		if !slices.Contains(models.ActionClasses, class) {
+			return fmt.Errorf("%w: unknown action class %q (want one of %s)", ErrInvalidFilter, class, strings.Join(models.ActionClasses, ", "))
+		}
+	}
+	return nil
+}
+
+// consensusFilter is a RecommendationFilter prepared for matching every
+// ticker of the universe.
+type consensusFilter struct {
+	filter     *RecommendationFilter
+	securities *SecurityService
+	sectors    map[string]bool
+	brokerages map[string]bool
+	excluded   map[string]bool
+	since      time.Time
+}
+
+func newConsensusFilter(filter *RecommendationFilter, securities *SecurityService, now time.Time) *consensusFilter {
+	f := &consensusFilter{
+		filter:     filter,
+		securities: securities,
+		sectors:    normalizedSet(filter.Sectors, strings.ToLower),
+		brokerages: normalizedSet(filter.Brokerages, models.BrokerageID),
+		excluded:   normalizedSet(filter.ExcludeTickers, strings.ToUpper),
+	}
+	if filter.MaxAge > 0 {
+		f.since = now.Add(-filter.MaxAge)
+	}
+	return f
+}
+
+// normalizedSet normalizes values into a set, nil when there are none.
+func normalizedSet(values []string, normalize func(string) string) map[string]bool {
+	if len(values) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[normalize(strings.TrimSpace(value))] = true
+	}
+	return set
+}
+
+// matches applies everything but the minimum score, which needs the ticker
+// scored.
+func (f *consensusFilter) matches(consensus *models.TickerConsensus) bool {
+	if consensus.Latest == nil || f.excluded[strings.ToUpper(consensus.Ticker)] {
+		return false
+	}
+	if consensus.CoveringBrokerages < f.filter.MinAnalysts {
+		return false
+	}
+	if !f.since.IsZero() && consensus.LastActionTime.Before(f.since) {
+		return false
+	}
+	if len(f.filter.ActionClasses) > 0 && !slices.Contains(f.filter.ActionClasses, models.ActionClass(consensus.Latest.Action)) {
+		return false
+	}
+	if f.sectors != nil {
+		security, ok := f.securities.Lookup(consensus.Ticker)
+		if !ok || !f.sectors[strings.ToLower(security.Sector)] {
+			return false
+		}
+	}
+	if f.brokerages != nil {
+		covered := false
+		for _, view := range consensus.Brokerages {
+			if f.brokerages[models.BrokerageID(view.Brokerage)] {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+// A cursor is the score and ticker of the last recommendation of a page;
+// the next page starts right after it in rank order.
+func encodeCursor(recommendation *models.StockRecommendation) string {
+	key := strconv.FormatFloat(recommendation.Score, 'g', -1, 64) + "|" + recommendation.Stock.Ticker
+	return base64.RawURLEncoding.EncodeToString([]byte(key))
+}
+
+func decodeCursor(cursor string) (float64, string, error) {
+	key, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, "", ErrInvalidCursor
+	}
+	scoreText, ticker, ok := strings.Cut(string(key), "|")
+	if !ok || ticker == "" {
+		return 0, "", ErrInvalidCursor
+	}
+	score, err := strconv.ParseFloat(scoreText, 64)
+	if err != nil {
+		return 0, "", ErrInvalidCursor
+	}
+	return score, ticker, nil
+}
+
+// cursorStart returns the index in ranked of the first recommendation after
+// the cursor. The ranked list may have changed since the cursor was issued;
+// the page then simply continues from where that ticker would rank now.
+func cursorStart(ranked []models.StockRecommendation, cursor, side string) (int, error) {
+	score, ticker, err := decodeCursor(cursor)
+	if err != nil {
+		return 0, err
+	}
+	sign := 1.0
+	if side == models.SideSell {
+		sign = -1
+	}
+	for i := range ranked {
+		current := ranked[i].Score * sign
+		if current < score*sign || (current == score*sign && ranked[i].Stock.Ticker > ticker) {
+			return i, nil
+		}
+	}
+	return len(ranked), nil
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository"
+)
+
+// scored builds a ranking with the given scores, in the order given.
+func scored(scores map[string]float64, tickers ...string) []models.StockRecommendation {
+	var ranked []models.StockRecommendation
+	for _, ticker := range tickers {
+		ranked = append(ranked, models.StockRecommendation{Stock: &models.Stock{Ticker: ticker}, Score: scores[ticker]})
+	}
+	return ranked
+}
+
+func TestCursorStart(t *testing.T) {
+	buy := scored(map[string]float64{"A": 50, "B": 40, "C": 40, "D": 10}, "A", "B", "C", "D")
+	sell := scored(map[string]float64{"W": -50, "X": -40, "Y": -40, "Z": -10}, "W", "X", "Y", "Z")
+	cursor := func(ticker string, score float64) string {
+		return encodeCursor(&models.StockRecommendation{Stock: &models.Stock{Ticker: ticker}, Score: score})
+	}
+
+	tests := []struct {
+		name   string
+		ranked []models.StockRecommendation
+		side   string
+		cursor string
+		want   int
+	}{
+		{"after the first", buy, models.SideBuy, cursor("A", 50), 1},
+		{"between tied scores", buy, models.SideBuy, cursor("B", 40), 2},
+		{"after the last", buy, models.SideBuy, cursor("D", 10), 4},
+		{"a ticker that has since dropped out", buy, models.SideBuy, cursor("BB", 40), 2},
+		{"a score that has since moved", buy, models.SideBuy, cursor("A", 45), 1},
+		{"sell side, most bearish first", sell, models.SideSell, cursor("W", -50), 1},
+		{"sell side between tied scores", sell, models.SideSell, cursor("X", -40), 2},
+		{"sell side after the last", sell, models.SideSell, cursor("Z", -10), 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cursorStart(tt.ranked, tt.cursor, tt.side)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("cursorStart = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorRejects(t *testing.T) {
+	encode := func(key string) string { return base64.RawURLEncoding.EncodeToString([]byte(key)) }
+	for _, cursor := range []string{"not base64!", encode("40"), encode("40|"), encode("forty|AAPL")} {
+		if _, _, err := decodeCursor(cursor); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("decodeCursor(%q) = %v, want ErrInvalidCursor", cursor, err)
+		}
+	}
+}
+
+// newPagingService ranks a few tickers, some with tied scores, on each side.
+func newPagingService(t *testing.T, now time.Time) *RecommendationService {
+	t.Helper()
+	store := repository.NewMemoryStockStore()
+	events := []struct{ ticker, ratingFrom, ratingTo, targetFrom, targetTo string }{
+		{"AAA", "Hold", "Buy", "$10.00", "$12.00"},
+		{"BBB", "Hold", "Buy", "$10.00", "$12.00"},
+		{"CCC", "Hold", "Buy", "$10.00", "$15.00"},
+		{"DDD", "Sell", "Strong Buy", "$10.00", "$20.00"},
+		{"EEE", "Hold", "Buy", "$10.00", "$11.00"},
+		{"SSS", "Buy", "Sell", "$12.00", "$8.00"},
+		{"TTT", "Buy", "Sell", "$12.00", "$8.00"},
+		{"UUU", "Buy", "Strong Sell", "$20.00", "$10.00"},
+	}
+	for _, e := range events {
+		stock := &models.Stock{
+			ID:         e.ticker + "-1",
+			Ticker:     e.ticker,
+			Company:    e.ticker + " Inc.",
+			Brokerage:  "Example Securities",
+			Action:     "target changed by",
+			RatingFrom: e.ratingFrom,
+			RatingTo:   e.ratingTo,
+			TargetFrom: e.targetFrom,
+			TargetTo:   e.targetTo,
+			Time:       now.Add(-time.Hour),
+		}
+		if err := store.Create(context.Background(), stock, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	strategies, err := NewStrategyRegistry(NewDefaultStrategy(), NewConsensusStrategy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	profiles, err := NewScoringProfileStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := NewRecommendationService(NewStockService(store, "", "", models.SyncWriteAtomic), strategies, profiles, nil, nil, nil)
+	service.now = func() time.Time { return now }
+	return service
+}
+
+func TestRecommendationPagingCoversTheRanking(t *testing.T) {
+	ctx := context.Background()
+	service := newPagingService(t, time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+
+	for _, side := range []string{models.SideBuy, models.SideSell} {
+		whole, err := service.GetRecommendations(ctx, RecommendationQuery{Side: side, Limit: 100})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if whole.Total != len(whole.Data) || whole.NextCursor != "" {
+			t.Fatalf("%s: one page of everything has total %d for %d rows and cursor %q", side, whole.Total, len(whole.Data), whole.NextCursor)
+		}
+		want := tickersOf(whole.Data)
+		tied := false
+		for i := 1; i < len(whole.Data); i++ {
+			tied = tied || whole.Data[i].Score == whole.Data[i-1].Score
+		}
+		if len(whole.Data) < 3 || !tied {
+			t.Fatalf("%s: ranking %q has no tied scores to page across", side, want)
+		}
+
+		// Pages of one end on every tie in the ranking
+		for _, limit := range []int{1, 2} {
+			var byCursor, byOffset []models.StockRecommendation
+			query := RecommendationQuery{Side: side, Limit: limit}
+			for pages := 0; pages < 20; pages++ {
+				page, err := service.GetRecommendations(ctx, query)
+				if err != nil {
+					t.Fatal(err)
+				}
+				byCursor = append(byCursor, page.Data...)
+				if page.NextCursor == "" {
+					break
+				}
+				query.Cursor = page.NextCursor
+			}
+			for offset := 0; offset < whole.Total; offset += limit {
+				page, err := service.GetRecommendations(ctx, RecommendationQuery{Side: side, Limit: limit, Offset: offset})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if page.Offset != offset {
+					t.Errorf("%s: page at offset %d reports offset %d", side, offset, page.Offset)
+				}
+				byOffset = append(byOffset, page.Data...)
+			}
+			if got := tickersOf(byCursor); got != want {
+				t.Errorf("%s: cursor pages of %d = %q, want %q", side, limit, got, want)
+			}
+			if got := tickersOf(byOffset); got != want {
+				t.Errorf("%s: offset pages of %d = %q, want %q", side, limit, got, want)
+			}
+		}
+	}
+}
+
+func TestRecommendationPagingRejects(t *testing.T) {
+	service := newPagingService(t, time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC))
+	first, err := service.GetRecommendations(context.Background(), RecommendationQuery{Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]RecommendationQuery{
+		"negative offset":       {Limit: 1, Offset: -1},
+		"offset and cursor":     {Limit: 1, Offset: 1, Cursor: first.NextCursor},
+		"malformed cursor":      {Limit: 1, Cursor: "????"},
+		"paged diversification": {Limit: 1, Cursor: first.NextCursor, Diversification: models.Diversification{MaxPerSector: 1}},
+	}
+	for name, query := range tests {
+		if _, err := service.GetRecommendations(context.Background(), query); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: GetRecommendations = %v, want ErrInvalidCursor", name, err)
+		}
+	}
+}
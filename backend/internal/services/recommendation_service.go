@@ -1,179 +1,284 @@
 package services
 
 import (
-	"math"
+	"context"
+	"errors"
+	"fmt"
 	"sort"
-	"strconv"
-	"strings"
+	"time"
 
 	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
 )
 
+var (
+	// ErrInvalidSide is returned for a side other than buy or sell.
+	ErrInvalidSide = errors.New("invalid recommendation side")
+	// ErrInvalidDiversification is returned for negative diversification
+	// limits.
+	ErrInvalidDiversification = errors.New("invalid diversification")
+)
+
+// RecommendationQuery selects a ranking. Empty Strategy and Side mean the
+// default strategy on the buy side. Offset or Cursor, not both, page
+// through the ranking; neither goes with diversification, whose picks
+// depend on everything ranked before them.
+type RecommendationQuery struct {
+	Strategy        string
+	Side            string
+	Limit           int
+	Offset          int
+	Cursor          string
+	Filter          RecommendationFilter
+	Diversification models.Diversification
+}
+
 type RecommendationService struct {
-	stockService *StockService
+	stockService StockReader
+	strategies   *StrategyRegistry
+	profiles     *ScoringProfileStore
+	credibility  *CredibilityService
+	securities   *SecurityService
+	anomalies    *AnomalyService
+	now          func() time.Time
 }
 
-func NewRecommendationService(stockService *StockService) *RecommendationService {
+// NewRecommendationService ranks tickers; credibility, securities and
+// anomalies may be nil.
+func NewRecommendationService(stockService StockReader, strategies *StrategyRegistry, profiles *ScoringProfileStore, credibility *CredibilityService, securities *SecurityService, anomalies *AnomalyService) *RecommendationService {
 	return &RecommendationService{
 		stockService: stockService,
+		strategies:   strategies,
+		profiles:     profiles,
+		credibility:  credibility,
+		securities:   securities,
+		anomalies:    anomalies,
+		now:          time.Now,
 	}
 }
 
-func (s *RecommendationService) GetRecommendations(limit int) ([]models.StockRecommendation, error) {
-	stocks, err := s.stockService.GetAllStocks(1000, 0)
+// Strategies lists the registered scoring strategies.
+func (s *RecommendationService) Strategies() []ScoringStrategy {
+	return s.strategies.All()
+}
+
+// GetRecommendations ranks tickers as query asks. The buy side keeps
+// tickers scoring above zero, best first; the sell side those below zero,
+// most bearish first. Filters apply before ranking, so a page holds the
+// best of the tickers that match. Diversification limits pass over names
+// that would concentrate the list and report them as skipped.
+func (s *RecommendationService) GetRecommendations(ctx context.Context, query RecommendationQuery) (*models.RecommendationList, error) {
+	if query.Strategy == "" {
+		query.Strategy = DefaultStrategyName
+	}
+	if query.Side == "" {
+		query.Side = models.SideBuy
+	}
+	if query.Side != models.SideBuy && query.Side != models.SideSell {
+		return nil, fmt.Errorf("%w %q", ErrInvalidSide, query.Side)
+	}
+	diversification := query.Diversification
+	if diversification.MaxPerSector < 0 || diversification.MaxPerIndustry < 0 || diversification.MinSectors < 0 {
+		return nil, fmt.Errorf("%w: limits must not be negative", ErrInvalidDiversification)
+	}
+	if err := query.Filter.validate(); err != nil {
+		return nil, err
+	}
+	if query.Offset < 0 {
+		return nil, fmt.Errorf("%w: offset must not be negative", ErrInvalidCursor)
+	}
+	if query.Offset > 0 && query.Cursor != "" {
+		return nil, fmt.Errorf("%w: use either an offset or a cursor", ErrInvalidCursor)
+	}
+	if diversification.Enabled() && (query.Offset > 0 || query.Cursor != "") {
+		return nil, fmt.Errorf("%w: diversified lists cannot be paged", ErrInvalidCursor)
+	}
+	if (diversification.Enabled() || len(query.Filter.Sectors) > 0) && (s.securities == nil || !s.securities.HasSectors()) {
+		return nil, ErrNoSectorData
+	}
+	strategy, err := s.strategies.Get(query.Strategy)
 	if err != nil {
 		return nil, err
 	}
-
-	// Group by ticker and keep only the most recent
-	tickerMap := make(map[string]models.Stock)
-	for _, stock := range stocks {
-		existing, exists := tickerMap[stock.Ticker]
-		if !exists || stock.Time.After(existing.Time) {
-			tickerMap[stock.Ticker] = stock
-		}
+	// One profile for the whole ranking, even if it is replaced meanwhile
+	profile := s.profiles.Active()
+	params := ScoringParams{Profile: profile, Now: s.now()}
+	if s.credibility != nil {
+		params.Credibility = s.credibility.Weights()
+	}
+	if s.anomalies != nil {
+		params.Anomalies = s.anomalies.Signals()
 	}
 
-	// Calculate scores for unique tickers
-	var recommendations []models.StockRecommendation
-	for _, stock := range tickerMap {
-		// IMPORTANT: copy loop variable before taking address to avoid pointer aliasing
-		stockCopy := stock
-		score, reason := s.calculateScore(&stockCopy)
-		// Only include stocks with meaningful scores
-		if score > 0 {
-			recommendations = append(recommendations, models.StockRecommendation{
-				Stock:  &stockCopy,
-				Score:  score,
-				Reason: reason,
-			})
+	// The consensus table already holds the most recent event of every
+	// ticker, so the whole universe is one row per ticker
+	tickers, err := allConsensus(ctx, s.stockService)
+	if err != nil {
+		return nil, err
+	}
+	filter := newConsensusFilter(&query.Filter, s.securities, params.Now)
+	matching := tickers[:0]
+	for i := range tickers {
+		if filter.matches(&tickers[i]) {
+			matching = append(matching, tickers[i])
 		}
 	}
 
-	// Sort by score descending
-	sort.Slice(recommendations, func(i, j int) bool {
-		if recommendations[i].Score == recommendations[j].Score {
-			// Secondary sort by ticker for stable ordering
-			return recommendations[i].Stock.Ticker < recommendations[j].Stock.Ticker
+	// Rank everything that matches: pages, and diversification replacing
+	// skipped names, reach further down than the limit
+	ranked := rankTickers(matching, strategy, params, query.Side, len(matching))
+	if query.Filter.MinScore > 0 {
+		sign := 1.0
+		if query.Side == models.SideSell {
+			sign = -1
 		}
-		return recommendations[i].Score > recommendations[j].Score
-	})
-
-	// Return top N recommendations
-	if len(recommendations) > limit {
-		recommendations = recommendations[:limit]
+		strong := ranked[:0]
+		for _, recommendation := range ranked {
+			if recommendation.Score*sign >= query.Filter.MinScore {
+				strong = append(strong, recommendation)
+			}
+		}
+		ranked = strong
 	}
 
-	return recommendations, nil
-}
-
-func (s *RecommendationService) calculateScore(stock *models.Stock) (float64, string) {
-	var score float64
-	var reasons []string
-
-	// Extract price change from target_from and target_to
-	targetFrom := s.parsePrice(stock.TargetFrom)
-	targetTo := s.parsePrice(stock.TargetTo)
-	var changePerc float64
-	if targetFrom > 0 {
-		changePerc = ((targetTo - targetFrom) / targetFrom) * 100
+	list := &models.RecommendationList{
+		Side:            query.Side,
+		Strategy:        strategy.Name(),
+		StrategyVersion: strategy.Version(),
+		ProfileVersion:  profile.Version,
+		Total:           len(ranked),
 	}
-
-	// Factor 1: Target price increase (weight: 40%)
-	if changePerc > 0 {
-		score += math.Min(changePerc*4, 40)
-		reasons = append(reasons, "target price increased")
+	if diversification.Enabled() {
+		list.Diversification = &diversification
+		list.Data, list.Skipped = diversify(s.withSectors(ranked), diversification, query.Limit)
+		return list, nil
 	}
 
-	// Factor 2: Rating improvement (weight: 30%)
-	ratingScore := s.getRatingScore(stock.RatingFrom, stock.RatingTo)
-	score += ratingScore
-	if ratingScore >= 30 {
-		reasons = append(reasons, "rating upgraded")
-	} else if ratingScore >= 20 {
-		reasons = append(reasons, "strong rating initiated")
-	} else if ratingScore >= 10 {
-		reasons = append(reasons, "positive rating maintained")
+	start := min(query.Offset, len(ranked))
+	if query.Cursor != "" {
+		if start, err = cursorStart(ranked, query.Cursor, query.Side); err != nil {
+			return nil, err
+		}
 	}
+	end := min(start+max(query.Limit, 0), len(ranked))
+	list.Offset = start
+	list.Data = s.withSectors(ranked[start:end])
+	if end < len(ranked) && end > start {
+		list.NextCursor = encodeCursor(&list.Data[len(list.Data)-1])
+	}
+	return list, nil
+}
 
-	// Factor 3: Action type (weight: 20%)
-	actionScore := s.getActionScore(stock.Action)
-	score += actionScore
-	if actionScore > 10 {
-		reasons = append(reasons, "positive analyst action")
+// withSectors fills in the sector and industry of each recommendation.
+func (s *RecommendationService) withSectors(recommendations []models.StockRecommendation) []models.StockRecommendation {
+	if s.securities == nil {
+		return recommendations
 	}
+	for i := range recommendations {
+		if security, ok := s.securities.Lookup(recommendations[i].Stock.Ticker); ok {
+			recommendations[i].Sector = security.Sector
+			recommendations[i].Industry = security.Industry
+		}
+	}
+	return recommendations
+}
 
-	// Bonus: Momentum synergy (up to +10)
-	// Reward strong alignment when there is a big target hike and an upgrade action/rating
-	actionLower := strings.ToLower(stock.Action)
-	if changePerc >= 50 && (ratingScore >= 30) && (strings.Contains(actionLower, "upgraded") || strings.Contains(actionLower, "raised")) {
-		score += 10
-		reasons = append(reasons, "strong multi-signal momentum")
-	} else if changePerc >= 25 && (ratingScore >= 30 || strings.Contains(actionLower, "upgraded") || strings.Contains(actionLower, "raised")) {
-		score += 5
-		reasons = append(reasons, "strong momentum")
+// diversify walks a ranking in order and keeps up to limit names, passing
+// over those that would exceed a sector or industry limit. Names without a
+// sector or industry are never held back by that limit. Once the remaining
+// places are only enough to reach MinSectors, they are kept for sectors not
+// yet in the list; MinSectors is lowered to the sectors the ranking has.
+func diversify(ranked []models.StockRecommendation, limits models.Diversification, limit int) ([]models.StockRecommendation, []models.SkippedRecommendation) {
+	minSectors := limits.MinSectors
+	available := map[string]bool{}
+	for _, recommendation := range ranked {
+		if recommendation.Sector != "" {
+			available[recommendation.Sector] = true
+		}
 	}
+	minSectors = min(minSectors, len(available), limit)
 
-	// Normalize score to 0-100
-	score = math.Min(score, 100)
-	score = math.Max(score, 0)
+	picked := []models.StockRecommendation{}
+	var skipped []models.SkippedRecommendation
+	sectors := map[string]int{}
+	industries := map[string]int{}
+	for _, recommendation := range ranked {
+		if len(picked) >= limit {
+			break
+		}
 
-	reason := "Good fundamentals"
-	if len(reasons) > 0 {
-		reason = strings.Join(reasons, ", ")
-	}
+		sector, industry := recommendation.Sector, recommendation.Industry
+		var reason string
+		switch {
+		case limits.MaxPerSector > 0 && sector != "" && sectors[sector] >= limits.MaxPerSector:
+			reason = fmt.Sprintf("sector %s is at its limit of %d", sector, limits.MaxPerSector)
+		case limits.MaxPerIndustry > 0 && industry != "" && industries[industry] >= limits.MaxPerIndustry:
+			reason = fmt.Sprintf("industry %s is at its limit of %d", industry, limits.MaxPerIndustry)
+		case len(sectors) < minSectors && limit-len(picked) <= minSectors-len(sectors) && (sector == "" || sectors[sector] > 0):
+			reason = fmt.Sprintf("remaining places are kept for new sectors (%d of %d listed)", len(sectors), minSectors)
+		}
+		if reason != "" {
+			skipped = append(skipped, models.SkippedRecommendation{
+				Ticker:   recommendation.Stock.Ticker,
+				Score:    recommendation.Score,
+				Sector:   sector,
+				Industry: industry,
+				Reason:   reason,
+			})
+			continue
+		}
 
-	return score, reason
+		picked = append(picked, recommendation)
+		if sector != "" {
+			sectors[sector]++
+		}
+		if industry != "" {
+			industries[industry]++
+		}
+	}
+	return picked, skipped
 }
 
-func (s *RecommendationService) parsePrice(priceStr string) float64 {
-	// Remove $ and any whitespace
-	cleaned := strings.TrimSpace(strings.ReplaceAll(priceStr, "$", ""))
-	price, err := strconv.ParseFloat(cleaned, 64)
-	if err != nil {
-		return 0
+// rankTickers scores every ticker and returns the limit strongest of them
+// on side.
+func rankTickers(tickers []models.TickerConsensus, strategy ScoringStrategy, params ScoringParams, side string, limit int) []models.StockRecommendation {
+	// The sell side ranks by how bearish a ticker is
+	sign := 1.0
+	if side == models.SideSell {
+		sign = -1
 	}
-	return price
-}
 
-func (s *RecommendationService) getRatingScore(ratingFrom, ratingTo string) float64 {
-	ratings := map[string]int{
-		"Strong Buy":     5,
-		"Buy":            4,
-		"Outperform":     4,
-		"Hold":           3,
-		"Market Perform": 3,
-		"Neutral":        3,
-		"Equal Weight":   3,
-		"Underperform":   2,
-		"Underweight":    2,
-		"Sell":           1,
-	}
-
-	scoreFrom := ratings[ratingFrom]
-	scoreTo := ratings[ratingTo]
-
-	// Only give points if there was an actual upgrade or strong positive rating
-	if scoreTo > scoreFrom {
-		return 30 // Upgraded
-	} else if scoreTo >= 4 && scoreFrom == 0 {
-		// New coverage with strong rating (no previous rating)
-		return 20
-	} else if scoreTo == scoreFrom && scoreTo >= 4 {
-		// Maintained strong rating (reiterated)
-		return 10
-	}
-	return 0
-}
+	// Calculate scores for unique tickers
+	recommendations := []models.StockRecommendation{}
+	for i := range tickers {
+		consensus := &tickers[i]
+		if consensus.Latest == nil {
+			continue
+		}
+		result := strategy.Score(consensus, params)
+		// Only include stocks with meaningful scores on this side
+		if result.Score*sign > 0 {
+			recommendations = append(recommendations, models.StockRecommendation{
+				Stock:   consensus.Latest,
+				Score:   result.Score,
+				Reason:  result.Reason,
+				Factors: result.Factors,
+			})
+		}
+	}
+
+	// Sort by score descending (ascending on the sell side)
+	sort.Slice(recommendations, func(i, j int) bool {
+		if recommendations[i].Score == recommendations[j].Score {
+			// Secondary sort by ticker for stable ordering
+			return recommendations[i].Stock.Ticker < recommendations[j].Stock.Ticker
+		}
+		return recommendations[i].Score*sign > recommendations[j].Score*sign
+	})
 
-func (s *RecommendationService) getActionScore(action string) float64 {
-	action = strings.ToLower(action)
-	if strings.Contains(action, "raised") || strings.Contains(action, "upgraded") {
-		return 20
-	} else if strings.Contains(action, "initiated") && !strings.Contains(action, "lowered") {
-		return 15
-	} else if strings.Contains(action, "reiterated") {
-		return 10
+	// Return top N recommendations
+	if len(recommendations) > limit {
+		recommendations = recommendations[:limit]
 	}
-	return 5
+
+	return recommendations
 }
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+)
+
+// ranking builds a ranking from "TICKER:sector/industry" entries, best first.
+func ranking(entries ...string) []models.StockRecommendation {
+	var ranked []models.StockRecommendation
+	for i, entry := range entries {
+		ticker, classification, _ := strings.Cut(entry, ":")
+		sector, industry, _ := strings.Cut(classification, "/")
+		ranked = append(ranked, models.StockRecommendation{
+			Stock:    &models.Stock{Ticker: ticker},
+			Score:    float64(100 - i),
+			Sector:   sector,
+			Industry: industry,
+		})
+	}
+	return ranked
+}
+
+func tickersOf(recommendations []models.StockRecommendation) string {
+	var tickers []string
+	for _, recommendation := range recommendations {
+		tickers = append(tickers, recommendation.Stock.Ticker)
+	}
+	return strings.Join(tickers, " ")
+}
+
+func TestDiversify(t *testing.T) {
+	tests := []struct {
+		name    string
+		ranked  []models.StockRecommendation
+		limits  models.Diversification
+		limit   int
+		want    string
+		skipped string
+	}{
+		{
+			name:   "no limits keeps the ranking",
+			ranked: ranking("A:Tech/Software", "B:Tech/Software", "C:Tech/Software"),
+			limit:  2,
+			want:   "A B",
+		},
+		{
+			name:    "sector limit passes over the excess",
+			ranked:  ranking("A:Tech/Software", "B:Tech/Chips", "C:Tech/Software", "D:Health/Pharma", "E:Tech/Chips", "F:Energy/Oil"),
+			limits:  models.Diversification{MaxPerSector: 2},
+			limit:   4,
+			want:    "A B D F",
+			skipped: "C: sector Tech is at its limit of 2; E: sector Tech is at its limit of 2",
+		},
+		{
+			name:    "industry limit",
+			ranked:  ranking("A:Tech/Software", "B:Tech/Software", "C:Tech/Chips"),
+			limits:  models.Diversification{MaxPerIndustry: 1},
+			limit:   3,
+			want:    "A C",
+			skipped: "B: industry Software is at its limit of 1",
+		},
+		{
+			name:   "unclassified names are never held back",
+			ranked: ranking("A:", "B:", "C:", "D:Tech/Software"),
+			limits: models.Diversification{MaxPerSector: 1, MaxPerIndustry: 1},
+			limit:  4,
+			want:   "A B C D",
+		},
+		{
+			name:    "last places are kept for new sectors",
+			ranked:  ranking("A:Tech/Software", "B:Tech/Software", "C:Tech/Chips", "D:Tech/Chips", "E:Health/Pharma", "F:Health/Biotech", "G:Energy/Oil"),
+			limits:  models.Diversification{MinSectors: 3},
+			limit:   4,
+			want:    "A B E G",
+			skipped: "C: remaining places are kept for new sectors (1 of 3 listed); D: remaining places are kept for new sectors (1 of 3 listed); F: remaining places are kept for new sectors (2 of 3 listed)",
+		},
+		{
+			name:   "minimum sectors lowered to what the ranking has",
+			ranked: ranking("A:Tech/Software", "B:Tech/Software", "C:", "D:Tech/Chips"),
+			limits: models.Diversification{MinSectors: 3},
+			limit:  3,
+			want:   "A B C",
+		},
+		{
+			name:   "minimum sectors lowered to the limit",
+			ranked: ranking("A:Tech/Software", "B:Health/Pharma", "C:Energy/Oil"),
+			limits: models.Diversification{MinSectors: 5},
+			limit:  2,
+			want:   "A B",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			picked, skipped := diversify(tt.ranked, tt.limits, tt.limit)
+			if got := tickersOf(picked); got != tt.want {
+				t.Errorf("picked %q, want %q", got, tt.want)
+			}
+			var reasons []string
+			for _, skip := range skipped {
+				reasons = append(reasons, fmt.Sprintf("%s: %s", skip.Ticker, skip.Reason))
+			}
+			if got := strings.Join(reasons, "; "); got != tt.skipped {
+				t.Errorf("skipped %q, want %q", got, tt.skipped)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository"
+)
+
+// RetentionResult reports what a retention pass did (or would do) to one table.
+type RetentionResult struct {
+	Table       string
+	MaxAge      time.Duration
+	Cutoff      time.Time
+	Expired     int
+	Deleted     int
+	ArchivePath string
+	RowLevelTTL bool
+}
+
+type RetentionService struct {
+	repo       *repository.RetentionRepository
+	rules      map[string]time.Duration
+	archiveDir string
+	now        func() time.Time
+}
+
+// NewRetentionService enforces rules (table name to maximum age). When
+// archiveDir is set, pruned rows are first written there as gzip-compressed
+// JSON lines.
+func NewRetentionService(repo *repository.RetentionRepository, rules map[string]time.Duration, archiveDir string) (*RetentionService, error) {
+	for table, maxAge := range rules {
+		if _, ok := repository.RetentionTargets[table]; !ok {
+			return nil, fmt.Errorf("retention rule for unknown table %q", table)
+		}
+		if maxAge <= 0 {
+			return nil, fmt.Errorf("retention for %s must be positive", table)
+		}
+	}
+
+	return &RetentionService{
+		repo:       repo,
+		rules:      rules,
+		archiveDir: archiveDir,
+		now:        time.Now,
+	}, nil
+}
+
+// Prune applies every rule once. With dryRun it only counts expired rows.
+func (s *RetentionService) Prune(ctx context.Context, dryRun bool) ([]RetentionResult, error) {
+	var results []RetentionResult
+
+	for _, table := range s.tables() {
+		maxAge := s.rules[table]
+		result := RetentionResult{
+			Table:  table,
+			MaxAge: maxAge,
+			Cutoff: s.now().Add(-maxAge).UTC(),
+		}
+
+		expired, err := s.repo.CountExpired(ctx, table, result.Cutoff)
+		if err != nil {
+			return results, fmt.Errorf("error counting expired %s: %w", table, err)
+		}
+		result.Expired = expired
+
+		if dryRun || expired == 0 {
+			results = append(results, result)
+			continue
+		}
+
+		var archive repository.ArchiveFunc
+		var archiveFile *gzipArchive
+		if s.archiveDir != "" {
+			archiveFile, err = newGzipArchive(s.archiveDir, table, s.now())
+			if err != nil {
+				return results, err
+			}
+			archive = archiveFile.Write
+			result.ArchivePath = archiveFile.path
+		}
+
+		result.Deleted, err = s.repo.DeleteExpired(ctx, table, result.Cutoff, archive)
+		if archiveFile != nil {
+			if closeErr := archiveFile.Close(); closeErr != nil && err == nil {
+				err = closeErr
+			}
+		}
+		results = append(results, result)
+		if err != nil {
+			return results, err
+		}
+
+		log.Printf("Retention: deleted %d %s rows older than %s", result.Deleted, table, result.Cutoff.Format(time.RFC3339))
+	}
+
+	return results, nil
+}
+
+// ApplyRowLevelTTL hands the rules to CockroachDB row-level TTL instead of
+// deleting rows from Go. TTL deletes cannot be archived, so it is refused
+// when an archive directory is configured, and they would leave the
+// consensus of pruned tickers stale, so it is refused for stocks.
+func (s *RetentionService) ApplyRowLevelTTL(ctx context.Context) ([]RetentionResult, error) {
+	if s.archiveDir != "" {
+		return nil, fmt.Errorf("row-level TTL cannot archive pruned rows; unset the archive directory or prune from Go")
+	}
+	for _, table := range s.tables() {
+		if repository.RetentionTargets[table].Consensus {
+			return nil, fmt.Errorf("row-level TTL cannot update ticker_consensus when %s expire; prune them from Go", table)
+		}
+	}
+
+	supported, err := s.repo.SupportsRowLevelTTL(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !supported {
+		return nil, fmt.Errorf("row-level TTL requires CockroachDB")
+	}
+
+	var results []RetentionResult
+	for _, table := range s.tables() {
+		if err := s.repo.ApplyRowLevelTTL(ctx, table, s.rules[table]); err != nil {
+			return results, err
+		}
+		results = append(results, RetentionResult{Table: table, MaxAge: s.rules[table], RowLevelTTL: true})
+	}
+	return results, nil
+}
+
+// StartBackground prunes every interval until ctx is cancelled.
+func (s *RetentionService) StartBackground(ctx context.Context, interval time.Duration) {
+	if interval <= 0 || len(s.rules) == 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := s.Prune(ctx, false); err != nil {
+				log.Printf("Retention error: %v", err)
+			}
+
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	log.Printf("Retention job scheduled every %s", interval)
+}
+
+func (s *RetentionService) tables() []string {
+	tables := make([]string, 0, len(s.rules))
+	for table := range s.rules {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	return tables
+}
+
+// gzipArchive writes pruned rows as one JSON object per line.
+type gzipArchive struct {
+	path    string
+	file    *os.File
+	gz      *gzip.Writer
+	encoder *json.Encoder
+}
+
+func newGzipArchive(dir, table string, now time.Time) (*gzipArchive, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating archive directory: %w", err)
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.jsonl.gz", table, now.UTC().Format("20060102T150405Z")))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("error creating archive file: %w", err)
+	}
+
+	gz := gzip.NewWriter(file)
+	return &gzipArchive{path: path, file: file, gz: gz, encoder: json.NewEncoder(gz)}, nil
+}
+
+func (a *gzipArchive) Write(rows []map[string]interface{}) error {
+	for _, row := range rows {
+		if err := a.encoder.Encode(row); err != nil {
+			return err
+		}
+	}
+	// Flush so rows are on disk before the batch is deleted
+	if err := a.gz.Flush(); err != nil {
+		return err
+	}
+	return a.file.Sync()
+}
+
+func (a *gzipArchive) Close() error {
+	if err := a.gz.Close(); err != nil {
+		a.file.Close()
+		return err
+	}
+	return a.file.Close()
+}
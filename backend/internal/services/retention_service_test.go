@@ -0,0 +1,153 @@
+package services
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository"
+)
+
+// newMigratedDatabase opens an in-memory SQLite database with every
+// migration applied, closed when the test ends.
+func newMigratedDatabase(t testing.TB) *repository.Database {
+	t.Helper()
+	db, err := repository.NewDatabase("sqlite://:memory:", repository.DatabaseOptions{})
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := repository.NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrating: %v", err)
+	}
+	return db
+}
+
+func newRetentionFixture(t *testing.T, now time.Time) *repository.Database {
+	t.Helper()
+	db := newMigratedDatabase(t)
+	stocks := repository.NewStockRepository(db)
+	for i, age := range []time.Duration{400 * 24 * time.Hour, 200 * 24 * time.Hour, time.Hour} {
+		stock := &models.Stock{
+			ID:         string(rune('a' + i)),
+			Ticker:     "AAPL",
+			Company:    "Apple Inc.",
+			Brokerage:  "Example Securities",
+			Action:     "target raised by",
+			TargetFrom: "$10.00",
+			TargetTo:   "$12.00",
+			RatingFrom: "Hold",
+			RatingTo:   "Buy",
+			Time:       now.Add(-age),
+		}
+		if err := stocks.Create(context.Background(), stock, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestRetentionDryRunOnlyCounts(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	db := newRetentionFixture(t, now)
+
+	service, err := NewRetentionService(repository.NewRetentionRepository(db), map[string]time.Duration{"stocks": 365 * 24 * time.Hour, "sync_runs": time.Hour}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.now = func() time.Time { return now }
+
+	results, err := service.Prune(context.Background(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Table != "stocks" || results[1].Table != "sync_runs" {
+		t.Fatalf("results %+v, want stocks then sync_runs", results)
+	}
+	if results[0].Expired != 1 || results[0].Deleted != 0 {
+		t.Errorf("stocks dry run expired %d, deleted %d; want 1, 0", results[0].Expired, results[0].Deleted)
+	}
+	if !results[0].Cutoff.Equal(now.Add(-365 * 24 * time.Hour)) {
+		t.Errorf("cutoff %s, want a year before %s", results[0].Cutoff, now)
+	}
+	if count, _ := repository.NewStockRepository(db).Count(context.Background()); count != 3 {
+		t.Errorf("dry run left %d events, want all 3", count)
+	}
+}
+
+func TestRetentionPruneArchivesBeforeDeleting(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	db := newRetentionFixture(t, now)
+
+	service, err := NewRetentionService(repository.NewRetentionRepository(db), map[string]time.Duration{"stocks": 100 * 24 * time.Hour}, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.now = func() time.Time { return now }
+
+	results, err := service.Prune(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Expired != 2 || results[0].Deleted != 2 {
+		t.Errorf("expired %d, deleted %d; want 2, 2", results[0].Expired, results[0].Deleted)
+	}
+
+	file, err := os.Open(results[0].ArchivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoder := json.NewDecoder(gz)
+	var archived []string
+	for decoder.More() {
+		var row map[string]interface{}
+		if err := decoder.Decode(&row); err != nil {
+			t.Fatal(err)
+		}
+		archived = append(archived, row["id"].(string))
+	}
+	if len(archived) != 2 || archived[0] != "a" || archived[1] != "b" {
+		t.Errorf("archived %v, want [a b]", archived)
+	}
+
+	consensus, err := repository.NewStockRepository(db).GetConsensus(context.Background(), "AAPL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if consensus.LatestStockID != "c" {
+		t.Errorf("consensus points at %s after prune, want c", consensus.LatestStockID)
+	}
+}
+
+func TestRetentionRejectsRules(t *testing.T) {
+	repo := repository.NewRetentionRepository(nil)
+	if _, err := NewRetentionService(repo, map[string]time.Duration{"screens": time.Hour}, ""); err == nil {
+		t.Error("a rule for a table without retention should be rejected")
+	}
+	if _, err := NewRetentionService(repo, map[string]time.Duration{"stocks": 0}, ""); err == nil {
+		t.Error("a zero maximum age should be rejected")
+	}
+
+	// Row-level TTL would leave ticker_consensus pointing at expired events
+	service, err := NewRetentionService(repo, map[string]time.Duration{"stocks": time.Hour}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.ApplyRowLevelTTL(context.Background()); err == nil {
+		t.Error("row-level TTL on stocks should be refused")
+	}
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+)
+
+// ErrUnknownStrategy is returned when a request names a strategy that is not
+// registered.
+var ErrUnknownStrategy = errors.New("unknown scoring strategy")
+
+// maxScore is the highest score a strategy can give.
+const maxScore = 100
+
+// ScoreResult is what a strategy concluded about one ticker.
+type ScoreResult struct {
+	Score   float64
+	Reason  string
+	Factors []models.ScoreFactor
+}
+
+// newScoreResult adds up the factors into a score. A total beyond
+// ±maxScore gets a score_cap factor taking off the excess, so the factors
+// always sum to the score, and the reason lists the labels of the factors
+// that earned or cost points, or fallback when none did.
+func newScoreResult(factors []models.ScoreFactor, fallback string) ScoreResult {
+	var score float64
+	var reasons []string
+	for _, factor := range factors {
+		score += factor.Points
+		if factor.Points != 0 {
+			reasons = append(reasons, factor.Label)
+		}
+	}
+
+	if math.Abs(score) > maxScore {
+		factors = append(factors, models.ScoreFactor{
+			Name:   "score_cap",
+			Label:  "score capped",
+			Input:  fmt.Sprintf("%.1f", score),
+			Weight: 1,
+			Points: math.Copysign(maxScore, score) - score,
+			Cap:    capOf(maxScore),
+		})
+		// Added like any other factor so the sum matches bit for bit
+		score += factors[len(factors)-1].Points
+	}
+
+	reason := fallback
+	if len(reasons) > 0 {
+		reason = strings.Join(reasons, ", ")
+	}
+	return ScoreResult{Score: score, Reason: reason, Factors: factors}
+}
+
+func capOf(points float64) *float64 {
+	return &points
+}
+
+// ScoringParams are the settings of one ranking, shared by every ticker in it.
+type ScoringParams struct {
+	Profile *ScoringProfile
+	// Now is the moment events are aged against
+	Now time.Time
+	// Credibility weighs each brokerage's signals by brokerage id; brokerages
+	// without an entry weigh 1
+	Credibility map[string]float64
+	// Anomalies holds the z-score of each ticker's flagged swing in analyst
+	// direction, positive when bullish
+	Anomalies map[string]float64
+}
+
+// Weight is the credibility of a brokerage's signals.
+func (p ScoringParams) Weight(brokerage string) float64 {
+	if weight, ok := p.Credibility[models.BrokerageID(brokerage)]; ok {
+		return weight
+	}
+	return 1
+}
+
+// Age returns how old an event at t is, never negative.
+func (p ScoringParams) Age(t time.Time) time.Duration {
+	if age := p.Now.Sub(t); age > 0 {
+		return age
+	}
+	return 0
+}
+
+// Expired reports whether an event at t is past the profile's maximum age.
+func (p ScoringParams) Expired(t time.Time) bool {
+	maxAge := p.Profile.Decay.MaxAgeDays
+	return maxAge > 0 && p.Age(t) > time.Duration(maxAge*24*float64(time.Hour))
+}
+
+// Decay is the share of its points a factor keeps for an event at t.
+func (p ScoringParams) Decay(t time.Time, halfLifeDays float64) float64 {
+	if halfLifeDays <= 0 {
+		return 1
+	}
+	return math.Pow(0.5, p.Age(t).Hours()/24/halfLifeDays)
+}
+
+// anomalyFactor scores a flagged swing in the direction of the ticker's
+// analyst events, when the profile gives anomalies points.
+func anomalyFactor(ticker string, params ScoringParams) (models.ScoreFactor, bool) {
+	weights := params.Profile.Anomaly
+	z, ok := params.Anomalies[ticker]
+	if !ok || weights.PointsPerZ == 0 {
+		return models.ScoreFactor{}, false
+	}
+
+	label := "unusual bullish analyst activity"
+	if z < 0 {
+		label = "unusual bearish analyst activity"
+	}
+	return models.ScoreFactor{
+		Name:   "anomaly",
+		Label:  label,
+		Input:  fmt.Sprintf("z-score %+.1f", z),
+		Weight: weights.PointsPerZ,
+		Points: math.Copysign(math.Min(math.Abs(z)*weights.PointsPerZ, weights.Cap), z),
+		Cap:    capOf(weights.Cap),
+	}, true
+}
+
+// ratingRevision is 1 for an upgrade, -1 for a downgrade and 0 otherwise,
+// judged by the rating scale and, when the ratings say nothing, the action
+// text.
+func ratingRevision(ratingFrom, ratingTo, action string, weights *RatingWeights) int {
+	from, fromKnown := weights.Scale[ratingFrom]
+	to, toKnown := weights.Scale[ratingTo]
+	if fromKnown && toKnown && from != to {
+		if to > from {
+			return 1
+		}
+		return -1
+	}
+
+	action = strings.ToLower(action)
+	switch {
+	case strings.Contains(action, "upgraded"):
+		return 1
+	case strings.Contains(action, "downgraded"):
+		return -1
+	}
+	return 0
+}
+
+// ScoringStrategy turns what is known about a ticker into a score from -100
+// (strongly bearish) to 100 (strongly bullish), using the weights of
+// params.Profile. Name identifies it in ?strategy= and Version changes
+// whenever the formula does, so stored or cached scores can be told apart.
+type ScoringStrategy interface {
+	Name() string
+	Version() string
+	Score(consensus *models.TickerConsensus, params ScoringParams) ScoreResult
+}
+
+// StrategyRegistry holds the strategies recommendations can be ranked with.
+type StrategyRegistry struct {
+	mu         sync.RWMutex
+	strategies map[string]ScoringStrategy
+}
+
+func NewStrategyRegistry(strategies ...ScoringStrategy) (*StrategyRegistry, error) {
+	registry := &StrategyRegistry{strategies: make(map[string]ScoringStrategy)}
+	for _, strategy := range strategies {
+		if err := registry.Register(strategy); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
+// Register adds a strategy; names must be unique.
+func (r *StrategyRegistry) Register(strategy ScoringStrategy) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name := strategy.Name()
+	if name == "" {
+		return fmt.Errorf("scoring strategy must have a name")
+	}
+	if _, exists := r.strategies[name]; exists {
+		return fmt.Errorf("scoring strategy %q is already registered", name)
+	}
+	r.strategies[name] = strategy
+	return nil
+}
+
+func (r *StrategyRegistry) Get(name string) (ScoringStrategy, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	strategy, exists := r.strategies[name]
+	if !exists {
+		return nil, fmt.Errorf("%w %q", ErrUnknownStrategy, name)
+	}
+	return strategy, nil
+}
+
+// All returns the registered strategies ordered by name.
+func (r *StrategyRegistry) All() []ScoringStrategy {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	strategies := make([]ScoringStrategy, 0, len(r.strategies))
+	for _, strategy := range r.strategies {
+		strategies = append(strategies, strategy)
+	}
+	sort.Slice(strategies, func(i, j int) bool {
+		return strategies[i].Name() < strategies[j].Name()
+	})
+	return strategies
+}
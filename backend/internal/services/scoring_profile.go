@@ -0,0 +1,374 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidProfile wraps every validation failure of a scoring profile.
+var ErrInvalidProfile = errors.New("invalid scoring profile")
+
+// ErrProfileVersionConflict is returned by Replace for a profile that keeps
+// the active version but changes its values.
+var ErrProfileVersionConflict = errors.New("scoring profile version already in use with different values")
+
+// ScoringProfile holds the weights and cutoffs of the scoring formulas.
+// Version must change whenever any value does, since responses echo it.
+type ScoringProfile struct {
+	Version   string           `json:"version" yaml:"version"`
+	Target    TargetWeights    `json:"target" yaml:"target"`
+	Rating    RatingWeights    `json:"rating" yaml:"rating"`
+	Action    ActionWeights    `json:"action" yaml:"action"`
+	Momentum  MomentumWeights  `json:"momentum" yaml:"momentum"`
+	Decay     DecayWeights     `json:"decay" yaml:"decay"`
+	Consensus ConsensusWeights `json:"consensus" yaml:"consensus"`
+	Anomaly   AnomalyWeights   `json:"anomaly" yaml:"anomaly"`
+}
+
+// TargetWeights scores the target price change in percent.
+type TargetWeights struct {
+	Multiplier float64 `json:"multiplier" yaml:"multiplier"`
+	Cap        float64 `json:"cap" yaml:"cap"`
+}
+
+// RatingWeights scores the move from rating_from to rating_to on Scale.
+type RatingWeights struct {
+	Scale map[string]int `json:"scale" yaml:"scale"`
+	// StrongLevel is the lowest scale level that counts as a strong rating
+	// and WeakLevel the highest that counts as a weak one (0: none does)
+	StrongLevel int     `json:"strong_level" yaml:"strong_level"`
+	WeakLevel   int     `json:"weak_level" yaml:"weak_level"`
+	Upgrade     float64 `json:"upgrade" yaml:"upgrade"`
+	NewStrong   float64 `json:"new_strong" yaml:"new_strong"`
+	Reiterated  float64 `json:"reiterated" yaml:"reiterated"`
+}
+
+// ActionWeights scores the action text by the keywords it contains.
+type ActionWeights struct {
+	RaisedOrUpgraded float64 `json:"raised_or_upgraded" yaml:"raised_or_upgraded"`
+	Initiated        float64 `json:"initiated" yaml:"initiated"`
+	Reiterated       float64 `json:"reiterated" yaml:"reiterated"`
+	Other            float64 `json:"other" yaml:"other"`
+	// PositiveAbove is the action score above which the reason mentions it
+	PositiveAbove float64 `json:"positive_above" yaml:"positive_above"`
+}
+
+// MomentumWeights adds a bonus when a big target hike lines up with an
+// upgrade.
+type MomentumWeights struct {
+	StrongChangePct float64 `json:"strong_change_pct" yaml:"strong_change_pct"`
+	StrongBonus     float64 `json:"strong_bonus" yaml:"strong_bonus"`
+	ChangePct       float64 `json:"change_pct" yaml:"change_pct"`
+	Bonus           float64 `json:"bonus" yaml:"bonus"`
+}
+
+// DecayWeights fade a factor's points with the age of the event behind it:
+// after one half-life a factor earns half its points. Zero disables decay
+// for that factor, and MaxAgeDays > 0 ignores events older than that.
+type DecayWeights struct {
+	TargetHalfLifeDays float64 `json:"target_half_life_days" yaml:"target_half_life_days"`
+	RatingHalfLifeDays float64 `json:"rating_half_life_days" yaml:"rating_half_life_days"`
+	ActionHalfLifeDays float64 `json:"action_half_life_days" yaml:"action_half_life_days"`
+	MaxAgeDays         float64 `json:"max_age_days" yaml:"max_age_days"`
+}
+
+// ConsensusWeights score a ticker from every brokerage's latest view. Only
+// views from the last WindowDays count (0 counts them all), and tickers
+// covered by fewer than MinBrokerages brokerages score nothing.
+type ConsensusWeights struct {
+	WindowDays    float64 `json:"window_days" yaml:"window_days"`
+	MinBrokerages int     `json:"min_brokerages" yaml:"min_brokerages"`
+	// Revisions are the points when every view in the window is a net upgrade
+	Revisions float64 `json:"revisions" yaml:"revisions"`
+	// Agreement are the points when every rated brokerage is bullish
+	Agreement        float64 `json:"agreement" yaml:"agreement"`
+	TargetMultiplier float64 `json:"target_multiplier" yaml:"target_multiplier"`
+	TargetCap        float64 `json:"target_cap" yaml:"target_cap"`
+}
+
+// AnomalyWeights score a flagged swing in the direction of a ticker's
+// analyst events: PointsPerZ per standard deviation, up to Cap, bullish or
+// bearish with the swing. Zero leaves anomalies out of scores.
+type AnomalyWeights struct {
+	PointsPerZ float64 `json:"points_per_z" yaml:"points_per_z"`
+	Cap        float64 `json:"cap" yaml:"cap"`
+}
+
+// DefaultScoringProfile holds the weights of the original hard-coded
+// formula, which bullish signals still score by; bearish signals mirror them.
+func DefaultScoringProfile() *ScoringProfile {
+	return &ScoringProfile{
+		Version: "builtin-3",
+		Target:  TargetWeights{Multiplier: 4, Cap: 40},
+		Rating: RatingWeights{
+			Scale: map[string]int{
+				"Strong Buy":     5,
+				"Buy":            4,
+				"Outperform":     4,
+				"Hold":           3,
+				"Market Perform": 3,
+				"Neutral":        3,
+				"Equal Weight":   3,
+				"Underperform":   2,
+				"Underweight":    2,
+				"Sell":           1,
+			},
+			StrongLevel: 4,
+			WeakLevel:   2,
+			Upgrade:     30,
+			NewStrong:   20,
+			Reiterated:  10,
+		},
+		Action: ActionWeights{
+			RaisedOrUpgraded: 20,
+			Initiated:        15,
+			Reiterated:       10,
+			Other:            5,
+			PositiveAbove:    10,
+		},
+		Momentum: MomentumWeights{
+			StrongChangePct: 50,
+			StrongBonus:     10,
+			ChangePct:       25,
+			Bonus:           5,
+		},
+		Consensus: ConsensusWeights{
+			WindowDays:       90,
+			MinBrokerages:    1,
+			Revisions:        40,
+			Agreement:        30,
+			TargetMultiplier: 2,
+			TargetCap:        30,
+		},
+	}
+}
+
+// ParseScoringProfile reads a YAML or JSON profile and validates it.
+func ParseScoringProfile(data []byte) (*ScoringProfile, error) {
+	var profile ScoringProfile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&profile); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProfile, err)
+	}
+	if err := profile.Validate(); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// Validate rejects profiles that would produce negative or inconsistent
+// scores.
+func (p *ScoringProfile) Validate() error {
+	var problems []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(p.Version != "", "version is required")
+
+	check(p.Target.Multiplier >= 0, "target.multiplier must not be negative")
+	check(p.Target.Cap >= 0, "target.cap must not be negative")
+
+	check(len(p.Rating.Scale) > 0, "rating.scale must list at least one rating")
+	for rating, level := range p.Rating.Scale {
+		check(level > 0, "rating.scale[%q] must be positive", rating)
+	}
+	check(p.Rating.StrongLevel > 0, "rating.strong_level must be positive")
+	check(p.Rating.WeakLevel >= 0 && p.Rating.WeakLevel < p.Rating.StrongLevel, "rating.weak_level must be below rating.strong_level")
+	check(p.Rating.Reiterated >= 0, "rating.reiterated must not be negative")
+	check(p.Rating.NewStrong >= p.Rating.Reiterated, "rating.new_strong must be at least rating.reiterated")
+	check(p.Rating.Upgrade >= p.Rating.NewStrong, "rating.upgrade must be at least rating.new_strong")
+
+	check(p.Action.Other >= 0 && p.Action.Reiterated >= 0 && p.Action.Initiated >= 0 && p.Action.RaisedOrUpgraded >= 0,
+		"action scores must not be negative")
+	check(p.Action.PositiveAbove >= 0, "action.positive_above must not be negative")
+
+	check(p.Momentum.ChangePct >= 0, "momentum.change_pct must not be negative")
+	check(p.Momentum.StrongChangePct >= p.Momentum.ChangePct, "momentum.strong_change_pct must be at least momentum.change_pct")
+	check(p.Momentum.Bonus >= 0 && p.Momentum.StrongBonus >= 0, "momentum bonuses must not be negative")
+
+	check(p.Decay.TargetHalfLifeDays >= 0 && p.Decay.RatingHalfLifeDays >= 0 && p.Decay.ActionHalfLifeDays >= 0,
+		"decay half-lives must not be negative")
+	check(p.Decay.MaxAgeDays >= 0, "decay.max_age_days must not be negative")
+
+	check(p.Consensus.WindowDays >= 0, "consensus.window_days must not be negative")
+	check(p.Consensus.MinBrokerages >= 0, "consensus.min_brokerages must not be negative")
+	check(p.Consensus.Revisions >= 0 && p.Consensus.Agreement >= 0, "consensus points must not be negative")
+	check(p.Consensus.TargetMultiplier >= 0 && p.Consensus.TargetCap >= 0, "consensus target weights must not be negative")
+
+	check(p.Anomaly.PointsPerZ >= 0 && p.Anomaly.Cap >= 0, "anomaly weights must not be negative")
+	check(p.Anomaly.PointsPerZ == 0 || p.Anomaly.Cap > 0, "anomaly.cap must be positive when anomaly.points_per_z is")
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %v", ErrInvalidProfile, problems)
+	}
+	return nil
+}
+
+// ScoringProfileStore holds the active profile. It is loaded from path at
+// startup, reloaded when the file changes and can be replaced at runtime;
+// readers always see a complete, validated profile.
+type ScoringProfileStore struct {
+	path string
+
+	mu      sync.RWMutex
+	active  *ScoringProfile
+	modTime time.Time
+}
+
+// NewScoringProfileStore loads the profile at path, or uses
+// DefaultScoringProfile when path is empty.
+func NewScoringProfileStore(path string) (*ScoringProfileStore, error) {
+	store := &ScoringProfileStore{path: path, active: DefaultScoringProfile()}
+	if path == "" {
+		return store, nil
+	}
+	if _, err := store.Reload(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Active returns the profile in effect. Callers must not modify it.
+func (s *ScoringProfileStore) Active() *ScoringProfile {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.active
+}
+
+// Reload reads the profile file again and activates it when valid. An
+// invalid file leaves the active profile in place.
+func (s *ScoringProfileStore) Reload() (*ScoringProfile, error) {
+	if s.path == "" {
+		return s.Active(), nil
+	}
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading scoring profile: %w", err)
+	}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading scoring profile: %w", err)
+	}
+	profile, err := ParseScoringProfile(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", s.path, err)
+	}
+
+	s.mu.Lock()
+	s.active = profile
+	s.modTime = info.ModTime()
+	s.mu.Unlock()
+	return profile, nil
+}
+
+// Replace validates data and activates it, writing it to the profile file
+// first when there is one so that it survives a restart. Responses echo the
+// version, so a profile that changes values under the active version is
+// refused.
+func (s *ScoringProfileStore) Replace(data []byte) (*ScoringProfile, error) {
+	profile, err := ParseScoringProfile(data)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if profile.Version == s.active.Version && !reflect.DeepEqual(profile, s.active) {
+		return nil, fmt.Errorf("%w: %s", ErrProfileVersionConflict, profile.Version)
+	}
+
+	if s.path != "" {
+		modTime, err := writeFileAtomic(s.path, data)
+		if err != nil {
+			return nil, fmt.Errorf("error saving scoring profile: %w", err)
+		}
+		s.modTime = modTime
+	}
+	s.active = profile
+	return profile, nil
+}
+
+// StartWatching reloads the profile every interval when its file changed,
+// until ctx is cancelled.
+func (s *ScoringProfileStore) StartWatching(ctx context.Context, interval time.Duration) {
+	if s.path == "" || interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+
+			info, err := os.Stat(s.path)
+			if err != nil {
+				log.Printf("Scoring profile watch error: %v", err)
+				continue
+			}
+			s.mu.RLock()
+			changed := !info.ModTime().Equal(s.modTime)
+			s.mu.RUnlock()
+			if !changed {
+				continue
+			}
+
+			profile, err := s.Reload()
+			if err != nil {
+				// Remember the broken file so it is reported once, not every tick
+				s.mu.Lock()
+				s.modTime = info.ModTime()
+				s.mu.Unlock()
+				log.Printf("Keeping scoring profile %s: %v", s.Active().Version, err)
+				continue
+			}
+			log.Printf("Scoring profile %s loaded from %s", profile.Version, s.path)
+		}
+	}()
+}
+
+func writeFileAtomic(path string, data []byte) (time.Time, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".scoring-profile-*")
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return time.Time{}, err
+	}
+	if err := tmp.Close(); err != nil {
+		return time.Time{}, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return time.Time{}, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
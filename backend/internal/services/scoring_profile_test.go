@@ -0,0 +1,58 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func marshalProfile(t *testing.T, profile *ScoringProfile) []byte {
+	t.Helper()
+	data, err := yaml.Marshal(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestReplaceRejectsChangedValuesUnderActiveVersion(t *testing.T) {
+	store, err := NewScoringProfileStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Re-uploading the active profile unchanged is harmless
+	if _, err := store.Replace(marshalProfile(t, DefaultScoringProfile())); err != nil {
+		t.Fatalf("Replace with the active profile: %v", err)
+	}
+
+	changed := DefaultScoringProfile()
+	changed.Target.Multiplier = 8
+	if _, err := store.Replace(marshalProfile(t, changed)); !errors.Is(err, ErrProfileVersionConflict) {
+		t.Fatalf("Replace with new weights under %s = %v, want ErrProfileVersionConflict", changed.Version, err)
+	}
+	if store.Active().Target.Multiplier != DefaultScoringProfile().Target.Multiplier {
+		t.Error("a refused profile was activated")
+	}
+
+	changed.Version = "custom-1"
+	profile, err := store.Replace(marshalProfile(t, changed))
+	if err != nil {
+		t.Fatalf("Replace with a new version: %v", err)
+	}
+	if store.Active() != profile || profile.Target.Multiplier != 8 {
+		t.Error("the new version was not activated")
+	}
+}
+
+func TestParseScoringProfileRejectsInvalid(t *testing.T) {
+	for name, data := range map[string]string{
+		"unknown field": "version: x\nunknown: 1\n",
+		"not yaml":      "version: [",
+	} {
+		if _, err := ParseScoringProfile([]byte(data)); !errors.Is(err, ErrInvalidProfile) {
+			t.Errorf("%s: ParseScoringProfile = %v, want ErrInvalidProfile", name, err)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+)
+
+func TestNewScoreResultCapsWithAFactor(t *testing.T) {
+	result := newScoreResult([]models.ScoreFactor{
+		{Name: "a", Label: "a", Points: 80},
+		{Name: "b", Label: "b", Points: 50.5},
+		{Name: "c", Label: "c"},
+	}, "fallback")
+
+	if result.Score != 100 {
+		t.Errorf("score %v, want 100", result.Score)
+	}
+	last := result.Factors[len(result.Factors)-1]
+	if last.Name != "score_cap" || last.Points != -30.5 {
+		t.Errorf("last factor %+v, want score_cap taking off 30.5", last)
+	}
+	if result.Reason != "a, b" {
+		t.Errorf("reason %q, want the labels of factors with points", result.Reason)
+	}
+
+	if empty := newScoreResult(nil, "fallback"); empty.Score != 0 || empty.Reason != "fallback" {
+		t.Errorf("no factors = %+v, want score 0 with the fallback reason", empty)
+	}
+}
+
+// TestFactorsSumToScore scores many tickers, with decay, credibility and
+// anomalies all in play, and checks that every score is exactly the sum of
+// its factors.
+func TestFactorsSumToScore(t *testing.T) {
+	profile := DefaultScoringProfile()
+	profile.Decay.TargetHalfLifeDays = 30
+	profile.Decay.RatingHalfLifeDays = 45
+	profile.Decay.ActionHalfLifeDays = 60
+	profile.Anomaly = AnomalyWeights{PointsPerZ: 7, Cap: 25}
+
+	ratings := []string{"", "Sell", "Underweight", "Hold", "Buy", "Strong Buy", "Unrated"}
+	actions := []string{"upgraded by", "downgraded by", "target raised by", "target lowered by", "initiated by", "reiterated by", "target set by"}
+	targets := []string{"", "$8.00", "$10.00", "$13.37", "$25.00", "N/A"}
+	brokerages := []string{"Alpha", "Beta", "Gamma"}
+
+	params := ScoringParams{
+		Profile:     profile,
+		Now:         scoringNow,
+		Credibility: map[string]float64{models.BrokerageID("Alpha"): 1.4, models.BrokerageID("Gamma"): 0.35},
+		Anomalies:   map[string]float64{},
+	}
+	strategies := []ScoringStrategy{NewDefaultStrategy(), NewConsensusStrategy()}
+	capped := 0
+
+	for i := 0; i < 300; i++ {
+		ticker := fmt.Sprintf("T%03d", i)
+		if i%4 == 0 {
+			params.Anomalies[ticker] = float64(i%9) - 4.5
+		}
+
+		var events []models.Stock
+		for j := 0; j <= i%3; j++ {
+			k := i + 7*j
+			events = append(events, models.Stock{
+				ID:         fmt.Sprintf("%s-%d", ticker, j),
+				Ticker:     ticker,
+				Brokerage:  brokerages[(i+j)%len(brokerages)],
+				Action:     actions[k%len(actions)],
+				RatingFrom: ratings[k%len(ratings)],
+				RatingTo:   ratings[(k/3)%len(ratings)],
+				TargetFrom: targets[k%len(targets)],
+				TargetTo:   targets[(k/2)%len(targets)],
+				Time:       scoringNow.Add(-time.Duration(k%200) * 24 * time.Hour),
+			})
+		}
+		consensus := models.NewTickerConsensus(events)
+		for j := range events {
+			if events[j].ID == consensus.LatestStockID {
+				consensus.Latest = &events[j]
+			}
+		}
+
+		for _, strategy := range strategies {
+			result := strategy.Score(consensus, params)
+			var sum float64
+			for _, factor := range result.Factors {
+				sum += factor.Points
+				if factor.Name == "score_cap" {
+					capped++
+				}
+			}
+			if sum != result.Score {
+				t.Errorf("%s %s: factors sum to %v, score is %v", strategy.Name(), ticker, sum, result.Score)
+			}
+			if math.Abs(result.Score) > maxScore {
+				t.Errorf("%s %s: score %v outside ±%d", strategy.Name(), ticker, result.Score, maxScore)
+			}
+		}
+	}
+	if capped == 0 {
+		t.Error("no score reached the cap; the fixture no longer covers score_cap")
+	}
+}
+
+func TestDecay(t *testing.T) {
+	params := ScoringParams{Now: scoringNow}
+	tests := []struct {
+		name     string
+		age      time.Duration
+		halfLife float64
+		want     float64
+	}{
+		{"fresh", 0, 30, 1},
+		{"one half-life", 30 * 24 * time.Hour, 30, 0.5},
+		{"two half-lives", 60 * 24 * time.Hour, 30, 0.25},
+		{"from the future", -24 * time.Hour, 30, 1},
+		{"decay disabled", 365 * 24 * time.Hour, 0, 1},
+	}
+	for _, tt := range tests {
+		if got := params.Decay(scoringNow.Add(-tt.age), tt.halfLife); got != tt.want {
+			t.Errorf("%s: Decay = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// A factor keeps half its points once its event is one half-life old.
+func TestDefaultScoreHalvesAfterAHalfLife(t *testing.T) {
+	profile := DefaultScoringProfile()
+	profile.Decay = DecayWeights{TargetHalfLifeDays: 10, RatingHalfLifeDays: 20, ActionHalfLifeDays: 40}
+	stock := models.Stock{Ticker: "AAPL", Action: "upgraded by", RatingFrom: "Hold", RatingTo: "Buy", TargetFrom: "$10.00", TargetTo: "$11.00"}
+	points := func(age time.Duration) map[string]float64 {
+		stock := stock
+		stock.Time = scoringNow.Add(-age)
+		consensus := models.NewTickerConsensus([]models.Stock{stock})
+		consensus.Latest = &stock
+		result := NewDefaultStrategy().Score(consensus, ScoringParams{Profile: profile, Now: scoringNow})
+		byName := map[string]float64{}
+		for _, factor := range result.Factors {
+			byName[factor.Name] = factor.Points
+		}
+		return byName
+	}
+
+	fresh := points(0)
+	for factor, days := range map[string]int{"target_change": 10, "rating_change": 20, "action": 40} {
+		if fresh[factor] == 0 {
+			t.Fatalf("%s earns no points fresh; the fixture no longer covers it", factor)
+		}
+		if got := points(time.Duration(days) * 24 * time.Hour)[factor]; got != fresh[factor]/2 {
+			t.Errorf("%s after its %d-day half-life = %v, want %v", factor, days, got, fresh[factor]/2)
+		}
+	}
+}
+
+func TestDefaultScoreIgnoresExpiredEvents(t *testing.T) {
+	profile := DefaultScoringProfile()
+	profile.Decay.MaxAgeDays = 30
+	for _, tt := range []struct {
+		age     time.Duration
+		expired bool
+	}{
+		{29 * 24 * time.Hour, false},
+		{30 * 24 * time.Hour, false},
+		{30*24*time.Hour + time.Minute, true},
+	} {
+		stock := models.Stock{Ticker: "AAPL", Action: "upgraded by", RatingFrom: "Hold", RatingTo: "Buy", Time: scoringNow.Add(-tt.age)}
+		consensus := models.NewTickerConsensus([]models.Stock{stock})
+		consensus.Latest = &stock
+		result := NewDefaultStrategy().Score(consensus, ScoringParams{Profile: profile, Now: scoringNow})
+
+		if expired := result.Score == 0 && len(result.Factors) == 0; expired != tt.expired {
+			t.Errorf("event %s old: score %v with %d factors, expired %v", tt.age, result.Score, len(result.Factors), tt.expired)
+		}
+	}
+}
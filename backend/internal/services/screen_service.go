@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/screen"
+)
+
+// ErrInvalidScreen is returned for a screen name or expression that does
+// not parse or type-check.
+var ErrInvalidScreen = errors.New("invalid screen")
+
+// screenNamePattern keeps names usable as a path segment.
+var screenNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// ScreenService saves named screens and runs them over every ticker's
+// consensus.
+type ScreenService struct {
+	repo       *repository.ScreenRepository
+	reader     StockReader
+	securities *SecurityService
+	now        func() time.Time
+}
+
+// NewScreenService runs screens against reader; without securities,
+// screens on sector or industry cannot run.
+func NewScreenService(repo *repository.ScreenRepository, reader StockReader, securities *SecurityService) *ScreenService {
+	return &ScreenService{
+		repo:       repo,
+		reader:     reader,
+		securities: securities,
+		now:        time.Now,
+	}
+}
+
+// Save checks the expression and stores the screen under name, replacing
+// any screen of that name. It reports whether the screen is new.
+func (s *ScreenService) Save(ctx context.Context, name, expression, description string) (*models.Screen, bool, error) {
+	if !screenNamePattern.MatchString(name) {
+		return nil, false, fmt.Errorf("%w: name %q must be 1 to 64 lowercase letters, digits, '-' or '_'", ErrInvalidScreen, name)
+	}
+	expression = strings.TrimSpace(expression)
+	if _, err := screen.Parse(expression); err != nil {
+		return nil, false, fmt.Errorf("%w: %v", ErrInvalidScreen, err)
+	}
+
+	saved := &models.Screen{
+		Name:        name,
+		Expression:  expression,
+		Description: strings.TrimSpace(description),
+		UpdatedAt:   s.now().UTC(),
+	}
+	created, err := s.repo.SaveScreen(ctx, saved)
+	if err != nil {
+		return nil, false, err
+	}
+	return saved, created, nil
+}
+
+func (s *ScreenService) Get(ctx context.Context, name string) (*models.Screen, error) {
+	return s.repo.GetScreen(ctx, name)
+}
+
+func (s *ScreenService) List(ctx context.Context, limit, offset int) ([]models.Screen, error) {
+	return s.repo.ListScreens(ctx, limit, offset)
+}
+
+func (s *ScreenService) Delete(ctx context.Context, name string) error {
+	return s.repo.DeleteScreen(ctx, name)
+}
+
+// Results runs the named screen and returns a page of the tickers passing
+// it, in ticker order. The parts of the expression that map to columns run
+// in SQL to narrow the rows read; the whole expression is then evaluated on
+// what is left.
+func (s *ScreenService) Results(ctx context.Context, name string, limit, offset int) (*models.ScreenResults, error) {
+	saved, err := s.repo.GetScreen(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := screen.Parse(saved.Expression)
+	if err != nil {
+		return nil, fmt.Errorf("%w: stored expression of %s: %v", ErrInvalidScreen, name, err)
+	}
+	if parsed.UsesSecurities() && (s.securities == nil || !s.securities.HasSectors()) {
+		return nil, ErrNoSectorData
+	}
+
+	now := s.now().UTC()
+	fetch := s.reader.GetConsensusAfter
+	condition, prefiltered := parsed.Prefilter(now)
+	if prefiltered {
+		fetch = func(ctx context.Context, afterTicker string, limit int) ([]models.TickerConsensus, error) {
+			return s.repo.ListConsensusWhere(ctx, condition.SQL, condition.Args, afterTicker, limit)
+		}
+	}
+
+	matches := []models.ScreenMatch{}
+	afterTicker := ""
+	for {
+		page, err := fetch(ctx, afterTicker, universePageSize)
+		if err != nil {
+			return nil, err
+		}
+		for i := range page {
+			row := &screen.Row{Consensus: &page[i], Now: now}
+			if s.securities != nil {
+				if security, ok := s.securities.Lookup(page[i].Ticker); ok {
+					row.Security = &security
+				}
+			}
+			if parsed.Match(row) {
+				matches = append(matches, models.ScreenMatch{
+					Ticker:  page[i].Ticker,
+					Company: page[i].Company,
+					Values:  parsed.Values(row),
+					Latest:  page[i].Latest,
+				})
+			}
+		}
+		if len(page) < universePageSize {
+			break
+		}
+		afterTicker = page[len(page)-1].Ticker
+	}
+
+	results := &models.ScreenResults{
+		Screen:      *saved,
+		Fields:      parsed.Fields(),
+		AsOf:        now,
+		Prefiltered: prefiltered,
+		Total:       len(matches),
+		Limit:       limit,
+		Offset:      offset,
+	}
+	start := min(max(offset, 0), len(matches))
+	results.Data = matches[start:min(start+max(limit, 0), len(matches))]
+	return results, nil
+}
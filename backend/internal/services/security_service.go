@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository"
+)
+
+// ErrNoSectorData is returned for diversification limits when no ticker has
+// a sector.
+var ErrNoSectorData = errors.New("no sector data loaded; fill the securities table or set SECURITIES_PATH")
+
+// LoadSecurities reads a securities CSV file with a "ticker,sector" header
+// and optional "industry" and "company" columns. Extra columns are ignored.
+func LoadSecurities(path string) ([]models.Security, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading securities: %w", err)
+	}
+	defer file.Close()
+
+	securities, err := ParseSecurities(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return securities, nil
+}
+
+// ParseSecurities reads securities in the LoadSecurities format.
+func ParseSecurities(r io.Reader) ([]models.Security, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading securities header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"ticker", "sector"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("securities file is missing the %q column", name)
+		}
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var securities []models.Security
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) < len(header) {
+			return nil, fmt.Errorf("line %d: expected %d columns", line, len(header))
+		}
+
+		ticker := strings.ToUpper(field(record, "ticker"))
+		if ticker == "" {
+			return nil, fmt.Errorf("line %d: missing ticker", line)
+		}
+		securities = append(securities, models.Security{
+			Ticker:   ticker,
+			Company:  field(record, "company"),
+			Sector:   field(record, "sector"),
+			Industry: field(record, "industry"),
+		})
+	}
+	return securities, nil
+}
+
+// SecurityService keeps the sector and industry of every ticker in memory,
+// from the securities table overlaid with an optional CSV file.
+type SecurityService struct {
+	repo *repository.SecurityRepository
+	file []models.Security
+
+	mu         sync.RWMutex
+	securities map[string]models.Security
+}
+
+// NewSecurityService serves securities from repo and file; file may be nil.
+func NewSecurityService(repo *repository.SecurityRepository, file []models.Security) *SecurityService {
+	return &SecurityService{
+		repo:       repo,
+		file:       file,
+		securities: make(map[string]models.Security),
+	}
+}
+
+// Refresh re-reads the securities table. Entries of the file win over the
+// table's.
+func (s *SecurityService) Refresh(ctx context.Context) error {
+	stored, err := s.repo.AllSecurities(ctx)
+	if err != nil {
+		return err
+	}
+
+	securities := make(map[string]models.Security, len(stored)+len(s.file))
+	for _, security := range append(stored, s.file...) {
+		securities[strings.ToUpper(security.Ticker)] = security
+	}
+
+	s.mu.Lock()
+	s.securities = securities
+	s.mu.Unlock()
+	return nil
+}
+
+// StartRefreshing refreshes securities every interval until ctx is
+// cancelled.
+func (s *SecurityService) StartRefreshing(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.Refresh(ctx); err != nil {
+					log.Printf("Securities refresh error: %v", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Lookup returns the reference data of a ticker.
+func (s *SecurityService) Lookup(ticker string) (models.Security, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	security, ok := s.securities[strings.ToUpper(ticker)]
+	return security, ok
+}
+
+// HasSectors reports whether any ticker has a sector.
+func (s *SecurityService) HasSectors() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, security := range s.securities {
+		if security.Sector != "" {
+			return true
+		}
+	}
+	return false
+}
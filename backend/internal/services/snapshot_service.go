@@ -0,0 +1,221 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository"
+)
+
+// ErrNotEnoughSnapshots is returned when diffing the latest snapshots of a
+// strategy that has fewer than two.
+var ErrNotEnoughSnapshots = errors.New("not enough snapshots to compare")
+
+// SnapshotService records the rankings of the configured strategies, on
+// both sides, so that they can be compared over time.
+type SnapshotService struct {
+	repo            *repository.SnapshotRepository
+	recommendations *RecommendationService
+	strategies      []string
+	size            int
+	now             func() time.Time
+}
+
+// NewSnapshotService snapshots the top size tickers of every named strategy.
+func NewSnapshotService(repo *repository.SnapshotRepository, recommendations *RecommendationService, strategies []string, size int) (*SnapshotService, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("snapshot size must be positive")
+	}
+	for _, name := range strategies {
+		if _, err := recommendations.strategies.Get(name); err != nil {
+			return nil, err
+		}
+	}
+
+	return &SnapshotService{
+		repo:            repo,
+		recommendations: recommendations,
+		strategies:      strategies,
+		size:            size,
+		now:             time.Now,
+	}, nil
+}
+
+// TakeSnapshots stores the current buy and sell rankings of every strategy.
+// A failing strategy does not stop the others; the first error is returned.
+func (s *SnapshotService) TakeSnapshots(ctx context.Context, source string) ([]models.RecommendationSnapshot, error) {
+	var snapshots []models.RecommendationSnapshot
+	var firstErr error
+
+	takenAt := s.now().UTC()
+	for _, strategy := range s.strategies {
+		for _, side := range []string{models.SideBuy, models.SideSell} {
+			snapshot, err := s.take(ctx, source, strategy, side, takenAt)
+			if err != nil {
+				log.Printf("Error taking %s %s snapshot: %v", strategy, side, err)
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			snapshots = append(snapshots, *snapshot)
+		}
+	}
+	return snapshots, firstErr
+}
+
+func (s *SnapshotService) take(ctx context.Context, source, strategy, side string, takenAt time.Time) (*models.RecommendationSnapshot, error) {
+	list, err := s.recommendations.GetRecommendations(ctx, RecommendationQuery{Strategy: strategy, Side: side, Limit: s.size})
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &models.RecommendationSnapshot{
+		Source:          source,
+		Side:            list.Side,
+		Strategy:        list.Strategy,
+		StrategyVersion: list.StrategyVersion,
+		ProfileVersion:  list.ProfileVersion,
+		TakenAt:         takenAt,
+		Entries:         make([]models.SnapshotEntry, 0, len(list.Data)),
+	}
+	for i, recommendation := range list.Data {
+		snapshot.Entries = append(snapshot.Entries, models.SnapshotEntry{
+			Rank:    i + 1,
+			Ticker:  recommendation.Stock.Ticker,
+			StockID: recommendation.Stock.ID,
+			Score:   recommendation.Score,
+			Reason:  recommendation.Reason,
+			Factors: recommendation.Factors,
+		})
+	}
+	if err := s.repo.CreateSnapshot(ctx, snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// StartSchedule takes snapshots every interval until ctx is cancelled.
+func (s *SnapshotService) StartSchedule(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := s.TakeSnapshots(ctx, models.SnapshotSourceScheduled); err != nil {
+					log.Printf("Scheduled snapshot error: %v", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// List returns snapshots without their entries, newest first.
+func (s *SnapshotService) List(ctx context.Context, filter repository.SnapshotFilter, limit, offset int) ([]models.RecommendationSnapshot, error) {
+	return s.repo.ListSnapshots(ctx, filter, limit, offset)
+}
+
+// Get returns a snapshot with its entries.
+func (s *SnapshotService) Get(ctx context.Context, id string) (*models.RecommendationSnapshot, error) {
+	return s.repo.GetSnapshot(ctx, id)
+}
+
+// Diff compares two snapshots. With no ids it compares the latest snapshot
+// of the strategy and side with the one before it.
+func (s *SnapshotService) Diff(ctx context.Context, fromID, toID, strategy, side string) (*models.SnapshotDiff, error) {
+	if fromID == "" && toID == "" {
+		if strategy == "" {
+			strategy = DefaultStrategyName
+		}
+		if side == "" {
+			side = models.SideBuy
+		}
+		latest, err := s.repo.ListSnapshots(ctx, repository.SnapshotFilter{Strategy: strategy, Side: side}, 2, 0)
+		if err != nil {
+			return nil, err
+		}
+		if len(latest) < 2 {
+			return nil, fmt.Errorf("%w: %s %s has %d", ErrNotEnoughSnapshots, strategy, side, len(latest))
+		}
+		fromID, toID = latest[1].ID, latest[0].ID
+	}
+
+	from, err := s.repo.GetSnapshot(ctx, fromID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.repo.GetSnapshot(ctx, toID)
+	if err != nil {
+		return nil, err
+	}
+	return diffSnapshots(from, to), nil
+}
+
+// diffSnapshots lists tickers that entered the ranking by their new rank,
+// those that left it by their old one, and moves biggest first.
+func diffSnapshots(from, to *models.RecommendationSnapshot) *models.SnapshotDiff {
+	diff := &models.SnapshotDiff{
+		Entered:   []models.SnapshotChange{},
+		Exited:    []models.SnapshotChange{},
+		MovedUp:   []models.SnapshotChange{},
+		MovedDown: []models.SnapshotChange{},
+	}
+
+	before := make(map[string]*models.SnapshotEntry, len(from.Entries))
+	for i := range from.Entries {
+		before[from.Entries[i].Ticker] = &from.Entries[i]
+	}
+	after := make(map[string]bool, len(to.Entries))
+
+	for i := range to.Entries {
+		entry := &to.Entries[i]
+		after[entry.Ticker] = true
+		change := models.SnapshotChange{Ticker: entry.Ticker, ToRank: &entry.Rank, ToScore: &entry.Score}
+
+		previous, ok := before[entry.Ticker]
+		if !ok {
+			diff.Entered = append(diff.Entered, change)
+			continue
+		}
+		change.FromRank, change.FromScore = &previous.Rank, &previous.Score
+		change.Change = previous.Rank - entry.Rank
+		switch {
+		case change.Change > 0:
+			diff.MovedUp = append(diff.MovedUp, change)
+		case change.Change < 0:
+			diff.MovedDown = append(diff.MovedDown, change)
+		default:
+			diff.Unchanged++
+		}
+	}
+
+	for i := range from.Entries {
+		entry := &from.Entries[i]
+		if !after[entry.Ticker] {
+			diff.Exited = append(diff.Exited, models.SnapshotChange{
+				Ticker: entry.Ticker, FromRank: &entry.Rank, FromScore: &entry.Score,
+			})
+		}
+	}
+
+	sort.SliceStable(diff.MovedUp, func(i, j int) bool { return diff.MovedUp[i].Change > diff.MovedUp[j].Change })
+	sort.SliceStable(diff.MovedDown, func(i, j int) bool { return diff.MovedDown[i].Change < diff.MovedDown[j].Change })
+
+	// The diff describes the snapshots, not their entries
+	diff.From, diff.To = *from, *to
+	diff.From.Entries, diff.To.Entries = nil, nil
+	return diff
+}
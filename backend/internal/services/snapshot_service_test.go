@@ -0,0 +1,140 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+)
+
+// rankedSnapshot ranks tickers in order, each "TICKER" or "TICKER=score";
+// scores default to 100 less the rank.
+func rankedSnapshot(id string, tickers ...string) *models.RecommendationSnapshot {
+	snapshot := &models.RecommendationSnapshot{ID: id}
+	for i, ticker := range tickers {
+		entry := models.SnapshotEntry{Rank: i + 1, Ticker: ticker, Score: float64(100 - i - 1)}
+		if name, score, ok := strings.Cut(ticker, "="); ok {
+			entry.Ticker = name
+			fmt.Sscan(score, &entry.Score)
+		}
+		snapshot.Entries = append(snapshot.Entries, entry)
+	}
+	return snapshot
+}
+
+func describeChanges(changes []models.SnapshotChange) string {
+	var parts []string
+	for _, change := range changes {
+		from, to := "-", "-"
+		if change.FromRank != nil {
+			from = fmt.Sprint(*change.FromRank)
+		}
+		if change.ToRank != nil {
+			to = fmt.Sprint(*change.ToRank)
+		}
+		parts = append(parts, fmt.Sprintf("%s %s>%s", change.Ticker, from, to))
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	tests := []struct {
+		name                      string
+		from, to                  []string
+		entered, exited, up, down string
+		unchanged                 int
+	}{
+		{
+			name:    "every bucket",
+			from:    []string{"AAA", "BBB", "CCC", "DDD", "EEE"},
+			to:      []string{"DDD", "BBB", "NEW", "AAA", "CCC"},
+			entered: "NEW ->3",
+			exited:  "EEE 5>-",
+			up:      "DDD 4>1",
+			down:    "AAA 1>4 CCC 3>5",
+			// BBB stayed second
+			unchanged: 1,
+		},
+		// Entered by new rank, exited by old rank, moves biggest first
+		{
+			name:    "order",
+			from:    []string{"GONE1", "AAA", "GONE2", "BBB", "CCC", "DDD"},
+			to:      []string{"DDD", "NEW1", "CCC", "NEW2", "BBB", "AAA"},
+			entered: "NEW1 ->2 NEW2 ->4",
+			exited:  "GONE1 1>- GONE2 3>-",
+			up:      "DDD 6>1 CCC 5>3",
+			down:    "AAA 2>6 BBB 4>5",
+		},
+		// Moves of the same size keep the order of the new ranking
+		{
+			name: "tied moves",
+			from: []string{"AAA", "BBB", "CCC", "DDD"},
+			to:   []string{"CCC", "DDD", "AAA", "BBB"},
+			up:   "CCC 3>1 DDD 4>2",
+			down: "AAA 1>3 BBB 2>4",
+		},
+		// Tickers tied on score that swap places still moved
+		{
+			name:      "tied scores",
+			from:      []string{"AAA=50", "BBB=50", "CCC=40"},
+			to:        []string{"BBB=50", "AAA=50", "CCC=40"},
+			up:        "BBB 2>1",
+			down:      "AAA 1>2",
+			unchanged: 1,
+		},
+		{
+			name:    "empty previous snapshot",
+			to:      []string{"AAA", "BBB"},
+			entered: "AAA ->1 BBB ->2",
+		},
+		{
+			name:   "empty new snapshot",
+			from:   []string{"AAA", "BBB"},
+			exited: "AAA 1>- BBB 2>-",
+		},
+		{
+			name:      "unchanged",
+			from:      []string{"AAA", "BBB"},
+			to:        []string{"AAA", "BBB"},
+			unchanged: 2,
+		},
+	}
+	for _, tt := range tests {
+		diff := diffSnapshots(rankedSnapshot("from", tt.from...), rankedSnapshot("to", tt.to...))
+		got := []string{describeChanges(diff.Entered), describeChanges(diff.Exited), describeChanges(diff.MovedUp), describeChanges(diff.MovedDown)}
+		want := []string{tt.entered, tt.exited, tt.up, tt.down}
+		for i, bucket := range []string{"entered", "exited", "moved up", "moved down"} {
+			if got[i] != want[i] {
+				t.Errorf("%s: %s %q, want %q", tt.name, bucket, got[i], want[i])
+			}
+		}
+		if diff.Unchanged != tt.unchanged {
+			t.Errorf("%s: %d unchanged, want %d", tt.name, diff.Unchanged, tt.unchanged)
+		}
+		if diff.From.ID != "from" || diff.To.ID != "to" || diff.From.Entries != nil || diff.To.Entries != nil {
+			t.Errorf("%s: diff should name both snapshots without their entries", tt.name)
+		}
+	}
+}
+
+func TestDiffSnapshotsReportsScoresAndChange(t *testing.T) {
+	diff := diffSnapshots(rankedSnapshot("from", "AAA=60", "BBB=55", "CCC=50"), rankedSnapshot("to", "CCC=70", "AAA=65"))
+
+	up := diff.MovedUp[0]
+	if up.Ticker != "CCC" || up.Change != 2 || *up.FromScore != 50 || *up.ToScore != 70 {
+		t.Errorf("moved up %+v, want CCC gaining 2 places from 50 to 70", up)
+	}
+	down := diff.MovedDown[0]
+	if down.Ticker != "AAA" || down.Change != -1 || *down.FromScore != 60 || *down.ToScore != 65 {
+		t.Errorf("moved down %+v, want AAA losing a place from 60 to 65", down)
+	}
+	exited := diff.Exited[0]
+	if exited.ToRank != nil || exited.ToScore != nil || *exited.FromScore != 55 || exited.Change != 0 {
+		t.Errorf("exited %+v, want BBB with only its old rank and score", exited)
+	}
+	// Empty buckets are empty lists, not null, in JSON
+	if empty := diffSnapshots(rankedSnapshot("from"), rankedSnapshot("to")); empty.Entered == nil || empty.Exited == nil || empty.MovedUp == nil || empty.MovedDown == nil {
+		t.Errorf("empty diff %+v has nil buckets", empty)
+	}
+}
@@ -1,8 +1,10 @@
 package services
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -20,25 +22,62 @@ const (
 	ABSOLUTE_MAX_PAGES = 100
 )
 
+// ErrSyncRunNotResumable is returned by ResumeSync for runs that did not fail
+// or already stored their last page.
+var ErrSyncRunNotResumable = errors.New("sync run cannot be resumed")
+
 type StockService struct {
-	repo       *repository.StockRepository
+	repo       repository.StockStore
 	apiURL     string
 	apiKey     string
+	writeMode  string
 	httpClient *http.Client
+
+	// onSynced runs after every sync that completes
+	onSynced []func(ctx context.Context, run *models.SyncRun)
+	// payloads keeps raw provider responses when set
+	payloads *repository.PayloadRepository
 }
 
-func NewStockService(repo *repository.StockRepository, apiURL, apiKey string) *StockService {
+// NewStockService syncs with writeMode models.SyncWriteAtomic or
+// models.SyncWriteBestEffort (see SavePage).
+func NewStockService(repo repository.StockStore, apiURL, apiKey, writeMode string) *StockService {
 	return &StockService{
-		repo:   repo,
-		apiURL: apiURL,
-		apiKey: apiKey,
+		repo:      repo,
+		apiURL:    apiURL,
+		apiKey:    apiKey,
+		writeMode: writeMode,
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
 	}
 }
 
-func (s *StockService) FetchAndStoreStocks(maxPages int) error {
+// OnSyncCompleted registers fn to run after every sync that completes, in
+// the sync's goroutine. Listeners are registered at startup, before any sync.
+func (s *StockService) OnSyncCompleted(fn func(ctx context.Context, run *models.SyncRun)) {
+	s.onSynced = append(s.onSynced, fn)
+}
+
+// KeepPayloads stores every provider response verbatim in payloads, so
+// syncs can be audited. Like OnSyncCompleted it is set at startup.
+func (s *StockService) KeepPayloads(payloads *repository.PayloadRepository) {
+	s.payloads = payloads
+}
+
+// FetchAndStoreStocks runs one synchronization. ctx bounds the whole run,
+// including provider requests and database writes.
+func (s *StockService) FetchAndStoreStocks(ctx context.Context, maxPages int) error {
+	run, err := s.StartSync(ctx, maxPages)
+	if err != nil {
+		return err
+	}
+	return s.RunSync(ctx, run)
+}
+
+// StartSync records a new running sync so callers get its id before RunSync
+// starts fetching.
+func (s *StockService) StartSync(ctx context.Context, maxPages int) (*models.SyncRun, error) {
 	// Validar y aplicar límites
 	if maxPages <= 0 {
 		maxPages = DEFAULT_MAX_PAGES
@@ -47,55 +86,132 @@ func (s *StockService) FetchAndStoreStocks(maxPages int) error {
 		maxPages = ABSOLUTE_MAX_PAGES
 	}
 
-	nextPage := ""
-	totalFetched := 0
-	pageCount := 0
+	run := &models.SyncRun{
+		Status:    models.SyncRunRunning,
+		WriteMode: s.writeMode,
+		MaxPages:  maxPages,
+		StartedAt: time.Now(),
+	}
+	if err := s.repo.CreateSyncRun(ctx, run); err != nil {
+		return nil, fmt.Errorf("error recording sync run: %w", err)
+	}
+	return run, nil
+}
 
-	log.Printf("Starting stock synchronization (max %d pages)...", maxPages)
+// ResumeSync marks a failed run as running again so that RunSync continues
+// from its checkpoint instead of refetching the pages already stored.
+func (s *StockService) ResumeSync(ctx context.Context, id string) (*models.SyncRun, error) {
+	run, err := s.repo.GetSyncRun(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if run.Status != models.SyncRunFailed || (run.Pages > 0 && run.NextPage == "") || run.Pages >= run.MaxPages {
+		return nil, fmt.Errorf("%w: run %s is %s after %d of %d pages", ErrSyncRunNotResumable, run.ID, run.Status, run.Pages, run.MaxPages)
+	}
+
+	run.Status = models.SyncRunRunning
+	run.Error = ""
+	run.FinishedAt = nil
+	if err := s.repo.UpdateSyncRun(ctx, run); err != nil {
+		return nil, fmt.Errorf("error recording sync run: %w", err)
+	}
+	return run, nil
+}
+
+// RunSync fetches pages from the run's checkpoint until the provider has no
+// more pages or MaxPages is reached, then records the outcome.
+func (s *StockService) RunSync(ctx context.Context, run *models.SyncRun) error {
+	err := s.syncPages(ctx, run)
+
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	run.Status = models.SyncRunCompleted
+	if err != nil {
+		run.Status = models.SyncRunFailed
+		run.Error = err.Error()
+	}
+	// Record the outcome even when ctx was cancelled mid-run
+	if updateErr := s.repo.UpdateSyncRun(context.WithoutCancel(ctx), run); updateErr != nil {
+		log.Printf("Error updating sync run %s: %v", run.ID, updateErr)
+	}
 
-	for {
-		stocks, next, err := s.fetchStocksFromAPI(nextPage)
+	if err == nil {
+		for _, fn := range s.onSynced {
+			fn(ctx, run)
+		}
+	}
+	return err
+}
+
+// GetSyncRun returns a run with its counters, checkpoint and failed rows.
+func (s *StockService) GetSyncRun(ctx context.Context, id string) (*models.SyncRun, error) {
+	return s.repo.GetSyncRun(ctx, id)
+}
+
+func (s *StockService) syncPages(ctx context.Context, run *models.SyncRun) error {
+	log.Printf("Starting stock synchronization %s (%s, max %d pages, from page %d)...",
+		run.ID, run.WriteMode, run.MaxPages, run.Pages+1)
+
+	for run.Pages < run.MaxPages {
+		stocks, next, body, err := s.fetchStocksFromAPI(ctx, run.NextPage)
 		if err != nil {
 			return fmt.Errorf("error fetching stocks: %w", err)
 		}
+		s.savePayload(ctx, run, body)
 
-		for _, stock := range stocks {
-			if err := s.repo.Create(&stock); err != nil {
-				log.Printf("Error storing stock %s: %v", stock.Ticker, err)
-				continue
-			}
+		// Rows, counters and checkpoint are committed together
+		failedBefore := run.Failed
+		if err := s.repo.SavePage(ctx, run, stocks, next); err != nil {
+			return fmt.Errorf("error storing page %d: %w", run.Pages+1, err)
 		}
-
-		totalFetched += len(stocks)
-		pageCount++
-		log.Printf("Fetched and stored %d stocks (total: %d, page: %d/%d)", len(stocks), totalFetched, pageCount, maxPages)
-
-		if next == "" {
-			log.Printf("Successfully fetched all available stocks: %d total", totalFetched)
-			break
+		for _, row := range run.FailedRows[len(run.FailedRows)-(run.Failed-failedBefore):] {
+			log.Printf("Error storing stock %s: %s", row.Ticker, row.Error)
 		}
+		log.Printf("Fetched and stored %d stocks (total: %d, page: %d/%d)", len(stocks), run.Fetched, run.Pages, run.MaxPages)
 
-		if pageCount >= maxPages {
-			log.Printf("Reached maximum sync pages limit (%d pages, %d stocks)", maxPages, totalFetched)
+		if next == "" {
+			log.Printf("Successfully fetched all available stocks: %d total", run.Fetched)
 			break
 		}
+	}
 
-		nextPage = next
+	if run.Pages >= run.MaxPages && run.NextPage != "" {
+		log.Printf("Reached maximum sync pages limit (%d pages, %d stocks)", run.MaxPages, run.Fetched)
 	}
 
-	log.Printf("Sync completed: %d stocks stored from %d pages", totalFetched, pageCount)
+	log.Printf("Sync completed: %d stocks stored from %d pages", run.Stored, run.Pages)
 	return nil
 }
 
-func (s *StockService) fetchStocksFromAPI(nextPage string) ([]models.Stock, string, error) {
+// savePayload keeps the raw response of the page being stored. Losing it
+// only loses the audit trail, so errors are logged rather than failing the
+// sync.
+func (s *StockService) savePayload(ctx context.Context, run *models.SyncRun, body []byte) {
+	if s.payloads == nil {
+		return
+	}
+	payload := &models.SyncPayload{
+		SyncRunID: run.ID,
+		Page:      run.Pages + 1,
+		Body:      string(body),
+		FetchedAt: time.Now(),
+	}
+	if err := s.payloads.SavePayload(ctx, payload); err != nil {
+		log.Printf("Error keeping payload of page %d of sync %s: %v", payload.Page, run.ID, err)
+	}
+}
+
+// fetchStocksFromAPI returns one page of stocks, the next page cursor and
+// the raw response body.
+func (s *StockService) fetchStocksFromAPI(ctx context.Context, nextPage string) ([]models.Stock, string, []byte, error) {
 	url := s.apiURL
 	if nextPage != "" {
 		url = fmt.Sprintf("%s?next_page=%s", s.apiURL, nextPage)
 	}
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return nil, "", err
+		return nil, "", nil, err
 	}
 
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.apiKey))
@@ -103,27 +219,27 @@ func (s *StockService) fetchStocksFromAPI(nextPage string) ([]models.Stock, stri
 
 	resp, err := s.httpClient.Do(req)
 	if err != nil {
-		return nil, "", err
+		return nil, "", nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, "", fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+		return nil, "", nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, "", err
+		return nil, "", nil, err
 	}
 
 	var apiResponse models.APIResponse
 	if err := json.Unmarshal(body, &apiResponse); err != nil {
-		return nil, "", err
+		return nil, "", nil, err
 	}
 
 	stocks := s.parseStocksFromResponse(apiResponse.Items)
-	return stocks, apiResponse.NextPage, nil
+	return stocks, apiResponse.NextPage, body, nil
 }
 
 func (s *StockService) parseStocksFromResponse(items []models.APIStockItem) []models.Stock {
@@ -164,18 +280,56 @@ func (s *StockService) generateID(ticker, timestamp string) string {
 	return fmt.Sprintf("%x", hash[:16])
 }
 
-func (s *StockService) GetAllStocks(limit, offset int) ([]models.Stock, error) {
-	return s.repo.GetAll(limit, offset)
+func (s *StockService) GetAllStocks(ctx context.Context, limit, offset int) ([]models.Stock, error) {
+	return s.repo.GetAll(ctx, limit, offset)
+}
+
+func (s *StockService) GetStockByID(ctx context.Context, id string) (*models.Stock, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// GetStockHistory returns the recorded revisions of a stock, newest first.
+func (s *StockService) GetStockHistory(ctx context.Context, id string) ([]models.StockRevision, error) {
+	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.GetRevisions(ctx, id)
+}
+
+// GetRevisionsAfter pages through the revisions of every stock, oldest
+// change first.
+func (s *StockService) GetRevisionsAfter(ctx context.Context, afterTime time.Time, afterID string, limit int) ([]models.StockRevision, error) {
+	return s.repo.ListRevisionsAfter(ctx, afterTime, afterID, limit)
+}
+
+func (s *StockService) SearchStocks(ctx context.Context, query string) ([]models.Stock, error) {
+	return s.repo.Search(ctx, query)
+}
+
+func (s *StockService) GetTotalCount(ctx context.Context) (int, error) {
+	return s.repo.Count(ctx)
+}
+
+// GetStocksAfter pages through every event by key, oldest first (see
+// repository.StockStore.ListStocksAfter).
+func (s *StockService) GetStocksAfter(ctx context.Context, afterTime time.Time, afterID string, limit int) ([]models.Stock, error) {
+	return s.repo.ListStocksAfter(ctx, afterTime, afterID, limit)
+}
+
+// GetConsensusAfter pages through every ticker's consensus in ticker order.
+func (s *StockService) GetConsensusAfter(ctx context.Context, afterTicker string, limit int) ([]models.TickerConsensus, error) {
+	return s.repo.ListConsensusAfter(ctx, afterTicker, limit)
 }
 
-func (s *StockService) GetStockByID(id string) (*models.Stock, error) {
-	return s.repo.GetByID(id)
+// GetAllConsensus returns per-ticker consensus, most recently active first.
+func (s *StockService) GetAllConsensus(ctx context.Context, limit, offset int) ([]models.TickerConsensus, error) {
+	return s.repo.ListConsensus(ctx, limit, offset)
 }
 
-func (s *StockService) SearchStocks(query string) ([]models.Stock, error) {
-	return s.repo.Search(query)
+func (s *StockService) GetConsensus(ctx context.Context, ticker string) (*models.TickerConsensus, error) {
+	return s.repo.GetConsensus(ctx, ticker)
 }
 
-func (s *StockService) GetTotalCount() (int, error) {
-	return s.repo.Count()
+func (s *StockService) GetTickerCount(ctx context.Context) (int, error) {
+	return s.repo.CountConsensus(ctx)
 }
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository"
+)
+
+// ErrInvalidCurrency is returned for a currency that is not a three-letter
+// ISO code.
+var ErrInvalidCurrency = errors.New("invalid currency")
+
+// targetChangeWindows are the days back the target spread is compared with.
+var targetChangeWindows = []int{30, 90, 365}
+
+// GetTargetSpread summarizes the latest target of every brokerage covering
+// ticker, and the same summary as it stood 30, 90 and 365 days ago. Targets
+// are compared in one currency: the requested one, or else the one most of
+// the brokerages use (USD on a tie). Targets in other currencies and those
+// that cannot be read are listed as excluded rather than converted or
+// guessed.
+func (s *StockService) GetTargetSpread(ctx context.Context, ticker, currency string) (*models.TargetSpread, error) {
+	if currency != "" && !models.IsCurrencyCode(currency) {
+		return nil, fmt.Errorf("%w %q", ErrInvalidCurrency, currency)
+	}
+
+	events, err := s.repo.ListTickerEvents(ctx, ticker)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, repository.ErrTickerNotFound
+	}
+
+	now := time.Now().UTC()
+	current := models.NewTickerConsensus(events)
+	if currency == "" {
+		currency = models.MainTargetCurrency(current.Brokerages)
+	}
+
+	spread := &models.TargetSpread{
+		Ticker:   current.Ticker,
+		Currency: currency,
+		AsOf:     now,
+		Targets:  []models.BrokerageTarget{},
+		Excluded: []models.ExcludedTarget{},
+		Changes:  []models.TargetChange{},
+	}
+	var targets []float64
+	for _, view := range current.Brokerages {
+		amount, viewCurrency, err := models.ParseTarget(view.Target)
+		excluded := models.ExcludedTarget{Brokerage: view.Brokerage, StockID: view.StockID, Raw: view.Target, Time: view.Time}
+		switch {
+		case err != nil:
+			excluded.Reason = err.Error()
+			spread.Excluded = append(spread.Excluded, excluded)
+		case viewCurrency != currency:
+			excluded.Reason = fmt.Sprintf("target is in %s, not %s", viewCurrency, currency)
+			spread.Excluded = append(spread.Excluded, excluded)
+		default:
+			targets = append(targets, amount)
+			spread.Targets = append(spread.Targets, models.BrokerageTarget{
+				Brokerage: view.Brokerage,
+				StockID:   view.StockID,
+				Raw:       view.Target,
+				Target:    amount,
+				Currency:  viewCurrency,
+				Time:      view.Time,
+			})
+		}
+	}
+	spread.Stats = models.NewTargetStats(targets)
+
+	// Events are oldest first, so each window replays a prefix of them
+	for _, days := range targetChangeWindows {
+		asOf := now.AddDate(0, 0, -days)
+		known := sort.Search(len(events), func(i int) bool { return events[i].Time.After(asOf) })
+		then := targetStats(models.NewTickerConsensus(events[:known]).Brokerages, currency)
+
+		change := models.TargetChange{
+			Days:   days,
+			AsOf:   asOf,
+			Stats:  then,
+			Change: spread.Stats.Sub(then),
+		}
+		if change.Change.Mean != nil && *then.Mean != 0 {
+			pct := *change.Change.Mean / *then.Mean * 100
+			change.MeanChangePct = &pct
+		}
+		spread.Changes = append(spread.Changes, change)
+	}
+	return spread, nil
+}
+
+// targetStats summarizes the readable targets in currency of views.
+func targetStats(views []models.BrokerageRating, currency string) models.TargetStats {
+	var targets []float64
+	for _, view := range views {
+		if amount, viewCurrency, err := models.ParseTarget(view.Target); err == nil && viewCurrency == currency {
+			targets = append(targets, amount)
+		}
+	}
+	return models.NewTargetStats(targets)
+}
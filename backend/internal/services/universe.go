@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+)
+
+// universePageSize is how many rows each query reads when walking a whole
+// table. Pages are fetched by key, not by offset, so the last page of a
+// large table costs the same as the first.
+const universePageSize = 1000
+
+// StockLister is the read side of StockService that whole-history scans
+// (credibility, backtests) depend on.
+type StockLister interface {
+	GetStocksAfter(ctx context.Context, afterTime time.Time, afterID string, limit int) ([]models.Stock, error)
+}
+
+// StockHistory adds the recorded revisions of events to StockLister, so
+// replays can restore the values an event had on a past date.
+type StockHistory interface {
+	StockLister
+	GetRevisionsAfter(ctx context.Context, afterTime time.Time, afterID string, limit int) ([]models.StockRevision, error)
+}
+
+// StockReader is the read side of StockService that recommendations depend on.
+type StockReader interface {
+	GetConsensusAfter(ctx context.Context, afterTicker string, limit int) ([]models.TickerConsensus, error)
+}
+
+// eachStockPage calls fn with every stored event, oldest first, one page at
+// a time. The next page is only read once fn returns.
+func eachStockPage(ctx context.Context, stocks StockLister, fn func(page []models.Stock) error) error {
+	return eachStockPageSince(ctx, stocks, time.Time{}, fn)
+}
+
+// eachStockPageSince is eachStockPage over the events from since on.
+func eachStockPageSince(ctx context.Context, stocks StockLister, since time.Time, fn func(page []models.Stock) error) error {
+	afterTime := since
+	var afterID string
+	for {
+		page, err := stocks.GetStocksAfter(ctx, afterTime, afterID, universePageSize)
+		if err != nil {
+			return err
+		}
+		if len(page) > 0 {
+			if err := fn(page); err != nil {
+				return err
+			}
+			last := page[len(page)-1]
+			afterTime, afterID = last.Time, last.ID
+		}
+		if len(page) < universePageSize {
+			return nil
+		}
+	}
+}
+
+// eachRevisionPage calls fn with every recorded revision, oldest change
+// first, one page at a time.
+func eachRevisionPage(ctx context.Context, history StockHistory, fn func(page []models.StockRevision) error) error {
+	var afterTime time.Time
+	var afterID string
+	for {
+		page, err := history.GetRevisionsAfter(ctx, afterTime, afterID, universePageSize)
+		if err != nil {
+			return err
+		}
+		if len(page) > 0 {
+			if err := fn(page); err != nil {
+				return err
+			}
+			last := page[len(page)-1]
+			afterTime, afterID = last.ChangedAt, last.ID
+		}
+		if len(page) < universePageSize {
+			return nil
+		}
+	}
+}
+
+// allConsensus reads the consensus of every ticker, in ticker order.
+func allConsensus(ctx context.Context, reader StockReader) ([]models.TickerConsensus, error) {
+	var tickers []models.TickerConsensus
+	afterTicker := ""
+	for {
+		page, err := reader.GetConsensusAfter(ctx, afterTicker, universePageSize)
+		if err != nil {
+			return nil, err
+		}
+		tickers = append(tickers, page...)
+		if len(page) < universePageSize {
+			return tickers, nil
+		}
+		afterTicker = page[len(page)-1].Ticker
+	}
+}
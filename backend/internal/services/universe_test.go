@@ -0,0 +1,31 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/models"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository"
+	"github.com/ElDanissito/stock-analyzer-platform/backend/internal/repository/storetest"
+)
+
+// BenchmarkAllConsensus reads the consensus of every ticker, as each
+// ranking does, from SQLite.
+func BenchmarkAllConsensus(b *testing.B) {
+	const events, tickers = 50000, 10000
+	repo := repository.NewStockRepository(newMigratedDatabase(b))
+	storetest.Seed(b, repo, events, tickers)
+	stocks := NewStockService(repo, "", "", models.SyncWriteAtomic)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		consensus, err := allConsensus(context.Background(), stocks)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(consensus) == 0 {
+			b.Fatal("no consensus rows read")
+		}
+	}
+}
@@ -1,5 +1,5 @@
 import axios from 'axios'
-import type { StocksResponse, Stock, RecommendationsResponse, SyncResponse } from '@/types/stock'
+import type { StocksResponse, Stock, StockHistoryResponse, TickersResponse, TickerConsensus, RecommendationsResponse, SyncResponse, BrokerageCredibility, RecommendationSnapshot, SnapshotDiff, Diversification, RecommendationFilter, TargetSpread, AnalystAnomaly, Screen, ScreenResults } from '@/types/stock'
 
 const API_URL = import.meta.env.VITE_API_URL || 'http://localhost:3000'
 
@@ -25,6 +25,12 @@ export const stocksApi = {
     return response.data
   },
 
+  // Get the revisions the provider made to a stored event
+  getStockHistory: async (id: string): Promise<StockHistoryResponse> => {
+    const response = await api.get<StockHistoryResponse>(`/api/stocks/${id}/history`)
+    return response.data
+  },
+
   // Search stocks
   searchStocks: async (query: string): Promise<StocksResponse> => {
     const response = await api.get<StocksResponse>('/api/stocks/search', {
@@ -33,6 +39,28 @@ export const stocksApi = {
     return response.data
   },
 
+  // Get per-ticker consensus, most recently active first
+  getTickers: async (limit = 50, offset = 0): Promise<TickersResponse> => {
+    const response = await api.get<TickersResponse>('/api/tickers', {
+      params: { limit, offset }
+    })
+    return response.data
+  },
+
+  // Get the consensus of one ticker
+  getTicker: async (ticker: string): Promise<TickerConsensus> => {
+    const response = await api.get<TickerConsensus>(`/api/tickers/${encodeURIComponent(ticker)}`)
+    return response.data
+  },
+
+  // Get the spread of the latest brokerage targets on a ticker
+  getTickerTargets: async (ticker: string, currency?: string): Promise<TargetSpread> => {
+    const response = await api.get<TargetSpread>(`/api/tickers/${encodeURIComponent(ticker)}/targets`, {
+      params: { currency }
+    })
+    return response.data
+  },
+
   // Sync stocks from external API
   syncStocks: async (pages?: number): Promise<SyncResponse> => {
     const response = await api.post<SyncResponse>('/api/sync', {
@@ -42,13 +70,79 @@ export const stocksApi = {
   },
 
   // Get recommendations
-  getRecommendations: async (limit = 10): Promise<RecommendationsResponse> => {
+  getRecommendations: async (limit = 10, strategy?: string, diversification: Diversification = {}, filter: RecommendationFilter = {}): Promise<RecommendationsResponse> => {
+    const { sector, brokerage, action, exclude, ...rest } = filter
     const response = await api.get<RecommendationsResponse>('/api/recommendations', {
-      params: { limit }
+      params: {
+        limit,
+        strategy,
+        ...diversification,
+        ...rest,
+        sector: sector?.join(','),
+        brokerage: brokerage?.join(','),
+        action: action?.join(','),
+        exclude: exclude?.join(',')
+      }
     })
     return response.data
   },
 
+  // Get the tickers with the strongest bearish signals
+  getAvoidList: async (limit = 10, strategy?: string): Promise<RecommendationsResponse> => {
+    const response = await api.get<RecommendationsResponse>('/api/recommendations/avoid', {
+      params: { limit, strategy }
+    })
+    return response.data
+  },
+
+  // List stored recommendation snapshots, newest first
+  getSnapshots: async (strategy?: string, side?: 'buy' | 'sell', limit = 50, offset = 0): Promise<{ data: RecommendationSnapshot[] }> => {
+    const response = await api.get<{ data: RecommendationSnapshot[] }>('/api/recommendations/snapshots', {
+      params: { strategy, side, limit, offset }
+    })
+    return response.data
+  },
+
+  // Compare two snapshots, or the two latest of a strategy and side
+  getSnapshotDiff: async (params: { from?: string; to?: string; strategy?: string; side?: 'buy' | 'sell' } = {}): Promise<SnapshotDiff> => {
+    const response = await api.get<SnapshotDiff>('/api/recommendations/snapshots/diff', { params })
+    return response.data
+  },
+
+  // List unusual analyst activity, latest and strongest first
+  getAnomalies: async (params: { scope?: 'ticker' | 'sector'; subject?: string; kind?: 'volume' | 'direction'; direction?: string; days?: number; limit?: number; offset?: number } = {}): Promise<{ data: AnalystAnomaly[] }> => {
+    const response = await api.get<{ data: AnalystAnomaly[] }>('/api/anomalies', { params })
+    return response.data
+  },
+
+  // List saved screens
+  getScreens: async (): Promise<{ data: Screen[] }> => {
+    const response = await api.get<{ data: Screen[] }>('/api/screens')
+    return response.data
+  },
+
+  // Save a screen expression under a name, replacing any screen of that name
+  saveScreen: async (name: string, expression: string, description = ''): Promise<Screen> => {
+    const response = await api.put<Screen>(`/api/screens/${encodeURIComponent(name)}`, { expression, description })
+    return response.data
+  },
+
+  deleteScreen: async (name: string): Promise<void> => {
+    await api.delete(`/api/screens/${encodeURIComponent(name)}`)
+  },
+
+  // Run a saved screen
+  getScreenResults: async (name: string, limit = 50, offset = 0): Promise<ScreenResults> => {
+    const response = await api.get<ScreenResults>(`/api/screens/${encodeURIComponent(name)}/results`, { params: { limit, offset } })
+    return response.data
+  },
+
+  // Get how much a brokerage's signals are trusted
+  getBrokerageCredibility: async (id: string): Promise<BrokerageCredibility> => {
+    const response = await api.get<BrokerageCredibility>(`/api/brokerages/${encodeURIComponent(id)}/credibility`)
+    return response.data
+  },
+
   // Health check
   healthCheck: async (): Promise<{ status: string }> => {
     const response = await api.get<{ status: string }>('/health')
@@ -13,6 +13,29 @@ export interface Stock {
   created_at: string
 }
 
+export interface StockValues {
+  target_from: string
+  target_to: string
+  action: string
+  brokerage: string
+  rating_from: string
+  rating_to: string
+}
+
+export interface StockRevision {
+  id: string
+  stock_id: string
+  sync_run_id?: string
+  old: StockValues
+  new: StockValues
+  changed_at: string
+}
+
+export interface StockHistoryResponse {
+  stock_id: string
+  data: StockRevision[] | null
+}
+
 export interface StocksResponse {
   data: Stock[]
   total: number
@@ -20,16 +43,225 @@ export interface StocksResponse {
   offset: number
 }
 
+export interface ScoreFactor {
+  name: string
+  label: string
+  input: string
+  weight: number
+  points: number
+  cap: number | null
+}
+
 export interface StockRecommendation {
   stock: Stock
   score: number
   reason: string
+  factors: ScoreFactor[]
+  sector?: string
+  industry?: string
+}
+
+export interface Diversification {
+  max_per_sector?: number
+  max_per_industry?: number
+  min_sectors?: number
+}
+
+export type ActionClass = 'upgrade' | 'downgrade' | 'target_raised' | 'target_lowered' | 'initiated' | 'reiterated' | 'other'
+
+export interface RecommendationFilter {
+  min_score?: number
+  sector?: string[]
+  brokerage?: string[]
+  action?: ActionClass[]
+  max_age_days?: number
+  exclude?: string[]
+  min_analysts?: number
+  offset?: number
+  cursor?: string
+}
+
+export interface SkippedRecommendation {
+  ticker: string
+  score: number
+  sector: string
+  industry: string
+  reason: string
 }
 
 export interface RecommendationsResponse {
+  side: 'buy' | 'sell'
+  strategy: string
+  strategy_version: string
+  profile_version: string
+  diversification?: Diversification
   data: StockRecommendation[]
+  skipped?: SkippedRecommendation[]
+  total: number
+  offset: number
+  next_cursor?: string
+}
+
+export interface RecommendationSnapshot {
+  id: string
+  source: 'scheduled' | 'sync' | 'manual'
+  side: 'buy' | 'sell'
+  strategy: string
+  strategy_version: string
+  profile_version: string
+  size: number
+  taken_at: string
+  entries?: SnapshotEntry[]
+}
+
+export interface SnapshotEntry {
+  rank: number
+  ticker: string
+  stock_id: string
+  score: number
+  reason: string
+  factors: ScoreFactor[]
+}
+
+export interface SnapshotChange {
+  ticker: string
+  from_rank: number | null
+  to_rank: number | null
+  from_score: number | null
+  to_score: number | null
+  change: number
+}
+
+export interface SnapshotDiff {
+  from: RecommendationSnapshot
+  to: RecommendationSnapshot
+  entered: SnapshotChange[]
+  exited: SnapshotChange[]
+  moved_up: SnapshotChange[]
+  moved_down: SnapshotChange[]
+  unchanged: number
+}
+
+export interface AnalystAnomaly {
+  id: string
+  scope: 'ticker' | 'sector'
+  subject: string
+  kind: 'volume' | 'direction'
+  direction: 'bullish' | 'bearish' | 'mixed'
+  window_start: string
+  window_end: string
+  observed: number
+  baseline_mean: number
+  baseline_stddev: number
+  z_score: number
+  events: number
+  bullish: number
+  bearish: number
+  detected_at: string
+}
+
+export interface Screen {
+  name: string
+  expression: string
+  description: string
+  created_at: string
+  updated_at: string
+}
+
+export interface ScreenMatch {
+  ticker: string
+  company: string
+  values: Record<string, string | number | null>
+  latest?: Stock
+}
+
+export interface ScreenResults {
+  screen: Screen
+  fields: string[]
+  as_of: string
+  prefiltered: boolean
+  total: number
+  limit: number
+  offset: number
+  data: ScreenMatch[]
+}
+
+export interface BrokerageRating {
+  brokerage: string
+  stock_id: string
+  rating_from: string
+  rating: string
+  target_from: string
+  target: string
+  action: string
+  time: string
+}
+
+export interface TargetStats {
+  count: number
+  mean: number | null
+  median: number | null
+  high: number | null
+  low: number | null
+  stddev: number | null
+  dispersion: number | null
+}
+
+export interface TargetSpread {
+  ticker: string
+  currency: string
+  as_of: string
+  stats: TargetStats
+  changes: {
+    days: number
+    as_of: string
+    stats: TargetStats
+    change: TargetStats
+    mean_change_pct: number | null
+  }[]
+  targets: { brokerage: string; stock_id: string; raw: string; target: number; currency: string; time: string }[]
+  excluded: { brokerage: string; stock_id: string; raw: string; time: string; reason: string }[]
+}
+
+export interface TickerConsensus {
+  ticker: string
+  company: string
+  brokerages: BrokerageRating[]
+  rating_distribution: Record<string, number>
+  covering_brokerages: number
+  mean_target: number | null
+  median_target: number | null
+  high_target: number | null
+  low_target: number | null
+  target_currency: string
+  latest_stock_id: string
+  last_action_time: string
+  updated_at: string
+  latest?: Stock
+}
+
+export interface TickersResponse {
+  data: TickerConsensus[]
+  total: number
+  limit: number
+  offset: number
 }
 
 export interface SyncResponse {
   message: string
+  run_id: string
+  pages: number
+  write_mode: 'atomic' | 'best_effort'
+}
+
+export interface BrokerageCredibility {
+  id: string
+  brokerage: string
+  credibility: number
+  source: 'tier' | 'history' | 'default'
+  tier?: string
+  events: number
+  hits: number
+  hit_rate: number | null
+  updated_at: string
 }